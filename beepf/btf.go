@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	btfutils "github.com/cen-ngc5139/BeePF/loader/lib/src/btf"
+	"github.com/cilium/ebpf"
+)
+
+var btfCommands = []command{
+	{name: "find", usage: "查找当前内核使用的 BTF", run: btfFindCmd},
+	{name: "min", usage: "为归档中的每个内核生成对象需要的最小化 BTF", run: btfMinCmd},
+}
+
+func btfCmd(args []string) error {
+	return dispatch(args, btfCommands, os.Stderr)
+}
+
+func btfFindCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf btf find", flag.ContinueOnError)
+	archive := fs.String("archive", "", "btfhub 风格的 BTF 归档目录 (可选)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	platform, err := btfutils.CurrentPlatform()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "platform: %s\n", platform)
+
+	if _, err := os.Stat("/sys/kernel/btf/vmlinux"); err == nil {
+		fmt.Fprintf(os.Stdout, "btf: /sys/kernel/btf/vmlinux\n")
+		return nil
+	}
+
+	if *archive == "" {
+		return btfutils.ErrBTFNotFound
+	}
+
+	path, err := btfutils.FindArchiveBTF(*archive, platform)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "btf: %s\n", path)
+	return nil
+}
+
+func btfMinCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf btf min", flag.ContinueOnError)
+	archive := fs.String("archive", "", "btfhub 风格的 BTF 归档目录")
+	out := fs.String("out", "", "输出目录，按归档的目录结构写入最小化 BTF")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf btf min -archive <dir> -out <dir> <object.o>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *archive == "" || *out == "" {
+		fs.Usage()
+		return fmt.Errorf("object file, archive and output directory are required")
+	}
+
+	btfs, err := minimizeArchive(fs.Arg(0), *archive)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(btfs))
+	for path := range btfs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		target := filepath.Join(*out, path)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, btfs[path], 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s (%d bytes)\n", target, len(btfs[path]))
+	}
+	return nil
+}
+
+// minimizeArchive 读取对象文件，为归档中的每个内核生成最小化 BTF
+func minimizeArchive(objectPath, archive string) (map[string][]byte, error) {
+	spec, err := ebpf.LoadCollectionSpec(objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("load object file: %w", err)
+	}
+
+	btfs, err := btfutils.MinimizeArchive(spec, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(btfs) == 0 {
+		return nil, fmt.Errorf("no BTF found in %s", archive)
+	}
+	return btfs, nil
+}
@@ -0,0 +1,74 @@
+// beepf 节点上的 eBPF 运维工具，无需部署 server 即可运行对象文件、查看程序和 map
+//
+// 用法:
+//
+//	beepf run [选项] <object.o> [变量参数]
+//	beepf replay [选项] <object.o> <recording>
+//	beepf prog list|show|dump
+//	beepf map list|dump
+//	beepf topo
+//	beepf package keygen|create|sign|verify
+//	beepf btf find|min
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{name: "run", usage: "加载对象文件并输出事件", run: runCmd},
+	{name: "replay", usage: "回放录制的原始事件", run: replayCmd},
+	{name: "prog", usage: "查看节点上的 eBPF 程序", run: progCmd},
+	{name: "map", usage: "查看节点上的 eBPF map", run: mapCmd},
+	{name: "topo", usage: "输出程序与 map 的拓扑", run: topoCmd},
+	{name: "package", usage: "创建、签名和校验程序包", run: packageCmd},
+	{name: "btf", usage: "查找内核 BTF，生成最小化 BTF", run: btfCmd},
+}
+
+func main() {
+	if err := dispatch(os.Args[1:], commands, os.Stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// dispatch 根据第一个参数选择子命令
+func dispatch(args []string, cmds []command, out io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printCommands(out, cmds)
+		if len(args) == 0 {
+			return fmt.Errorf("command is required")
+		}
+		return flag.ErrHelp
+	}
+
+	for _, cmd := range cmds {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	printCommands(out, cmds)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func printCommands(out io.Writer, cmds []command) {
+	fmt.Fprintf(out, "Usage: beepf <command> [arguments]\n\nCommands:\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
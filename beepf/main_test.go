@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestDispatch(t *testing.T) {
+	var called []string
+	cmds := []command{
+		{name: "run", usage: "run usage", run: func(args []string) error {
+			called = append(called, "run "+strings.Join(args, " "))
+			return nil
+		}},
+		{name: "fail", usage: "fail usage", run: func(args []string) error {
+			return errors.New("failed")
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantErr   string
+		wantHelp  bool
+		wantUsage bool
+		wantCall  string
+	}{
+		{name: "no command", args: nil, wantErr: "command is required", wantUsage: true},
+		{name: "help", args: []string{"help"}, wantHelp: true, wantUsage: true},
+		{name: "-h", args: []string{"-h"}, wantHelp: true, wantUsage: true},
+		{name: "--help", args: []string{"--help"}, wantHelp: true, wantUsage: true},
+		{name: "unknown", args: []string{"bogus"}, wantErr: `unknown command "bogus"`, wantUsage: true},
+		{name: "subcommand args", args: []string{"run", "-o", "json", "a.o"}, wantCall: "run -o json a.o"},
+		{name: "subcommand error", args: []string{"fail"}, wantErr: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = nil
+			var out bytes.Buffer
+			err := dispatch(tt.args, cmds, &out)
+
+			switch {
+			case tt.wantHelp:
+				if !errors.Is(err, flag.ErrHelp) {
+					t.Errorf("dispatch() error = %v, want flag.ErrHelp", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("dispatch() error = %v, want %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("dispatch() error = %v", err)
+			}
+
+			if gotUsage := strings.Contains(out.String(), "Usage: beepf"); gotUsage != tt.wantUsage {
+				t.Errorf("usage printed = %v, want %v: %q", gotUsage, tt.wantUsage, out.String())
+			}
+			if tt.wantUsage && (!strings.Contains(out.String(), "run usage") || !strings.Contains(out.String(), "fail usage")) {
+				t.Errorf("usage does not list all commands: %q", out.String())
+			}
+
+			if tt.wantCall == "" && len(called) != 0 || tt.wantCall != "" && (len(called) != 1 || called[0] != tt.wantCall) {
+				t.Errorf("called = %v, want %q", called, tt.wantCall)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/observability/topology"
+	"github.com/cilium/ebpf"
+)
+
+// mapSummary map 信息
+type mapSummary struct {
+	ID         ebpf.MapID `json:"id"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	KeySize    uint32     `json:"key_size"`
+	ValueSize  uint32     `json:"value_size"`
+	MaxEntries uint32     `json:"max_entries"`
+	Flags      uint32     `json:"flags"`
+	Frozen     bool       `json:"frozen"`
+}
+
+func newMapSummary(id ebpf.MapID, info *ebpf.MapInfo) mapSummary {
+	return mapSummary{
+		ID:         id,
+		Name:       info.Name,
+		Type:       info.Type.String(),
+		KeySize:    info.KeySize,
+		ValueSize:  info.ValueSize,
+		MaxEntries: info.MaxEntries,
+		Flags:      info.Flags,
+		Frozen:     info.Frozen(),
+	}
+}
+
+// mapEntry map 中的一个元素，per-CPU map 的值按 CPU 展开
+type mapEntry struct {
+	Key    string   `json:"key"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+var mapCommands = []command{
+	{name: "list", usage: "列出所有 map", run: mapListCmd},
+	{name: "dump", usage: "输出 map 中的所有元素", run: mapDumpCmd},
+}
+
+func mapCmd(args []string) error {
+	return dispatch(args, mapCommands, os.Stderr)
+}
+
+func mapListCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf map list", flag.ContinueOnError)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	maps, err := topology.ListAllMaps()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]ebpf.MapID, 0, len(maps))
+	for id := range maps {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	summaries := make([]mapSummary, 0, len(ids))
+	for _, id := range ids {
+		summaries = append(summaries, newMapSummary(id, maps[id]))
+	}
+
+	if *output == outputJSON {
+		return printJSON(os.Stdout, summaries)
+	}
+	return printMapTable(summaries)
+}
+
+func printMapTable(maps []mapSummary) error {
+	table := newTable(os.Stdout)
+	fmt.Fprintln(table, "ID\tTYPE\tNAME\tKEY\tVALUE\tMAX ENTRIES\tFLAGS")
+	for _, m := range maps {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%dB\t%dB\t%d\t%#x\n", m.ID, m.Type, m.Name, m.KeySize, m.ValueSize, m.MaxEntries, m.Flags)
+	}
+	return table.Flush()
+}
+
+func mapDumpCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf map dump", flag.ContinueOnError)
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf map dump [options] <map id>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("map id is required")
+	}
+
+	id, err := strconv.ParseUint(fs.Arg(0), 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid map id %q", fs.Arg(0))
+	}
+
+	m, err := ebpf.NewMapFromID(ebpf.MapID(id))
+	if err != nil {
+		return fmt.Errorf("open map %d: %w", id, err)
+	}
+	defer m.Close()
+
+	entries, err := dumpMap(m)
+	if err != nil {
+		return fmt.Errorf("dump map %d: %w", id, err)
+	}
+
+	if *output == outputJSON {
+		return printJSON(os.Stdout, entries)
+	}
+
+	for _, entry := range entries {
+		if entry.Values == nil {
+			fmt.Fprintf(os.Stdout, "key: %s  value: %s\n", entry.Key, entry.Value)
+			continue
+		}
+
+		fmt.Fprintf(os.Stdout, "key: %s\n", entry.Key)
+		for cpu, value := range entry.Values {
+			fmt.Fprintf(os.Stdout, "  cpu %d: %s\n", cpu, value)
+		}
+	}
+	fmt.Fprintf(os.Stdout, "Found %d elements\n", len(entries))
+	return nil
+}
+
+// dumpMap 遍历 map 并以十六进制输出键值
+func dumpMap(m *ebpf.Map) ([]mapEntry, error) {
+	var (
+		entries []mapEntry
+		key     []byte
+	)
+
+	iter := m.Iterate()
+	if isPerCPU(m.Type()) {
+		var values [][]byte
+		for iter.Next(&key, &values) {
+			entry := mapEntry{Key: hex.EncodeToString(key), Values: make([]string, 0, len(values))}
+			for _, value := range values {
+				entry.Values = append(entry.Values, hex.EncodeToString(value))
+			}
+			entries = append(entries, entry)
+		}
+	} else {
+		var value []byte
+		for iter.Next(&key, &value) {
+			entries = append(entries, mapEntry{Key: hex.EncodeToString(key), Value: hex.EncodeToString(value)})
+		}
+	}
+
+	return entries, iter.Err()
+}
+
+func isPerCPU(t ebpf.MapType) bool {
+	switch t {
+	case ebpf.PerCPUHash, ebpf.PerCPUArray, ebpf.LRUCPUHash, ebpf.PerCPUCGroupStorage:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/ebpf"
+)
+
+func TestDumpMap(t *testing.T) {
+	cpus, err := ebpf.PossibleCPU()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mapType ebpf.MapType
+		want    []mapEntry
+	}{
+		{
+			name:    "hex key and value",
+			mapType: ebpf.Hash,
+			want:    []mapEntry{{Key: "01000000", Value: "0a000000"}},
+		},
+		{
+			name:    "per-CPU values",
+			mapType: ebpf.PerCPUHash,
+			want:    []mapEntry{{Key: "01000000", Values: repeat("0a000000", cpus)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ebpf.NewMap(&ebpf.MapSpec{
+				Type:       tt.mapType,
+				KeySize:    4,
+				ValueSize:  4,
+				MaxEntries: 1,
+			})
+			if err != nil {
+				t.Skipf("create %s map error = %v", tt.mapType, err)
+			}
+			defer m.Close()
+
+			key, _ := hex.DecodeString("01000000")
+			value, _ := hex.DecodeString("0a000000")
+			if isPerCPU(tt.mapType) {
+				values := make([][]byte, cpus)
+				for i := range values {
+					values[i] = value
+				}
+				err = m.Put(key, values)
+			} else {
+				err = m.Put(key, value)
+			}
+			if err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			got, err := dumpMap(m)
+			if err != nil {
+				t.Fatalf("dumpMap() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dumpMap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func repeat(s string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = s
+	}
+	return out
+}
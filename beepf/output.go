@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// outputFlag 注册 -o 参数
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", outputText, "输出格式 (text|json)")
+}
+
+func checkOutput(output string) error {
+	if output != outputText && output != outputJSON {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+	return nil
+}
+
+// printJSON 以缩进格式输出 JSON
+func printJSON(out io.Writer, v any) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// newTable 创建表格输出
+func newTable(out io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+)
+
+var packageCommands = []command{
+	{name: "keygen", usage: "生成 ed25519 签名密钥对", run: packageKeygenCmd},
+	{name: "create", usage: "将对象文件打包，可选同时签名", run: packageCreateCmd},
+	{name: "sign", usage: "签名已有的包", run: packageSignCmd},
+	{name: "verify", usage: "校验包的完整性和签名", run: packageVerifyCmd},
+}
+
+func packageCmd(args []string) error {
+	return dispatch(args, packageCommands, os.Stderr)
+}
+
+func packageKeygenCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf package keygen", flag.ContinueOnError)
+	out := fs.String("out", "beepf", "密钥文件前缀，生成 <out>.pub 和 <out>.key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	publicPEM, privatePEM, err := meta.GenerateSigningKey()
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+
+	if err := os.WriteFile(*out+".key", privatePEM, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".pub", publicPEM, 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "public key: %s.pub\nprivate key: %s.key\n", *out, *out)
+	return nil
+}
+
+func packageCreateCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf package create", flag.ContinueOnError)
+	name := fs.String("name", "", "包名称")
+	version := fs.String("version", "", "包版本")
+	archs := fs.String("arch", "", "支持的架构，逗号分隔，如 amd64,arm64 (为空表示不限制)")
+	metaPath := fs.String("meta", "", "骨架 JSON 文件，提供 doc 和变量的 cmdarg (可选)")
+	keyPath := fs.String("key", "", "签名私钥 (可选)")
+	btfArchive := fs.String("btf-archive", "", "btfhub 风格的 BTF 归档目录，为其中每个内核内嵌最小化 BTF (可选)")
+	out := fs.String("out", "", "输出文件，默认为 <name>-<version>.beepf")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf package create [options] <object.o>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("object file is required")
+	}
+
+	object, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("read object file: %w", err)
+	}
+
+	var properties meta.Properties
+	if *metaPath != "" {
+		data, err := os.ReadFile(*metaPath)
+		if err != nil {
+			return fmt.Errorf("read meta file: %w", err)
+		}
+		if err := properties.MergeSkelDoc(data); err != nil {
+			return err
+		}
+	}
+
+	// 打包前检查对象文件和变量配置
+	if _, err := meta.GenerateMeta(object, properties); err != nil {
+		return err
+	}
+
+	manifest := meta.PackageManifest{
+		Name:    *name,
+		Version: *version,
+		Doc:     properties.Doc,
+		CmdArgs: properties.CmdArgs,
+	}
+	if *archs != "" {
+		manifest.Archs = strings.Split(*archs, ",")
+	}
+
+	pkg, err := meta.NewPackage(object, manifest)
+	if err != nil {
+		return err
+	}
+
+	if *btfArchive != "" {
+		btfs, err := minimizeArchive(fs.Arg(0), *btfArchive)
+		if err != nil {
+			return err
+		}
+		if err := pkg.SetBTF(btfs); err != nil {
+			return err
+		}
+	}
+
+	if *keyPath != "" {
+		key, err := readPrivateKey(*keyPath)
+		if err != nil {
+			return err
+		}
+		pkg.Sign(key)
+	}
+
+	if *out == "" {
+		*out = fmt.Sprintf("%s-%s.beepf", *name, *version)
+	}
+
+	if err := meta.WritePackage(*out, pkg); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "package: %s (signed: %t, embedded btf: %d)\n", *out, len(pkg.Signature) > 0, len(pkg.BTF))
+	return nil
+}
+
+func packageSignCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf package sign", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "签名私钥")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf package sign -key <private key> <package>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || *keyPath == "" {
+		fs.Usage()
+		return fmt.Errorf("package and private key are required")
+	}
+
+	pkg, err := meta.ReadPackage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// 不对被篡改的包签名
+	if err := pkg.Verify(nil, false); err != nil {
+		return err
+	}
+
+	key, err := readPrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	pkg.Sign(key)
+
+	return meta.WritePackage(fs.Arg(0), pkg)
+}
+
+func packageVerifyCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf package verify", flag.ContinueOnError)
+	var keyPaths stringList
+	fs.Var(&keyPaths, "trusted-key", "可信公钥，可重复指定")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf package verify -trusted-key <public key> <package>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || len(keyPaths) == 0 {
+		fs.Usage()
+		return fmt.Errorf("package and trusted key are required")
+	}
+
+	keys, err := readPublicKeys(keyPaths)
+	if err != nil {
+		return err
+	}
+
+	pkg, err := meta.ReadPackage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.Verify(keys, true); err != nil {
+		return err
+	}
+
+	m := pkg.Manifest
+	fmt.Fprintf(os.Stdout, "OK %s %s archs=%v sha256=%s created=%s\n",
+		m.Name, m.Version, m.Archs, m.ObjectSHA256, m.CreatedAt.Format("2006-01-02T15:04:05Z07:00"))
+	return nil
+}
+
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	return meta.ParsePrivateKey(data)
+}
+
+func readPublicKeys(paths []string) ([]ed25519.PublicKey, error) {
+	keys := make([]ed25519.PublicKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read public key: %w", err)
+		}
+
+		key, err := meta.ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// stringList 可重复指定的字符串参数
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
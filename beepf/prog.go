@@ -0,0 +1,223 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/observability/topology"
+	"github.com/cilium/ebpf"
+)
+
+// progSummary 程序信息
+type progSummary struct {
+	ID       ebpf.ProgramID   `json:"id"`
+	Name     string           `json:"name"`
+	Type     string           `json:"type"`
+	Tag      string           `json:"tag"`
+	BTF      uint32           `json:"btf_id,omitempty"`
+	Maps     []ebpf.MapID     `json:"maps"`
+	LoadTime time.Duration    `json:"load_time_since_boot,omitempty"`
+	UID      uint32           `json:"created_by_uid"`
+	Runtime  *progRuntimeInfo `json:"runtime,omitempty"`
+}
+
+// progRuntimeInfo 程序运行统计，需要开启 kernel.bpf_stats_enabled
+type progRuntimeInfo struct {
+	RunCount uint64        `json:"run_count"`
+	RunTime  time.Duration `json:"run_time"`
+}
+
+func newProgSummary(id ebpf.ProgramID, info *ebpf.ProgramInfo) progSummary {
+	summary := progSummary{
+		ID:   id,
+		Name: info.Name,
+		Type: info.Type.String(),
+		Tag:  info.Tag,
+	}
+
+	if btfID, ok := info.BTFID(); ok {
+		summary.BTF = uint32(btfID)
+	}
+	if maps, ok := info.MapIDs(); ok {
+		summary.Maps = maps
+	}
+	if loadTime, ok := info.LoadTime(); ok {
+		summary.LoadTime = loadTime
+	}
+	if uid, ok := info.CreatedByUID(); ok {
+		summary.UID = uid
+	}
+
+	runCount, hasCount := info.RunCount()
+	runTime, hasTime := info.Runtime()
+	if hasCount && hasTime && runCount > 0 {
+		summary.Runtime = &progRuntimeInfo{RunCount: runCount, RunTime: runTime}
+	}
+
+	return summary
+}
+
+var progCommands = []command{
+	{name: "list", usage: "列出所有程序", run: progListCmd},
+	{name: "show", usage: "查看程序详情及关联的 map", run: progShowCmd},
+	{name: "dump", usage: "输出程序指令 (xlated|jited)", run: progDumpCmd},
+}
+
+func progCmd(args []string) error {
+	return dispatch(args, progCommands, os.Stderr)
+}
+
+func progListCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf prog list", flag.ContinueOnError)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	progs, err := topology.ListAllPrograms()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]ebpf.ProgramID, 0, len(progs))
+	for id := range progs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	summaries := make([]progSummary, 0, len(ids))
+	for _, id := range ids {
+		summaries = append(summaries, newProgSummary(id, progs[id]))
+	}
+
+	if *output == outputJSON {
+		return printJSON(os.Stdout, summaries)
+	}
+
+	table := newTable(os.Stdout)
+	fmt.Fprintln(table, "ID\tTYPE\tNAME\tTAG\tMAPS")
+	for _, s := range summaries {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%v\n", s.ID, s.Type, s.Name, s.Tag, s.Maps)
+	}
+	return table.Flush()
+}
+
+func progShowCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf prog show", flag.ContinueOnError)
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf prog show [options] <prog id>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	id, err := parseProgID(fs)
+	if err != nil {
+		return err
+	}
+
+	info, err := topology.GetProgInfo(id)
+	if err != nil {
+		return fmt.Errorf("get program %d: %w", id, err)
+	}
+
+	summary := newProgSummary(id, info)
+	maps := make([]mapSummary, 0, len(summary.Maps))
+	for _, mapID := range summary.Maps {
+		mapInfo, err := topology.GetMapInfo(mapID)
+		if err != nil {
+			return fmt.Errorf("get map %d: %w", mapID, err)
+		}
+		maps = append(maps, newMapSummary(mapID, mapInfo))
+	}
+
+	if *output == outputJSON {
+		return printJSON(os.Stdout, struct {
+			progSummary
+			MapsDetail []mapSummary `json:"maps_detail"`
+		}{summary, maps})
+	}
+
+	table := newTable(os.Stdout)
+	fmt.Fprintf(table, "ID:\t%d\n", summary.ID)
+	fmt.Fprintf(table, "Name:\t%s\n", summary.Name)
+	fmt.Fprintf(table, "Type:\t%s\n", summary.Type)
+	fmt.Fprintf(table, "Tag:\t%s\n", summary.Tag)
+	fmt.Fprintf(table, "BTF ID:\t%d\n", summary.BTF)
+	fmt.Fprintf(table, "Created by UID:\t%d\n", summary.UID)
+	if summary.Runtime != nil {
+		fmt.Fprintf(table, "Run count:\t%d\n", summary.Runtime.RunCount)
+		fmt.Fprintf(table, "Run time:\t%s\n", summary.Runtime.RunTime)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if len(maps) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(os.Stdout, "\nMaps:")
+	return printMapTable(maps)
+}
+
+func progDumpCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf prog dump", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf prog dump xlated|jited <prog id>\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("dump type and program id are required")
+	}
+
+	id, err := strconv.ParseUint(fs.Arg(1), 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid program id %q", fs.Arg(1))
+	}
+
+	var dump []byte
+	switch fs.Arg(0) {
+	case "xlated":
+		dump, err = topology.GetProgDumpXlated(ebpf.ProgramID(id))
+	case "jited":
+		dump, err = topology.GetProgDumpJited(ebpf.ProgramID(id))
+	default:
+		return fmt.Errorf("invalid dump type %q", fs.Arg(0))
+	}
+	if err != nil {
+		return fmt.Errorf("dump program %d: %w", id, err)
+	}
+
+	_, err = os.Stdout.Write(dump)
+	return err
+}
+
+func parseProgID(fs *flag.FlagSet) (ebpf.ProgramID, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 0, fmt.Errorf("program id is required")
+	}
+
+	id, err := strconv.ParseUint(fs.Arg(0), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid program id %q", fs.Arg(0))
+	}
+	return ebpf.ProgramID(id), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	loader "github.com/cen-ngc5139/BeePF/loader/lib/src/cli"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+)
+
+// replayCmd 将 beepf run -record 录制的原始事件按对象文件中的类型解码输出，不加载程序，无需 root
+func replayCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf replay", flag.ContinueOnError)
+	btfPath := fs.String("btf", "", "内核 BTF 文件或 btfhub 风格的 BTF 归档目录 (可选)")
+	output := outputFlag(fs)
+	verbose := fs.Bool("v", false, "输出调试日志")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf replay [options] <object.o|package.beepf> <recording>\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("object file and recording are required")
+	}
+
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	logger, err := newLogger(*verbose)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	config := &loader.Config{
+		BTFPath: *btfPath,
+		Logger:  logger,
+		Properties: meta.Properties{
+			EventHandler: &eventPrinter{out: os.Stdout, format: *output},
+		},
+	}
+	if isPackage(fs.Arg(0)) {
+		config.PackagePath = fs.Arg(0)
+	} else {
+		config.ObjectPath = fs.Arg(0)
+	}
+
+	bpfLoader, err := loader.NewBPFLoader(config)
+	if err != nil {
+		return err
+	}
+
+	if err := bpfLoader.Init(); err != nil {
+		return err
+	}
+
+	ctx, cancel := loader.SignalContext(context.Background())
+	defer cancel()
+
+	return bpfLoader.Replay(ctx, fs.Arg(1))
+}
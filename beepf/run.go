@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	loader "github.com/cen-ngc5139/BeePF/loader/lib/src/cli"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"go.uber.org/zap"
+)
+
+// manifest run 子命令的清单文件，命令行参数优先于清单中的配置
+type manifest struct {
+	// Object 对象文件或程序包（.beepf）路径，相对路径相对于清单文件所在目录
+	Object string `json:"object"`
+
+	// TrustedKeys 可信的包签名公钥文件
+	TrustedKeys []string `json:"trusted_keys,omitempty"`
+
+	// RequireSignature 拒绝未签名的程序包
+	RequireSignature bool `json:"require_signature,omitempty"`
+
+	// Meta 骨架 JSON 文件，提供 doc 和变量的 cmdarg
+	Meta string `json:"meta,omitempty"`
+
+	// BTF 内核 BTF 文件或 btfhub 风格的 BTF 归档目录
+	BTF string `json:"btf,omitempty"`
+
+	// PollTimeout 轮询超时时间，如 100ms
+	PollTimeout string `json:"poll_timeout,omitempty"`
+
+	// Output 事件输出格式 (text|json)
+	Output string `json:"output,omitempty"`
+
+	// Record 录制原始事件的文件，可通过 beepf replay 回放
+	Record string `json:"record,omitempty"`
+
+	// Variables 全局变量的值
+	Variables map[string]json.RawMessage `json:"variables,omitempty"`
+}
+
+// loadManifest 读取清单文件，并将其中的相对路径转换为相对于清单文件
+func loadManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+
+	if m.Object == "" {
+		return nil, fmt.Errorf("manifest %s: object is required", path)
+	}
+
+	dir := filepath.Dir(path)
+	paths := []*string{&m.Object, &m.Meta, &m.BTF, &m.Record}
+	for i := range m.TrustedKeys {
+		paths = append(paths, &m.TrustedKeys[i])
+	}
+	for _, p := range paths {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+
+	return &m, nil
+}
+
+func runCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf run", flag.ContinueOnError)
+	manifestPath := fs.String("manifest", "", "清单文件，描述对象文件及其配置 (可选)")
+	metaPath := fs.String("meta", "", "骨架 JSON 文件，提供 doc 和变量的 cmdarg (可选)")
+	btfPath := fs.String("btf", "", "内核 BTF 文件或 btfhub 风格的 BTF 归档目录 (可选)")
+	pollTimeout := fs.Duration("poll-timeout", 100*time.Millisecond, "轮询超时时间")
+	output := outputFlag(fs)
+	record := fs.String("record", "", "将原始事件追加录制到文件 (可选)")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	var trustedKeys stringList
+	fs.Var(&trustedKeys, "trusted-key", "可信的包签名公钥，可重复指定")
+	requireSignature := fs.Bool("require-signature", false, "拒绝未签名的程序包")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: beepf run [options] <object.o|package.beepf> [variable flags]\n")
+		fmt.Fprintf(fs.Output(), "       beepf run [options] -manifest <manifest.json> [variable flags]\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n使用 beepf run <object.o> -h 查看对象文件的变量参数\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	m := &manifest{}
+	varArgs := fs.Args()
+	if *manifestPath != "" {
+		var err error
+		if m, err = loadManifest(*manifestPath); err != nil {
+			return err
+		}
+	} else {
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return fmt.Errorf("object file is required")
+		}
+		m.Object, varArgs = fs.Arg(0), fs.Args()[1:]
+	}
+
+	// 命令行显式设置的参数覆盖清单
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "meta":
+			m.Meta = *metaPath
+		case "btf":
+			m.BTF = *btfPath
+		case "poll-timeout":
+			m.PollTimeout = pollTimeout.String()
+		case "o":
+			m.Output = *output
+		case "record":
+			m.Record = *record
+		case "trusted-key":
+			m.TrustedKeys = trustedKeys
+		case "require-signature":
+			m.RequireSignature = *requireSignature
+		}
+	})
+
+	if m.Output == "" {
+		m.Output = outputText
+	}
+	if err := checkOutput(m.Output); err != nil {
+		return err
+	}
+
+	timeout := *pollTimeout
+	if m.PollTimeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(m.PollTimeout); err != nil {
+			return fmt.Errorf("parse poll timeout: %w", err)
+		}
+	}
+
+	keys, err := readPublicKeys(m.TrustedKeys)
+	if err != nil {
+		return err
+	}
+
+	var properties meta.Properties
+	if m.Meta != "" {
+		data, err := os.ReadFile(m.Meta)
+		if err != nil {
+			return fmt.Errorf("read meta file: %w", err)
+		}
+
+		if err := properties.MergeSkelDoc(data); err != nil {
+			return err
+		}
+	}
+
+	config := &loader.Config{
+		BTFPath:          m.BTF,
+		PollTimeout:      timeout,
+		TrustedKeys:      keys,
+		RequireSignature: m.RequireSignature,
+		RecordPath:       m.Record,
+	}
+
+	var objectBytes []byte
+	if isPackage(m.Object) {
+		// 先校验包再根据其中的对象文件生成参数
+		pkg, err := meta.ReadPackage(m.Object)
+		if err != nil {
+			return err
+		}
+
+		if err := pkg.Verify(keys, m.RequireSignature); err != nil {
+			return fmt.Errorf("verify package %s: %w", m.Object, err)
+		}
+
+		pkg.MergeProperties(&properties)
+		objectBytes = pkg.Object
+		config.PackagePath = m.Object
+	} else {
+		if objectBytes, err = os.ReadFile(m.Object); err != nil {
+			return fmt.Errorf("read object file: %w", err)
+		}
+		config.ObjectPath = m.Object
+	}
+
+	// 根据对象文件中的全局变量生成参数
+	varFlags, err := loader.NewVariableFlagSet("beepf run "+filepath.Base(m.Object), objectBytes, properties)
+	if err != nil {
+		return err
+	}
+
+	if err := varFlags.Parse(varArgs); err != nil {
+		return err
+	}
+
+	if properties.Variables == nil {
+		properties.Variables = make(map[string]json.RawMessage)
+	}
+	for name, value := range m.Variables {
+		properties.Variables[name] = value
+	}
+	for name, value := range varFlags.Variables() {
+		properties.Variables[name] = value
+	}
+
+	logger, err := newLogger(*verbose)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	properties.EventHandler = &eventPrinter{out: os.Stdout, format: m.Output}
+
+	config.Logger = logger
+	config.Properties = properties
+
+	bpfLoader, err := loader.NewBPFLoader(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := loader.SignalContext(context.Background())
+	defer cancel()
+
+	return bpfLoader.Run(ctx)
+}
+
+// isPackage 根据扩展名判断是否为程序包
+func isPackage(path string) bool {
+	return filepath.Ext(path) == ".beepf"
+}
+
+func newLogger(verbose bool) (*zap.Logger, error) {
+	if verbose {
+		return zap.NewDevelopment()
+	}
+	return zap.NewProduction()
+}
+
+// eventPrinter 将事件逐行写到标准输出
+// json 格式每行一个 JSON 对象，text 格式按字段名排序输出 key=value
+type eventPrinter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	format string
+}
+
+func (p *eventPrinter) HandleEvent(ctx *meta.UserContext, data *meta.ReceivedEventData) error {
+	var line string
+	switch data.Type {
+	case meta.TypeJsonText:
+		if p.format == outputJSON {
+			line = data.JsonText
+		} else {
+			line = jsonToText(data.JsonText)
+		}
+	case meta.TypePlainText:
+		line = data.Text
+	default:
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := fmt.Fprintln(p.out, strings.TrimRight(line, "\n"))
+	return err
+}
+
+// jsonToText 将 JSON 对象转换为 key=value 形式，非对象原样返回
+func jsonToText(text string) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return text
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := string(fields[key])
+		// 只有字符串去掉引号，null 会被 Unmarshal 解码为空字符串
+		var str string
+		if strings.HasPrefix(value, `"`) && json.Unmarshal(fields[key], &str) == nil {
+			value = str
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		want    *manifest
+		wantErr bool
+	}{
+		{
+			name:    "relative paths resolve against manifest dir",
+			content: `{"object": "prog.o", "meta": "skel/meta.json", "btf": "btf", "record": "out.rec", "trusted_keys": ["keys/a.pub"]}`,
+			want: &manifest{
+				Object:      filepath.Join(dir, "prog.o"),
+				Meta:        filepath.Join(dir, "skel/meta.json"),
+				BTF:         filepath.Join(dir, "btf"),
+				Record:      filepath.Join(dir, "out.rec"),
+				TrustedKeys: []string{filepath.Join(dir, "keys/a.pub")},
+			},
+		},
+		{
+			name:    "absolute paths are kept",
+			content: `{"object": "/opt/prog.o", "btf": "/sys/kernel/btf/vmlinux"}`,
+			want:    &manifest{Object: "/opt/prog.o", BTF: "/sys/kernel/btf/vmlinux"},
+		},
+		{
+			name:    "empty optional paths stay empty",
+			content: `{"object": "prog.o", "output": "json", "poll_timeout": "100ms"}`,
+			want:    &manifest{Object: filepath.Join(dir, "prog.o"), Output: "json", PollTimeout: "100ms"},
+		},
+		{name: "object is required", content: `{"meta": "meta.json"}`, wantErr: true},
+		{name: "invalid json", content: `{"object":`, wantErr: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "manifest"+string(rune('a'+i))+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadManifest(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadManifest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadManifest(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("loadManifest() of a missing file error = nil, want error")
+	}
+}
+
+func TestJsonToText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "sorted keys", in: `{"pid": 1, "comm": "bash"}`, want: "comm=bash pid=1"},
+		{name: "nested values stay json", in: `{"key": {"pid": 1}, "hist": [1, 2]}`, want: `hist=[1, 2] key={"pid": 1}`},
+		{name: "bool and null", in: `{"ok": true, "err": null}`, want: "err=null ok=true"},
+		{name: "empty object", in: `{}`, want: ""},
+		{name: "not an object", in: `[1, 2]`, want: `[1, 2]`},
+		{name: "plain text", in: "hello", want: "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonToText(tt.in); got != tt.want {
+				t.Errorf("jsonToText(%s) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/observability/topology"
+)
+
+func topoCmd(args []string) error {
+	fs := flag.NewFlagSet("beepf topo", flag.ContinueOnError)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	topo, err := topology.MergeTopology()
+	if err != nil {
+		return err
+	}
+
+	if *output == outputJSON {
+		return printJSON(os.Stdout, topo)
+	}
+
+	progNames := make(map[uint32]string, len(topo.ProgNodes))
+	for _, node := range topo.ProgNodes {
+		progNames[node.ID] = node.Name
+	}
+	mapNames := make(map[uint32]string, len(topo.MapNodes))
+	for _, node := range topo.MapNodes {
+		mapNames[node.ID] = node.Name
+	}
+
+	// 按程序分组输出程序使用的 map
+	progMaps := make(map[uint32][]uint32)
+	for _, edge := range topo.Edges {
+		progMaps[edge.ProgID] = append(progMaps[edge.ProgID], edge.MapID)
+	}
+
+	progIDs := make([]uint32, 0, len(progNames))
+	for id := range progNames {
+		progIDs = append(progIDs, id)
+	}
+	sort.Slice(progIDs, func(i, j int) bool { return progIDs[i] < progIDs[j] })
+
+	for _, progID := range progIDs {
+		fmt.Fprintf(os.Stdout, "prog %d %s\n", progID, progNames[progID])
+		for _, mapID := range progMaps[progID] {
+			fmt.Fprintf(os.Stdout, "  └─ map %d %s\n", mapID, mapNames[mapID])
+		}
+	}
+	return nil
+}
@@ -41,7 +41,11 @@ func main() {
 		Properties:  meta.Properties{},
 	}
 
-	bpfLoader := loader.NewBPFLoader(config)
+	bpfLoader, err := loader.NewBPFLoader(config)
+	if err != nil {
+		logger.Fatal("创建 BPF 加载器失败", zap.Error(err))
+		return
+	}
 
 	err = bpfLoader.Init()
 	if err != nil {
@@ -55,6 +59,7 @@ func main() {
 		return
 	}
 
+	bpfLoader.StopOnSignal()
 	if err := bpfLoader.Start(); err != nil {
 		logger.Fatal("start failed", zap.Error(err))
 	}
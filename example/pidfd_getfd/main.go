@@ -38,7 +38,11 @@ func main() {
 		},
 	}
 
-	bpfLoader := loader.NewBPFLoader(config)
+	bpfLoader, err := loader.NewBPFLoader(config)
+	if err != nil {
+		logger.Fatal("创建 BPF 加载器失败", zap.Error(err))
+		return
+	}
 
 	err = bpfLoader.Init()
 	if err != nil {
@@ -69,5 +73,9 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
+	if err := bpfLoader.Stop(); err != nil {
+		logger.Error("停止失败", zap.Error(err))
+	}
+
 	logger.Info("正常关闭")
 }
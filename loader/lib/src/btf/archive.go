@@ -0,0 +1,226 @@
+package btf
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/ulikunitz/xz"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// btfSuffix 归档中 BTF 文件的后缀
+	btfSuffix = ".btf"
+	// tarXzSuffix btfhub 压缩文件的后缀
+	tarXzSuffix = ".tar.xz"
+	// osReleasePath 发行版信息文件
+	osReleasePath = "/etc/os-release"
+)
+
+// Platform 描述查找 BTF 所需的内核和发行版信息
+// btfhub 的目录结构为 <distro>/<release>/<arch>/<kernel>.btf[.tar.xz]
+type Platform struct {
+	// Distro 发行版 ID，如 ubuntu、centos
+	Distro string `json:"distro"`
+	// Release 发行版版本，如 20.04、7
+	Release string `json:"release"`
+	// Arch btfhub 使用的架构名，如 x86_64、arm64
+	Arch string `json:"arch"`
+	// Kernel 内核版本，即 uname -r
+	Kernel string `json:"kernel"`
+}
+
+// Path 返回平台在归档中对应的 BTF 文件相对路径
+func (p Platform) Path() string {
+	return filepath.Join(p.Distro, p.Release, p.Arch, p.Kernel+btfSuffix)
+}
+
+// String 实现 fmt.Stringer 接口
+func (p Platform) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", p.Distro, p.Release, p.Arch, p.Kernel)
+}
+
+// CurrentPlatform 读取 /etc/os-release 和 uname 获取当前节点的平台信息
+func CurrentPlatform() (Platform, error) {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return Platform{}, fmt.Errorf("uname error: %w", err)
+	}
+
+	distro, release, err := readOSRelease(osReleasePath)
+	if err != nil {
+		return Platform{}, err
+	}
+
+	return Platform{
+		Distro:  distro,
+		Release: release,
+		Arch:    ArchiveArch(runtime.GOARCH),
+		Kernel:  unix.ByteSliceToString(uts.Release[:]),
+	}, nil
+}
+
+// ArchiveArch 将 GOARCH 转换为 btfhub 使用的架构名
+func ArchiveArch(goarch string) string {
+	switch goarch {
+	case "amd64":
+		return "x86_64"
+	default:
+		return goarch
+	}
+}
+
+// ParsePlatformPath 从归档中的相对路径解析平台信息，路径不符合目录结构时返回 false
+func ParsePlatformPath(path string) (Platform, bool) {
+	path = strings.TrimSuffix(filepath.ToSlash(path), tarXzSuffix)
+	if !strings.HasSuffix(path, btfSuffix) {
+		return Platform{}, false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(path, btfSuffix), "/")
+	if len(parts) != 4 {
+		return Platform{}, false
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return Platform{}, false
+		}
+	}
+
+	return Platform{Distro: parts[0], Release: parts[1], Arch: parts[2], Kernel: parts[3]}, true
+}
+
+// FindArchiveBTF 在 btfhub 风格的归档目录中查找平台对应的 BTF 文件
+// 未压缩的 .btf 文件优先于 .btf.tar.xz 文件
+func FindArchiveBTF(root string, p Platform) (string, error) {
+	path := filepath.Join(root, p.Path())
+	for _, candidate := range []string{path, path + tarXzSuffix} {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s in %s", ErrBTFNotFound, p, root)
+}
+
+// LoadArchiveBTF 在归档目录中查找并加载平台对应的 BTF
+func LoadArchiveBTF(root string, p Platform) (*btf.Spec, error) {
+	path, err := FindArchiveBTF(root, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadBTFFile(path)
+}
+
+// LoadBTFFile 加载 BTF 文件，支持原始 BTF、带 BTF 的 ELF 文件以及 btfhub 的 .btf.tar.xz 文件
+func LoadBTFFile(path string) (*btf.Spec, error) {
+	if !strings.HasSuffix(path, tarXzSuffix) {
+		spec, err := btf.LoadSpec(path)
+		if err != nil {
+			return nil, fmt.Errorf("load BTF %s error: %w", path, err)
+		}
+		return spec, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open BTF %s error: %w", path, err)
+	}
+	defer f.Close()
+
+	data, err := ReadTarXz(f)
+	if err != nil {
+		return nil, fmt.Errorf("read BTF %s error: %w", path, err)
+	}
+
+	spec, err := btf.LoadSpecFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("load BTF %s error: %w", path, err)
+	}
+	return spec, nil
+}
+
+// ReadTarXz 读取 xz 压缩的 tar 文件中的第一个 .btf 文件
+func ReadTarXz(r io.Reader) ([]byte, error) {
+	xr, err := xz.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("xz reader error: %w", err)
+	}
+
+	tr := tar.NewReader(xr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, ErrBTFNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar error: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, btfSuffix) {
+			continue
+		}
+
+		return io.ReadAll(tr)
+	}
+}
+
+// WalkArchive 遍历归档目录中所有符合目录结构的 BTF 文件
+func WalkArchive(root string, fn func(p Platform, path string) error) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		p, ok := ParsePlatformPath(rel)
+		if !ok {
+			return nil
+		}
+
+		return fn(p, path)
+	})
+}
+
+// readOSRelease 读取 os-release 中的 ID 和 VERSION_ID
+func readOSRelease(path string) (distro, release string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("read %s error: %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			distro = value
+		case "VERSION_ID":
+			release = value
+		}
+	}
+
+	if distro == "" || release == "" {
+		return "", "", fmt.Errorf("ID or VERSION_ID not found in %s", path)
+	}
+
+	return distro, release, nil
+}
@@ -0,0 +1,90 @@
+package btf
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+func TestParsePlatformPath(t *testing.T) {
+	p, ok := ParsePlatformPath("ubuntu/20.04/x86_64/5.4.0-42-generic.btf.tar.xz")
+	require.True(t, ok)
+	assert.Equal(t, Platform{Distro: "ubuntu", Release: "20.04", Arch: "x86_64", Kernel: "5.4.0-42-generic"}, p)
+	assert.Equal(t, filepath.FromSlash("ubuntu/20.04/x86_64/5.4.0-42-generic.btf"), p.Path())
+
+	_, ok = ParsePlatformPath("ubuntu/20.04/5.4.0-42-generic.btf")
+	assert.False(t, ok)
+	_, ok = ParsePlatformPath("ubuntu/20.04/x86_64/5.4.0-42-generic.txt")
+	assert.False(t, ok)
+}
+
+func TestLoadArchiveBTF(t *testing.T) {
+	root := t.TempDir()
+	p := Platform{Distro: "centos", Release: "7", Arch: "x86_64", Kernel: "3.10.0-1160.el7.x86_64"}
+	writeTarXz(t, filepath.Join(root, p.Path()+tarXzSuffix), p.Kernel+btfSuffix, marshalTypes(t, &btf.Int{Name: "compressed", Size: 4}))
+
+	spec, err := LoadArchiveBTF(root, p)
+	require.NoError(t, err)
+	_, err = spec.AnyTypeByName("compressed")
+	assert.NoError(t, err)
+
+	// 未压缩的文件优先
+	require.NoError(t, os.WriteFile(filepath.Join(root, p.Path()), marshalTypes(t, &btf.Int{Name: "raw", Size: 4}), 0644))
+	spec, err = LoadArchiveBTF(root, p)
+	require.NoError(t, err)
+	_, err = spec.AnyTypeByName("raw")
+	assert.NoError(t, err)
+
+	var found []Platform
+	require.NoError(t, WalkArchive(root, func(p Platform, path string) error {
+		found = append(found, p)
+		return nil
+	}))
+	assert.Equal(t, []Platform{p, p}, found)
+
+	p.Kernel = "missing"
+	_, err = LoadArchiveBTF(root, p)
+	assert.ErrorIs(t, err, ErrBTFNotFound)
+}
+
+func TestReadOSRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	require.NoError(t, os.WriteFile(path, []byte("NAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"20.04\"\n"), 0644))
+
+	distro, release, err := readOSRelease(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ubuntu", distro)
+	assert.Equal(t, "20.04", release)
+}
+
+func marshalTypes(t *testing.T, types ...btf.Type) []byte {
+	t.Helper()
+	builder, err := btf.NewBuilder(types)
+	require.NoError(t, err)
+	data, err := builder.Marshal(nil, nil)
+	require.NoError(t, err)
+	return data
+}
+
+func writeTarXz(t *testing.T, path, name string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	xw, err := xz.NewWriter(f)
+	require.NoError(t, err)
+	tw := tar.NewWriter(xw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+	_, err = tw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, xw.Close())
+}
@@ -1,10 +1,15 @@
 package btf
 
 import (
+	"errors"
 	"fmt"
+
 	"github.com/cilium/ebpf/btf"
 )
 
+// ErrBTFNotFound 没有找到当前内核对应的 BTF
+var ErrBTFNotFound = errors.New("kernel BTF not found")
+
 // LoadSystemBTF 加载系统 BTF 信息
 func LoadSystemBTF(path string) (spec *btf.Spec, err error) {
 	// 直接使用 cilium/ebpf 的 API 加载 BTF
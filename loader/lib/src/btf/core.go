@@ -0,0 +1,143 @@
+package btf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+)
+
+// CORERelocationFailure 无法在目标内核上完成的 CO-RE 重定位
+type CORERelocationFailure struct {
+	// Insn 重定位所在指令的下标，与校验器日志中的指令下标一致
+	Insn int `json:"insn"`
+	// Kind 重定位种类，如 byte_off、type_size、enumval_value
+	Kind string `json:"kind"`
+	// Type 本地类型名称
+	Type string `json:"type"`
+	// Field 访问的字段或枚举值，如 task_struct.cgroups.dfl_cgrp，类型重定位为空
+	Field string `json:"field,omitempty"`
+	// Reason 失败原因
+	Reason string `json:"reason"`
+}
+
+// String 实现 fmt.Stringer 接口
+func (f CORERelocationFailure) String() string {
+	target := f.Type
+	if f.Field != "" {
+		target = f.Field
+	}
+	return fmt.Sprintf("insn %d: %s %s: %s", f.Insn, f.Kind, target, f.Reason)
+}
+
+// CORERelocationFailures 在 kernel 上逐条求解程序的 CO-RE 重定位，返回无法完成的重定位
+// types 为对象文件的 BTF，即 CollectionSpec.Types
+func CORERelocationFailures(prog *ebpf.ProgramSpec, types, kernel *btf.Spec) ([]CORERelocationFailure, error) {
+	if types == nil || kernel == nil {
+		return nil, nil
+	}
+
+	bo := prog.ByteOrder
+	if bo == nil {
+		bo = binary.NativeEndian
+	}
+
+	var failures []CORERelocationFailure
+	iter := prog.Instructions.Iterate()
+	for iter.Next() {
+		relo := btf.CORERelocationMetadata(iter.Ins)
+		if relo == nil {
+			continue
+		}
+
+		parsed, err := parseRelocation(relo.String(), types)
+		if err != nil {
+			return nil, err
+		}
+
+		reason := ""
+		fixups, err := btf.CORERelocate([]*btf.CORERelocation{relo}, []*btf.Spec{kernel}, bo, types.TypeID)
+		switch {
+		case err != nil:
+			reason = err.Error()
+		case len(fixups) == 1 && strings.HasSuffix(fixups[0].String(), "=poison"):
+			reason = "not found in kernel BTF"
+		default:
+			continue
+		}
+
+		kind := parsed.kind
+		if kind == "" {
+			kind = "unknown"
+		}
+
+		failures = append(failures, CORERelocationFailure{
+			Insn:   int(iter.Offset),
+			Kind:   kind,
+			Type:   parsed.local.TypeName(),
+			Field:  accessorPath(parsed),
+			Reason: reason,
+		})
+	}
+
+	return failures, nil
+}
+
+// FindSourceLine 根据 BTF 行信息查找第 insn 条指令对应的源码行，insn 为原始指令下标
+func FindSourceLine(insns asm.Instructions, insn int) (*btf.Line, bool) {
+	var line *btf.Line
+	iter := insns.Iterate()
+	for iter.Next() && int(iter.Offset) <= insn {
+		if l, ok := iter.Ins.Source().(*btf.Line); ok {
+			line = l
+		}
+	}
+
+	return line, line != nil
+}
+
+// accessorPath 将重定位的访问路径转换为可读的字段名，类型重定位返回空
+func accessorPath(relo relocation) string {
+	switch relo.kind {
+	case "enumval_exists", "enumval_value":
+		enum, ok := btf.UnderlyingType(relo.local).(*btf.Enum)
+		if !ok || relo.accessor[0] >= len(enum.Values) {
+			return ""
+		}
+		return relo.local.TypeName() + "." + enum.Values[relo.accessor[0]].Name
+	case "byte_off", "byte_sz", "field_exists", "signed", "lshift_u64", "rshift_u64":
+	default:
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(relo.local.TypeName())
+	if relo.accessor[0] != 0 {
+		fmt.Fprintf(&b, "[%d]", relo.accessor[0])
+	}
+
+	typ := btf.UnderlyingType(relo.local)
+	for _, index := range relo.accessor[1:] {
+		switch t := typ.(type) {
+		case *btf.Struct, *btf.Union:
+			members := compositeMembers(t)
+			if index >= len(members) {
+				return b.String()
+			}
+			if members[index].Name != "" {
+				b.WriteString("." + members[index].Name)
+			}
+			typ = btf.UnderlyingType(members[index].Type)
+		case *btf.Array:
+			fmt.Fprintf(&b, "[%d]", index)
+			typ = btf.UnderlyingType(t.Type)
+		default:
+			return b.String()
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,430 @@
+package btf
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// reloPattern 匹配 CORERelocation.String() 的输出
+// cilium/ebpf 没有导出重定位的类型、访问路径和种类，只能从字符串中解析
+var reloPattern = regexp.MustCompile(`^CORERelocation\(([a-z_0-9]+), .*\[([0-9:]+)\], local_id=(\d+)\)$`)
+
+// localIDPattern 只匹配本地类型 ID，输出格式变化导致 reloPattern 无法匹配时使用
+var localIDPattern = regexp.MustCompile(`local_id=(\d+)`)
+
+// relocation 对象中的一条 CO-RE 重定位
+// 只能解析出本地类型时 kind 为空，裁剪时保留整个类型
+type relocation struct {
+	kind     string
+	accessor []int
+	local    btf.Type
+}
+
+// MinimizeBTF 根据对象的 CO-RE 重定位裁剪内核 BTF，只保留重定位需要的类型和字段
+// 返回原始 BTF 格式的数据，可以代替完整的内核 BTF 用于 CO-RE 重定位
+func MinimizeBTF(spec *ebpf.CollectionSpec, kernel *btf.Spec) ([]byte, error) {
+	relos, err := coreRelocations(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &minimizer{
+		kernel:  kernel,
+		copies:  make(map[btf.Type]btf.Type),
+		members: make(map[btf.Type]map[int]bool),
+	}
+
+	for _, relo := range relos {
+		m.addRelocation(relo)
+	}
+
+	for _, typ := range m.order {
+		switch c := typ.(type) {
+		case *btf.Struct:
+			sortMembers(c.Members)
+		case *btf.Union:
+			sortMembers(c.Members)
+		}
+	}
+
+	builder, err := btf.NewBuilder(m.order)
+	if err != nil {
+		return nil, fmt.Errorf("build minimized BTF error: %w", err)
+	}
+
+	data, err := builder.Marshal(nil, &btf.MarshalOptions{Order: spec.ByteOrder, PreventNoTypeFound: true})
+	if err != nil {
+		return nil, fmt.Errorf("marshal minimized BTF error: %w", err)
+	}
+
+	return data, nil
+}
+
+// coreRelocations 收集对象中所有程序的 CO-RE 重定位，重复的重定位只保留一条
+func coreRelocations(spec *ebpf.CollectionSpec) ([]relocation, error) {
+	if spec.Types == nil {
+		return nil, nil
+	}
+
+	var relos []relocation
+	seen := make(map[string]bool)
+	for _, prog := range spec.Programs {
+		for i := range prog.Instructions {
+			relo := btf.CORERelocationMetadata(&prog.Instructions[i])
+			if relo == nil {
+				continue
+			}
+
+			str := relo.String()
+			if seen[str] {
+				continue
+			}
+			seen[str] = true
+
+			parsed, err := parseRelocation(str, spec.Types)
+			if err != nil {
+				return nil, fmt.Errorf("program %s: %w", prog.Name, err)
+			}
+			relos = append(relos, parsed)
+		}
+	}
+
+	return relos, nil
+}
+
+// parseRelocation 解析重定位的字符串形式，并从对象的 BTF 中取出本地类型
+// 无法解析种类和访问路径时退化为只包含本地类型的重定位
+func parseRelocation(str string, types *btf.Spec) (relocation, error) {
+	match := reloPattern.FindStringSubmatch(str)
+	if match == nil {
+		return parseLocalType(str, types)
+	}
+
+	var accessor []int
+	for _, part := range strings.Split(match[2], ":") {
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return relocation{}, fmt.Errorf("parse accessor of %q error: %w", str, err)
+		}
+		accessor = append(accessor, index)
+	}
+
+	id, err := strconv.ParseUint(match[3], 10, 32)
+	if err != nil {
+		return relocation{}, fmt.Errorf("parse local id of %q error: %w", str, err)
+	}
+
+	local, err := types.TypeByID(btf.TypeID(id))
+	if err != nil {
+		return relocation{}, fmt.Errorf("local type of %q: %w", str, err)
+	}
+
+	return relocation{kind: match[1], accessor: accessor, local: local}, nil
+}
+
+// parseLocalType 只从重定位中取出本地类型
+func parseLocalType(str string, types *btf.Spec) (relocation, error) {
+	match := localIDPattern.FindStringSubmatch(str)
+	if match == nil {
+		return relocation{}, fmt.Errorf("unrecognized CO-RE relocation %q", str)
+	}
+
+	id, err := strconv.ParseUint(match[1], 10, 32)
+	if err != nil {
+		return relocation{}, fmt.Errorf("parse local id of %q error: %w", str, err)
+	}
+
+	local, err := types.TypeByID(btf.TypeID(id))
+	if err != nil {
+		return relocation{}, fmt.Errorf("local type of %q: %w", str, err)
+	}
+
+	return relocation{local: local}, nil
+}
+
+// minimizer 记录裁剪后的类型
+// 复合类型的副本初始没有成员，只有被重定位访问到的成员才会加入
+type minimizer struct {
+	kernel *btf.Spec
+	// copies 内核类型到裁剪后副本的映射
+	copies map[btf.Type]btf.Type
+	// members 内核复合类型中已加入副本的成员下标
+	members map[btf.Type]map[int]bool
+	// order 副本的创建顺序，保证生成的 BTF 稳定
+	order []btf.Type
+}
+
+// addRelocation 将重定位在内核中所有候选类型上用到的类型加入结果
+// 在某个候选类型上无法完成的重定位会被忽略，加载时由 CO-RE 按缺失处理
+func (m *minimizer) addRelocation(relo relocation) {
+	for _, target := range m.candidates(relo.local) {
+		switch relo.kind {
+		case "byte_off", "byte_sz", "field_exists", "signed", "lshift_u64", "rshift_u64":
+			_ = m.addField(relo.local, target, relo.accessor)
+		case "type_matches":
+			m.addAllMembers(target)
+		case "":
+			// 不知道访问了哪些成员，保留整个类型
+			m.addWholeType(target, make(map[btf.Type]bool))
+		default:
+			// 类型和枚举值重定位只需要类型本身，枚举不会被裁剪
+			m.copyType(target)
+		}
+	}
+}
+
+// candidates 按名称查找与本地类型种类相同的内核类型
+func (m *minimizer) candidates(local btf.Type) []btf.Type {
+	name := essentialName(local.TypeName())
+	if name == "" {
+		return nil
+	}
+
+	types, err := m.kernel.AnyTypesByName(name)
+	if err != nil {
+		return nil
+	}
+
+	var result []btf.Type
+	for _, typ := range types {
+		if reflect.TypeOf(typ) == reflect.TypeOf(local) {
+			result = append(result, typ)
+		}
+	}
+	return result
+}
+
+// addField 沿访问路径将访问到的成员加入结果
+// 访问路径的第一个下标是数组下标，之后依次是成员或数组元素的下标
+func (m *minimizer) addField(local, target btf.Type, accessor []int) error {
+	local, target = btf.UnderlyingType(local), btf.UnderlyingType(target)
+	m.copyType(target)
+
+	for _, index := range accessor[1:] {
+		switch lt := local.(type) {
+		case *btf.Struct, *btf.Union:
+			localMembers := compositeMembers(lt)
+			if index >= len(localMembers) {
+				return fmt.Errorf("member index %d out of range in %s", index, lt)
+			}
+
+			member := localMembers[index]
+			local = btf.UnderlyingType(member.Type)
+			if member.Name == "" {
+				// 匿名成员只在本地类型中下降一层，目标类型查找成员时会递归匿名成员
+				continue
+			}
+
+			path, ok := findMember(target, member.Name)
+			if !ok {
+				return fmt.Errorf("member %s not found in %s", member.Name, target)
+			}
+
+			for _, i := range path {
+				target = btf.UnderlyingType(m.addMember(target, i).Type)
+			}
+		case *btf.Array:
+			ta, ok := target.(*btf.Array)
+			if !ok {
+				return fmt.Errorf("%s is not an array", target)
+			}
+			local, target = btf.UnderlyingType(lt.Type), btf.UnderlyingType(ta.Type)
+		default:
+			return fmt.Errorf("unexpected %s in accessor", local)
+		}
+	}
+
+	return nil
+}
+
+// addAllMembers 将复合类型的所有成员加入结果，成员的复合类型仍然会被裁剪
+func (m *minimizer) addAllMembers(target btf.Type) {
+	target = btf.UnderlyingType(target)
+	m.copyType(target)
+	for i := range compositeMembers(target) {
+		m.addMember(target, i)
+	}
+}
+
+// addWholeType 将复合类型的所有成员以及内嵌的结构体、联合体和数组元素的成员全部加入结果
+// 指针指向的类型不会展开，CO-RE 的访问路径不会经过指针
+func (m *minimizer) addWholeType(target btf.Type, visited map[btf.Type]bool) {
+	target = btf.UnderlyingType(target)
+	if visited[target] {
+		return
+	}
+	visited[target] = true
+
+	switch t := target.(type) {
+	case *btf.Struct, *btf.Union:
+		m.addAllMembers(t)
+		for _, member := range compositeMembers(t) {
+			m.addWholeType(member.Type, visited)
+		}
+	case *btf.Array:
+		m.copyType(t)
+		m.addWholeType(t.Type, visited)
+	default:
+		m.copyType(t)
+	}
+}
+
+// addMember 将复合类型的第 index 个成员加入其副本，返回内核中的原始成员
+func (m *minimizer) addMember(composite btf.Type, index int) btf.Member {
+	member := compositeMembers(composite)[index]
+	if m.members[composite] == nil {
+		m.members[composite] = make(map[int]bool)
+	}
+	if m.members[composite][index] {
+		return member
+	}
+	m.members[composite][index] = true
+
+	copied := member
+	copied.Type = m.copyType(member.Type)
+	switch c := m.copyType(composite).(type) {
+	case *btf.Struct:
+		c.Members = append(c.Members, copied)
+	case *btf.Union:
+		c.Members = append(c.Members, copied)
+	}
+
+	return member
+}
+
+// copyType 返回内核类型的裁剪副本
+// 复合类型的副本保留名称和大小但不含成员，其他引用类型递归复制，基础类型直接复用
+func (m *minimizer) copyType(typ btf.Type) btf.Type {
+	if c, ok := m.copies[typ]; ok {
+		return c
+	}
+
+	var c btf.Type
+	switch t := typ.(type) {
+	case *btf.Struct:
+		c = &btf.Struct{Name: t.Name, Size: t.Size}
+	case *btf.Union:
+		c = &btf.Union{Name: t.Name, Size: t.Size}
+	case *btf.Typedef:
+		typedef := &btf.Typedef{Name: t.Name}
+		m.record(typ, typedef)
+		typedef.Type = m.copyType(t.Type)
+		return typedef
+	case *btf.Pointer:
+		pointer := &btf.Pointer{}
+		m.record(typ, pointer)
+		pointer.Target = m.copyType(t.Target)
+		return pointer
+	case *btf.Array:
+		c = &btf.Array{Index: m.copyType(t.Index), Type: m.copyType(t.Type), Nelems: t.Nelems}
+	case *btf.Const:
+		c = &btf.Const{Type: m.copyType(t.Type)}
+	case *btf.Volatile:
+		c = &btf.Volatile{Type: m.copyType(t.Type)}
+	case *btf.Restrict:
+		c = &btf.Restrict{Type: m.copyType(t.Type)}
+	case *btf.TypeTag:
+		c = &btf.TypeTag{Value: t.Value, Type: m.copyType(t.Type)}
+	case *btf.FuncProto:
+		proto := &btf.FuncProto{}
+		m.record(typ, proto)
+		proto.Return = m.copyType(t.Return)
+		for _, param := range t.Params {
+			proto.Params = append(proto.Params, btf.FuncParam{Name: param.Name, Type: m.copyType(param.Type)})
+		}
+		return proto
+	default:
+		// 整数、枚举、浮点数、前向声明和 void 不引用其他类型
+		c = typ
+	}
+
+	m.record(typ, c)
+	return c
+}
+
+// record 记录类型副本
+func (m *minimizer) record(typ, c btf.Type) {
+	m.copies[typ] = c
+	m.order = append(m.order, c)
+}
+
+// findMember 按名称查找成员，递归匿名成员，返回从外到内的成员下标
+func findMember(typ btf.Type, name string) ([]int, bool) {
+	for i, member := range compositeMembers(typ) {
+		if member.Name == name {
+			return []int{i}, true
+		}
+
+		if member.Name != "" {
+			continue
+		}
+
+		if path, ok := findMember(btf.UnderlyingType(member.Type), name); ok {
+			return append([]int{i}, path...), true
+		}
+	}
+
+	return nil, false
+}
+
+// compositeMembers 返回结构体或联合体的成员，其他类型返回空
+func compositeMembers(typ btf.Type) []btf.Member {
+	switch t := typ.(type) {
+	case *btf.Struct:
+		return t.Members
+	case *btf.Union:
+		return t.Members
+	default:
+		return nil
+	}
+}
+
+// sortMembers 按偏移排序成员，保持与内核类型一致的布局顺序
+func sortMembers(members []btf.Member) {
+	slices.SortStableFunc(members, func(a, b btf.Member) int {
+		return int(a.Offset) - int(b.Offset)
+	})
+}
+
+// essentialName 去掉 CO-RE 类型名中 ___ 之后的变体后缀
+func essentialName(name string) string {
+	if i := strings.Index(name, "___"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// MinimizeArchive 为归档目录中的每个内核生成最小化 BTF，键为平台在归档中的相对路径
+func MinimizeArchive(spec *ebpf.CollectionSpec, root string) (map[string][]byte, error) {
+	btfs := make(map[string][]byte)
+	err := WalkArchive(root, func(p Platform, path string) error {
+		if _, ok := btfs[p.Path()]; ok {
+			return nil
+		}
+
+		kernel, err := LoadBTFFile(path)
+		if err != nil {
+			return err
+		}
+
+		data, err := MinimizeBTF(spec, kernel)
+		if err != nil {
+			return fmt.Errorf("minimize BTF for %s error: %w", p, err)
+		}
+
+		btfs[p.Path()] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return btfs, nil
+}
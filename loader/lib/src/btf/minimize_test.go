@@ -0,0 +1,138 @@
+package btf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimizeBTF(t *testing.T) {
+	kernel, err := btf.LoadKernelSpec()
+	if err != nil {
+		t.Skipf("kernel BTF not available: %v", err)
+	}
+
+	spec, err := ebpf.LoadCollectionSpec("../../../testdata/shepherd_x86_bpfel.o")
+	require.NoError(t, err)
+
+	data, err := MinimizeBTF(spec, kernel)
+	require.NoError(t, err)
+
+	minimized, err := btf.LoadSpecFromReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	taskStruct, err := minimized.AnyTypeByName("task_struct")
+	require.NoError(t, err)
+	assert.NotEmpty(t, taskStruct.(*btf.Struct).Members)
+
+	// 裁剪后的 BTF 与完整的内核 BTF 得到相同的重定位结果
+	var relos []*btf.CORERelocation
+	for _, prog := range spec.Programs {
+		for i := range prog.Instructions {
+			if relo := btf.CORERelocationMetadata(&prog.Instructions[i]); relo != nil {
+				relos = append(relos, relo)
+			}
+		}
+	}
+	require.NotEmpty(t, relos)
+
+	want, err := btf.CORERelocate(relos, []*btf.Spec{kernel}, spec.ByteOrder, spec.Types.TypeID)
+	require.NoError(t, err)
+	got, err := btf.CORERelocate(relos, []*btf.Spec{minimized}, spec.ByteOrder, spec.Types.TypeID)
+	require.NoError(t, err)
+
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].String(), got[i].String(), relos[i].String())
+	}
+}
+
+// TestRelocationFormat 检查 CORERelocation.String() 的格式仍能被完整解析
+// 该格式不是 cilium/ebpf 的公开接口，升级依赖后格式变化时这里会失败
+func TestRelocationFormat(t *testing.T) {
+	spec, err := ebpf.LoadCollectionSpec("../../../testdata/shepherd_x86_bpfel.o")
+	require.NoError(t, err)
+
+	var count int
+	for _, prog := range spec.Programs {
+		for i := range prog.Instructions {
+			relo := btf.CORERelocationMetadata(&prog.Instructions[i])
+			if relo == nil {
+				continue
+			}
+			count++
+
+			str := relo.String()
+			require.Regexp(t, reloPattern, str, "CORERelocation.String() format changed")
+
+			parsed, err := parseRelocation(str, spec.Types)
+			require.NoError(t, err)
+			assert.NotEmpty(t, parsed.kind, str)
+			assert.NotEmpty(t, parsed.accessor, str)
+		}
+	}
+	require.NotZero(t, count, "object has no CO-RE relocations")
+}
+
+func TestMinimizeUnparsedRelocation(t *testing.T) {
+	i32 := &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}
+	inner := &btf.Struct{Name: "inner", Size: 8, Members: []btf.Member{
+		{Name: "a", Type: i32},
+		{Name: "b", Type: i32, Offset: 32},
+	}}
+	outer := &btf.Struct{Name: "outer", Size: 24, Members: []btf.Member{
+		{Name: "in", Type: inner},
+		{Name: "c", Type: i32, Offset: 64},
+		{Name: "p", Type: &btf.Pointer{Target: &btf.Struct{Name: "other", Size: 4, Members: []btf.Member{{Name: "x", Type: i32}}}}, Offset: 128},
+	}}
+
+	kernel := loadTypes(t, outer)
+	local := loadTypes(t, &btf.Struct{Name: "outer", Size: 4, Members: []btf.Member{{Name: "c", Type: i32}}})
+
+	localOuter, err := local.AnyTypeByName("outer")
+	require.NoError(t, err)
+	id, err := local.TypeID(localOuter)
+	require.NoError(t, err)
+
+	// 格式无法识别但带有 local_id 时退化为保留整个类型
+	relo, err := parseRelocation(fmt.Sprintf("CORERelocation(new format, local_id=%d)", id), local)
+	require.NoError(t, err)
+	assert.Empty(t, relo.kind)
+
+	_, err = parseRelocation("CORERelocation(new format)", local)
+	assert.Error(t, err)
+
+	m := &minimizer{kernel: kernel, copies: make(map[btf.Type]btf.Type), members: make(map[btf.Type]map[int]bool)}
+	m.addRelocation(relo)
+
+	members := func(name string) int {
+		for _, typ := range m.order {
+			if s, ok := typ.(*btf.Struct); ok && s.Name == name {
+				return len(s.Members)
+			}
+		}
+		return -1
+	}
+	assert.Equal(t, 3, members("outer"))
+	assert.Equal(t, 2, members("inner"))
+	// 指针指向的类型只保留外壳
+	assert.Equal(t, 0, members("other"))
+}
+
+// loadTypes 将类型编码为 BTF 后重新加载
+func loadTypes(t *testing.T, types ...btf.Type) *btf.Spec {
+	t.Helper()
+
+	builder, err := btf.NewBuilder(types)
+	require.NoError(t, err)
+	data, err := builder.Marshal(nil, nil)
+	require.NoError(t, err)
+	spec, err := btf.LoadSpecFromReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	return spec
+}
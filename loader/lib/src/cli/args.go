@@ -0,0 +1,209 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// VariableFlagSet 根据对象文件中的全局变量和 BpfSkelDoc 生成的命令行参数
+// 参数值按变量的 BTF 类型检查，解析后通过 Variables 写入 Properties.Variables
+type VariableFlagSet struct {
+	*flag.FlagSet
+
+	doc    *meta.BpfSkelDoc
+	values []*variableValue
+}
+
+// NewVariableFlagSet 为对象文件生成命令行参数
+// 变量的参数名默认为变量名，可通过 properties.CmdArgs 修改参数名、短参数、帮助信息和默认值
+func NewVariableFlagSet(name string, objectBytes []byte, properties meta.Properties) (*VariableFlagSet, error) {
+	spec, err := ebpf.LoadCollectionSpecFromReader(bytes.NewReader(objectBytes))
+	if err != nil {
+		return nil, fmt.Errorf("load collection spec failed: %w", err)
+	}
+
+	objMeta, err := meta.GenerateMeta(objectBytes, properties)
+	if err != nil {
+		return nil, fmt.Errorf("generate meta failed: %w", err)
+	}
+
+	types := make(map[string]btf.Type, len(spec.Variables))
+	for varName, varSpec := range spec.Variables {
+		if varSpec.Type() != nil {
+			types[varName] = varSpec.Type().Type
+		}
+	}
+
+	var variables []meta.DataSectionVariableMeta
+	for _, section := range objMeta.BpfSkel.DataSections {
+		variables = append(variables, section.Variables...)
+	}
+
+	return newVariableFlagSet(name, objMeta.BpfSkel.Doc, variables, types)
+}
+
+func newVariableFlagSet(name string, doc *meta.BpfSkelDoc, variables []meta.DataSectionVariableMeta, types map[string]btf.Type) (*VariableFlagSet, error) {
+	fs := &VariableFlagSet{
+		FlagSet: flag.NewFlagSet(name, flag.ContinueOnError),
+		doc:     doc,
+	}
+	fs.Usage = fs.usage
+
+	sort.Slice(variables, func(i, j int) bool { return variables[i].Name < variables[j].Name })
+
+	for _, variable := range variables {
+		typ, ok := types[variable.Name]
+		if !ok || !meta.Encodable(typ) {
+			continue
+		}
+
+		value := &variableValue{name: variable.Name, typ: typ}
+		if variable.CmdArg.Default != nil {
+			if err := value.setRaw(*variable.CmdArg.Default); err != nil {
+				return nil, fmt.Errorf("default value of variable %s: %w", variable.Name, err)
+			}
+		}
+
+		long := variable.CmdArg.Long
+		if long == "" {
+			long = variable.Name
+		}
+
+		usage := variable.CmdArg.Help
+		if usage == "" {
+			usage = fmt.Sprintf("set global variable %s", variable.Name)
+		}
+		usage = fmt.Sprintf("%s (%s)", usage, variable.Type)
+
+		var flagValue flag.Value = value
+		if isBoolType(typ) {
+			flagValue = &boolVariableValue{value}
+		}
+
+		if err := fs.define(flagValue, long, usage, variable.Name); err != nil {
+			return nil, err
+		}
+		if variable.CmdArg.Short != "" {
+			if len(variable.CmdArg.Short) != 1 {
+				return nil, fmt.Errorf("short argument of variable %s must be a single character", variable.Name)
+			}
+			if err := fs.define(flagValue, variable.CmdArg.Short, fmt.Sprintf("shorthand for -%s", long), variable.Name); err != nil {
+				return nil, err
+			}
+		}
+
+		fs.values = append(fs.values, value)
+	}
+
+	return fs, nil
+}
+
+// define 定义变量的参数，参数名冲突时返回错误而不是像 flag.Var 一样 panic
+// -h 和 -help 保留给帮助信息
+func (fs *VariableFlagSet) define(value flag.Value, name, usage, variable string) error {
+	if name == "h" || name == "help" {
+		return fmt.Errorf("argument -%s of variable %s is reserved for help", name, variable)
+	}
+
+	if fs.Lookup(name) != nil {
+		return fmt.Errorf("argument -%s of variable %s is already defined", name, variable)
+	}
+
+	fs.Var(value, name, usage)
+	return nil
+}
+
+// Variables 返回命令行设置或带默认值的变量，未设置的变量保持对象文件中的初始值
+func (fs *VariableFlagSet) Variables() map[string]json.RawMessage {
+	variables := make(map[string]json.RawMessage)
+	for _, value := range fs.values {
+		if value.raw != nil {
+			variables[value.name] = value.raw
+		}
+	}
+	return variables
+}
+
+// usage 输出程序文档和参数说明
+func (fs *VariableFlagSet) usage() {
+	out := fs.Output()
+	if fs.doc != nil {
+		printDocLine(out, fs.doc.Brief)
+		if fs.doc.Version != "" {
+			fmt.Fprintf(out, "version: %s\n", fs.doc.Version)
+		}
+		printDocLine(out, fs.doc.Description)
+	}
+
+	fmt.Fprintf(out, "Usage of %s:\n", fs.Name())
+	fs.PrintDefaults()
+
+	if fs.doc != nil {
+		printDocLine(out, fs.doc.Details)
+	}
+}
+
+func printDocLine(out io.Writer, text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		fmt.Fprintln(out, text)
+	}
+}
+
+// variableValue 全局变量参数，保存经过类型检查的 JSON 值
+type variableValue struct {
+	name string
+	typ  btf.Type
+	raw  json.RawMessage
+}
+
+func (v *variableValue) String() string {
+	if v == nil || v.raw == nil {
+		return ""
+	}
+	return string(v.raw)
+}
+
+// Set 解析命令行参数值
+// 值先按 JSON 解析，失败时作为字符串处理，因此 char 数组和枚举名不需要加引号
+func (v *variableValue) Set(s string) error {
+	if json.Valid([]byte(s)) {
+		if err := v.setRaw(json.RawMessage(s)); err == nil {
+			return nil
+		}
+	}
+
+	quoted, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return v.setRaw(quoted)
+}
+
+func (v *variableValue) setRaw(raw json.RawMessage) error {
+	if _, err := meta.EncodeVariable(v.typ, raw, nil); err != nil {
+		return err
+	}
+	v.raw = raw
+	return nil
+}
+
+// boolVariableValue bool 变量参数，允许省略参数值
+type boolVariableValue struct {
+	*variableValue
+}
+
+func (v *boolVariableValue) IsBoolFlag() bool { return true }
+
+func isBoolType(typ btf.Type) bool {
+	i, ok := btf.UnderlyingType(typ).(*btf.Int)
+	return ok && i.Encoding == btf.Bool
+}
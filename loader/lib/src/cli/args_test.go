@@ -0,0 +1,117 @@
+package loader
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf/btf"
+)
+
+func TestVariableFlagSet_Parse(t *testing.T) {
+	s32 := &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}
+	char := &btf.Int{Name: "char", Size: 1, Encoding: btf.Signed}
+	boolean := &btf.Int{Name: "_Bool", Size: 1, Encoding: btf.Bool}
+	types := map[string]btf.Type{
+		"target_pid": &btf.Volatile{Type: &btf.Const{Type: s32}},
+		"comm":       &btf.Array{Type: char, Nelems: 16},
+		"verbose":    boolean,
+		"dummy":      &btf.Pointer{Target: s32},
+	}
+
+	defaultPid := json.RawMessage(`-1`)
+	variables := []meta.DataSectionVariableMeta{
+		{Name: "target_pid", Type: "int", CmdArg: meta.VariableCommandArgument{Long: "pid", Short: "p", Default: &defaultPid}},
+		{Name: "comm", Type: "char[16]"},
+		{Name: "verbose", Type: "_Bool"},
+		{Name: "dummy", Type: "int *"},
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]json.RawMessage
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]json.RawMessage{"target_pid": json.RawMessage(`-1`)},
+		},
+		{
+			name: "long and short names",
+			args: []string{"-p", "42", "-comm", "bash", "-verbose"},
+			want: map[string]json.RawMessage{
+				"target_pid": json.RawMessage(`42`),
+				"comm":       json.RawMessage(`"bash"`),
+				"verbose":    json.RawMessage(`true`),
+			},
+		},
+		{
+			name: "custom long name",
+			args: []string{"-pid=7"},
+			want: map[string]json.RawMessage{"target_pid": json.RawMessage(`7`)},
+		},
+		{name: "type mismatch", args: []string{"-pid", "abc"}, wantErr: true},
+		{name: "string too long", args: []string{"-comm", "0123456789abcdef"}, wantErr: true},
+		{name: "unsupported type has no flag", args: []string{"-dummy", "0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, err := newVariableFlagSet("test", nil, variables, types)
+			if err != nil {
+				t.Fatalf("newVariableFlagSet() error = %v", err)
+			}
+			fs.SetOutput(io.Discard)
+
+			err = fs.Parse(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if got := fs.Variables(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variables() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariableFlagSet_Conflicts(t *testing.T) {
+	s32 := &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}
+	types := map[string]btf.Type{"pid": s32, "port": s32, "help": s32, "h": s32}
+
+	tests := []struct {
+		name      string
+		variables []meta.DataSectionVariableMeta
+	}{
+		{
+			name: "same short",
+			variables: []meta.DataSectionVariableMeta{
+				{Name: "pid", CmdArg: meta.VariableCommandArgument{Short: "p"}},
+				{Name: "port", CmdArg: meta.VariableCommandArgument{Short: "p"}},
+			},
+		},
+		{
+			name: "long collides with variable name",
+			variables: []meta.DataSectionVariableMeta{
+				{Name: "pid"},
+				{Name: "port", CmdArg: meta.VariableCommandArgument{Long: "pid"}},
+			},
+		},
+		{name: "help", variables: []meta.DataSectionVariableMeta{{Name: "help"}}},
+		{name: "h", variables: []meta.DataSectionVariableMeta{{Name: "h"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 参数名冲突时返回错误，不能 panic
+			if _, err := newVariableFlagSet("test", nil, tt.variables, types); err == nil {
+				t.Error("newVariableFlagSet() want error for conflicting arguments")
+			}
+		})
+	}
+}
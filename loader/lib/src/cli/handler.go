@@ -3,7 +3,7 @@ package loader
 import (
 	"errors"
 	"fmt"
-	"os"
+	"time"
 
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/container"
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
@@ -12,11 +12,13 @@ import (
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/btf"
-	"github.com/cilium/ebpf/perf"
 	"github.com/cilium/ebpf/ringbuf"
 	"go.uber.org/zap"
 )
 
+// defaultSampleInterval 元数据中没有采样配置时的采样间隔（毫秒）
+const defaultSampleInterval = 1000
+
 // MapHandler 定义 Map 处理器接口
 type MapHandler interface {
 	Type() ebpf.MapType
@@ -26,6 +28,8 @@ type MapHandler interface {
 	Close()
 	SetEventHandler(meta.EventHandler)
 	SetExportTypes([]meta.ExportedTypesStructMeta)
+	SetRecorder(*skeleton.Recorder)
+	SetObjectMeta(*meta.EunomiaObjectMeta)
 }
 
 // BaseMapHandler 提供通用实现
@@ -35,10 +39,119 @@ type BaseMapHandler struct {
 	Collection   *ebpf.Collection
 	MapSpec      *ebpf.MapSpec
 	BTFContainer *container.BTFContainer
-	Poller       skeleton.Poller
-	Stats        *metrics.Collector
+	// Pollers 处理器创建的轮询器，同类型的多个 map 共用一个处理器
+	Pollers []skeleton.Poller
+	// Stats 不为空时注册 perf event 和 ring buffer map 的事件统计
+	Stats        metrics.Collector
 	EventHandler meta.EventHandler
 	ExportTypes  []meta.ExportedTypesStructMeta
+	// Recorder 不为空时录制 perf event 和 ring buffer 中的原始事件
+	Recorder *skeleton.Recorder
+	// ObjectMeta 对象的全局元数据，提供 perf 缓冲区和流水线配置
+	ObjectMeta *meta.EunomiaObjectMeta
+	// Pipelines 配置了流水线的 map 在轮询器和事件处理器之间的有界队列，同类型的 map 共用一个处理器
+	Pipelines []*skeleton.Pipeline
+}
+
+// SetRecorder 设置原始事件录制器
+func (h *BaseMapHandler) SetRecorder(recorder *skeleton.Recorder) {
+	h.Recorder = recorder
+}
+
+// SetObjectMeta 设置对象的全局元数据
+func (h *BaseMapHandler) SetObjectMeta(objectMeta *meta.EunomiaObjectMeta) {
+	h.ObjectMeta = objectMeta
+}
+
+// newEventStats 创建 map 的事件统计，并注册到统计收集器
+func (h *BaseMapHandler) newEventStats(spec *ebpf.MapSpec) *skeleton.EventStats {
+	stats := skeleton.NewEventStats(spec.Name, spec.Type)
+	if h.Stats != nil {
+		h.Stats.RegisterMapStats(spec.Name, stats)
+	}
+	return stats
+}
+
+// perfBufferOptions 返回 map 的 perf 缓冲区配置
+func (h *BaseMapHandler) perfBufferOptions(name string) meta.PerfBufferOptions {
+	if h.ObjectMeta == nil {
+		return meta.PerfBufferOptions{Pages: meta.DefaultPerfBufferPages}
+	}
+	return h.ObjectMeta.PerfBufferOptions(name)
+}
+
+// setupPipeline 按 map 配置在事件处理器之前加入有界队列和工作协程，未配置时原样返回处理器
+func (h *BaseMapHandler) setupPipeline(spec *ebpf.MapSpec, structType *btf.Struct, processor skeleton.EventProcessor, stats *skeleton.EventStats) (skeleton.EventProcessor, error) {
+	if h.ObjectMeta == nil {
+		return processor, nil
+	}
+	mapMeta, ok := h.ObjectMeta.BpfSkel.Maps[spec.Name]
+	if !ok || mapMeta.Properties == nil || mapMeta.Properties.Pipeline == nil {
+		return processor, nil
+	}
+	props := mapMeta.Properties.Pipeline
+
+	config := skeleton.PipelineConfig{
+		QueueSize:  props.QueueSize,
+		Workers:    props.Workers,
+		DropPolicy: props.DropPolicy,
+		OnError: func(err error) {
+			h.Logger.Warn("pipeline handle event failed", zap.String("map", spec.Name), zap.Error(err))
+		},
+	}
+
+	if props.OrderKey != "" {
+		keyFunc, err := orderKeyFunc(structType, props.OrderKey)
+		if err != nil {
+			return nil, fmt.Errorf("map %s pipeline error: %w", spec.Name, err)
+		}
+		config.KeyFunc = keyFunc
+	}
+
+	pipeline, err := skeleton.NewPipeline(processor, config)
+	if err != nil {
+		return nil, fmt.Errorf("map %s pipeline error: %w", spec.Name, err)
+	}
+
+	h.Pipelines = append(h.Pipelines, pipeline)
+	stats.SetPipeline(pipeline)
+	return pipeline, nil
+}
+
+// orderKeyFunc 按导出结构体中的字段生成流水线的排序键
+func orderKeyFunc(structType *btf.Struct, field string) (skeleton.KeyFunc, error) {
+	for _, member := range structType.Members {
+		if member.Name != field {
+			continue
+		}
+		if member.BitfieldSize > 0 {
+			return nil, fmt.Errorf("order key %s is a bitfield", field)
+		}
+
+		size, err := btf.Sizeof(member.Type)
+		if err != nil {
+			return nil, fmt.Errorf("order key %s size error: %w", field, err)
+		}
+		return skeleton.FieldKey(member.Offset.Bytes(), uint32(size)), nil
+	}
+
+	return nil, fmt.Errorf("order key %s not found in struct %s", field, structType.Name)
+}
+
+// closePollers 关闭处理器创建的所有轮询器
+func (h *BaseMapHandler) closePollers() {
+	for _, poller := range h.Pollers {
+		poller.Close()
+	}
+	h.Pollers = nil
+}
+
+// closePipelines 等待流水线处理完已入队的事件，需要在轮询器停止后调用
+func (h *BaseMapHandler) closePipelines() {
+	for _, pipeline := range h.Pipelines {
+		pipeline.Close()
+	}
+	h.Pipelines = nil
 }
 
 // setupExporter 设置事件导出器
@@ -62,7 +175,29 @@ func (h *BaseMapHandler) setupExporter(structType *btf.Struct) (*export.EventExp
 	return exporter, nil
 }
 
-func (h *BaseMapHandler) setupKeyValueExporter(m *ebpf.MapSpec) (*export.EventExporter, error) {
+// sampleMeta 返回 map 的采样配置，元数据中没有配置时每秒采样一次且不清理 map
+func (h *BaseMapHandler) sampleMeta(name string) *meta.MapSampleMeta {
+	if h.ObjectMeta != nil {
+		if mapMeta, ok := h.ObjectMeta.BpfSkel.Maps[name]; ok && mapMeta.Sample != nil {
+			sample := *mapMeta.Sample
+			if sample.Interval == 0 {
+				sample.Interval = defaultSampleInterval
+			}
+			if sample.Type == "" {
+				sample.Type = meta.SampleMapTypeDefaultKV
+			}
+			return &sample
+		}
+	}
+
+	return &meta.MapSampleMeta{
+		Interval: defaultSampleInterval,
+		Type:     meta.SampleMapTypeDefaultKV,
+		Unit:     "us",
+	}
+}
+
+func (h *BaseMapHandler) setupKeyValueExporter(m *ebpf.MapSpec, sample *meta.MapSampleMeta) (*export.EventExporter, error) {
 	ee := export.NewEventExporterBuilder().
 		SetExportFormat(export.FormatJson).
 		SetUserContext(meta.NewUserContext(0)).
@@ -72,12 +207,7 @@ func (h *BaseMapHandler) setupKeyValueExporter(m *ebpf.MapSpec) (*export.EventEx
 		export.NewBTFTypeDescriptor(m.Key, m.Key.TypeName()),
 		export.NewBTFTypeDescriptor(m.Value, m.Value.TypeName()),
 		h.BTFContainer,
-		&meta.MapSampleMeta{
-			Interval: 1000,
-			Type:     meta.SampleMapTypeDefaultKV,
-			Unit:     "us",
-			ClearMap: true,
-		},
+		sample,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("build event exporter failed: %w", err)
@@ -88,15 +218,19 @@ func (h *BaseMapHandler) setupKeyValueExporter(m *ebpf.MapSpec) (*export.EventEx
 
 // setupPoller 设置轮询器
 func (h *BaseMapHandler) setupPoller(poller skeleton.Poller) (*skeleton.ProgramPoller, error) {
-	h.Poller = poller
-	// 创建程序轮询器
-	programPoller := skeleton.NewProgramPoller(h.Config.PollTimeout)
+	return h.setupPollerWithInterval(poller, h.Config.PollTimeout)
+}
 
-	// 启动轮询
+// setupPollerWithInterval 设置轮询器，非事件驱动的轮询器按 interval 调度，每个 map 使用独立的调度
+func (h *BaseMapHandler) setupPollerWithInterval(poller skeleton.Poller, interval time.Duration) (*skeleton.ProgramPoller, error) {
+	h.Pollers = append(h.Pollers, poller)
+	// 创建程序轮询器
+	programPoller := skeleton.NewProgramPoller(interval)
 
-	programPoller.StartPolling(
+	// 启动轮询，perf event 和 ring buffer 由事件驱动，Stop 时立即唤醒阻塞的读取
+	programPoller.StartPoller(
 		"",
-		poller.GetPollFunc(),
+		poller,
 		h.handlePollingError,
 	)
 
@@ -105,8 +239,17 @@ func (h *BaseMapHandler) setupPoller(poller skeleton.Poller) (*skeleton.ProgramP
 
 // findTargetStruct 查找目标结构体
 func (h *BaseMapHandler) findTargetStruct() (*btf.Struct, error) {
+	vars := make([]btf.Type, 0, len(h.Collection.Variables))
 	for _, v := range h.Collection.Variables {
-		structType, err := skeleton.FindStructType(v.Type())
+		vars = append(vars, v.Type())
+	}
+	return h.findExportStruct(vars)
+}
+
+// findExportStruct 在全局变量类型中查找导出的结构体
+func (h *BaseMapHandler) findExportStruct(vars []btf.Type) (*btf.Struct, error) {
+	for _, v := range vars {
+		structType, err := skeleton.FindStructType(v)
 		if err != nil {
 			h.Logger.Warn("find struct type failed", zap.Error(err))
 			continue
@@ -139,8 +282,8 @@ func (h *PerfEventMapHandler) SetBTFContainer(btfContainer *container.BTFContain
 }
 
 func (h *PerfEventMapHandler) Setup(spec *ebpf.MapSpec, m *ebpf.Map) (*skeleton.ProgramPoller, error) {
-	// 创建读取器
-	reader, err := perf.NewReader(m, os.Getpagesize())
+	// 按元数据和 map 配置创建读取器
+	reader, err := skeleton.NewPerfReader(m, h.perfBufferOptions(spec.Name))
 	if err != nil {
 		return nil, fmt.Errorf("create perf reader failed: %w", err)
 	}
@@ -158,12 +301,19 @@ func (h *PerfEventMapHandler) Setup(spec *ebpf.MapSpec, m *ebpf.Map) (*skeleton.
 	}
 
 	// 创建处理器
-	processor := export.NewJsonExportEventHandler(exporter)
+	stats := h.newEventStats(spec)
+	processor, err := h.setupPipeline(spec, structType, export.NewJsonExportEventHandler(exporter), stats)
+	if err != nil {
+		return nil, err
+	}
 
 	poller := &skeleton.PerfEventPoller{
 		Reader:    reader,
 		Processor: processor,
 		Timeout:   h.Config.PollTimeout,
+		MapName:   spec.Name,
+		Recorder:  h.Recorder,
+		Stats:     stats,
 	}
 
 	// 设置轮询器
@@ -175,9 +325,8 @@ func (h *PerfEventMapHandler) SetEventHandler(handler meta.EventHandler) {
 }
 
 func (h *PerfEventMapHandler) Close() {
-	if h.Poller != nil {
-		h.Poller.Close()
-	}
+	h.closePollers()
+	h.closePipelines()
 }
 
 func (h *PerfEventMapHandler) SetExportTypes(exportTypes []meta.ExportedTypesStructMeta) {
@@ -211,20 +360,27 @@ func (h *RingBufMapHandler) Setup(spec *ebpf.MapSpec, m *ebpf.Map) (*skeleton.Pr
 		return nil, err
 	}
 
-	processor := export.NewJsonExportEventHandler(exporter)
+	stats := h.newEventStats(spec)
+	processor, err := h.setupPipeline(spec, structType, export.NewJsonExportEventHandler(exporter), stats)
+	if err != nil {
+		return nil, err
+	}
+
 	poller := &skeleton.RingBufPoller{
 		Reader:    reader,
 		Processor: processor,
 		Timeout:   h.Config.PollTimeout,
+		MapName:   spec.Name,
+		Recorder:  h.Recorder,
+		Stats:     stats,
 	}
 
 	return h.setupPoller(poller)
 }
 
 func (h *RingBufMapHandler) Close() {
-	if h.Poller != nil {
-		h.Poller.Close()
-	}
+	h.closePollers()
+	h.closePipelines()
 }
 
 func (h *RingBufMapHandler) SetCollection(collection *ebpf.Collection) {
@@ -252,27 +408,25 @@ func (s *SampleMapHandler) Type() ebpf.MapType {
 }
 
 func (s *SampleMapHandler) Setup(spec *ebpf.MapSpec, m *ebpf.Map) (*skeleton.ProgramPoller, error) {
-	exporter, err := s.setupKeyValueExporter(spec)
+	sample := s.sampleMeta(spec.Name)
+	exporter, err := s.setupKeyValueExporter(spec, sample)
 	if err != nil {
 		return nil, err
 	}
 
 	processor := export.NewJsonMapExporter(exporter)
-	poller := &skeleton.SampleMapPoller{
-		BpfMap:    m,
-		Processor: processor,
-		SampleConfig: &skeleton.MapSampleConfig{
-			Interval: 1000,
-			ClearMap: true,
-		},
-	}
+	processor.MapType = spec.Type
+	poller := skeleton.NewSampleMapPoller(m, processor, &skeleton.MapSampleConfig{
+		Interval: int(sample.Interval),
+		ClearMap: sample.ClearMap,
+	})
 
-	return s.setupPoller(poller)
+	return s.setupPollerWithInterval(poller, time.Duration(sample.Interval)*time.Millisecond)
 }
 
 func (s *SampleMapHandler) Close() {
-	if s != nil && s.Poller != nil {
-		s.Poller.Close()
+	if s != nil {
+		s.closePollers()
 	}
 }
 
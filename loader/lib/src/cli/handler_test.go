@@ -0,0 +1,65 @@
+package loader
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton"
+	"github.com/cilium/ebpf"
+	"go.uber.org/zap/zaptest"
+)
+
+// discardProcessor 丢弃收到的事件
+type discardProcessor struct{}
+
+func (discardProcessor) HandleEvent(data []byte) error { return nil }
+
+func TestRingBufMapHandler_CloseAllPollers(t *testing.T) {
+	h := &RingBufMapHandler{
+		BaseMapHandler: BaseMapHandler{
+			Logger: zaptest.NewLogger(t),
+			Config: &Config{PollTimeout: 10 * time.Millisecond},
+		},
+	}
+
+	// 两个 ring buffer map 共用一个处理器
+	var (
+		pollers        []*skeleton.RingBufPoller
+		programPollers []*skeleton.ProgramPoller
+	)
+	for i := 0; i < 2; i++ {
+		m, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: 1 << 12})
+		if err != nil {
+			t.Skipf("create ringbuf map: %v", err)
+		}
+		t.Cleanup(func() { m.Close() })
+
+		poller, err := skeleton.NewRingBufPoller(m, discardProcessor{}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		programPoller, err := h.setupPoller(poller)
+		if err != nil {
+			t.Fatalf("setupPoller() error = %v", err)
+		}
+		pollers = append(pollers, poller)
+		programPollers = append(programPollers, programPoller)
+	}
+
+	for _, p := range programPollers {
+		p.Stop()
+	}
+	h.Close()
+
+	for i, poller := range pollers {
+		if _, err := poller.Reader.Read(); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("reader %d Read() error = %v, want %v", i, err, os.ErrClosed)
+		}
+	}
+	if len(h.Pollers) != 0 {
+		t.Errorf("got %d pollers after Close, want 0", len(h.Pollers))
+	}
+}
@@ -0,0 +1,123 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
+	"github.com/cilium/ebpf/btf"
+	"github.com/cilium/ebpf/link"
+)
+
+// IteratorOutput 一次迭代的输出
+type IteratorOutput struct {
+	// Name 迭代器程序名称
+	Name string `json:"name"`
+
+	// Text bpf_seq_printf 写入的文本输出
+	Text string `json:"text,omitempty"`
+
+	// Records bpf_seq_write 写入并按 BTF 结构体解码的记录
+	Records []json.RawMessage `json:"records,omitempty"`
+}
+
+// RunIterator 执行一次迭代器程序并读取其输出
+// 程序配置了 Iter.StructName 时，输出按该结构体切分并解码为 JSON 记录，否则作为文本返回
+func (l *BPFLoader) RunIterator(name string) (*IteratorOutput, error) {
+	// 迭代期间链接和 collection 不能被禁用、升级或停止关闭
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	progLink, ok := l.ProgLinks[name]
+	if !ok {
+		return nil, fmt.Errorf("program %s not attached", name)
+	}
+
+	iter, ok := progLink.(*link.Iter)
+	if !ok {
+		return nil, fmt.Errorf("program %s is not an iterator", name)
+	}
+
+	reader, err := iter.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open iterator %s failed: %w", name, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read iterator %s failed: %w", name, err)
+	}
+
+	output := &IteratorOutput{Name: name}
+
+	structName := l.iterStructName(name)
+	if structName == "" {
+		output.Text = string(data)
+		return output, nil
+	}
+
+	output.Records, err = l.decodeIterRecords(structName, data)
+	if err != nil {
+		return nil, fmt.Errorf("decode iterator %s output failed: %w", name, err)
+	}
+
+	return output, nil
+}
+
+// iterStructName 返回迭代器输出的结构体名称
+func (l *BPFLoader) iterStructName(name string) string {
+	progMeta, ok := l.PreLoadSkeleton.Meta.BpfSkel.Progs[name]
+	if !ok || progMeta.Properties == nil || progMeta.Properties.Iter == nil {
+		return ""
+	}
+	return progMeta.Properties.Iter.StructName
+}
+
+// decodeIterRecords 将 bpf_seq_write 输出按结构体大小切分并解码
+func (l *BPFLoader) decodeIterRecords(structName string, data []byte) ([]json.RawMessage, error) {
+	var structType *btf.Struct
+	if err := l.BTFContainer.GetSpec().TypeByName(structName, &structType); err != nil {
+		return nil, fmt.Errorf("find struct %s: %w", structName, err)
+	}
+
+	checkedTypes, err := export.NewBTFTypeDescriptor(structType, structType.TypeName()).BuildCheckedExportedMembers()
+	if err != nil {
+		return nil, fmt.Errorf("build checked members: %w", err)
+	}
+
+	chunks, err := splitRecords(data, int(structType.Size))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]json.RawMessage, 0, len(chunks))
+	for _, chunk := range chunks {
+		record, err := export.DumpToJsonWithCheckedTypes(checkedTypes, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("dump record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// splitRecords 将数据切分为固定大小的记录
+func splitRecords(data []byte, size int) ([][]byte, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid record size %d", size)
+	}
+
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf("output length %d is not a multiple of record size %d", len(data), size)
+	}
+
+	records := make([][]byte, 0, len(data)/size)
+	for off := 0; off < len(data); off += size {
+		records = append(records, data[off:off+size])
+	}
+
+	return records, nil
+}
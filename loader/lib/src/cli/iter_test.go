@@ -0,0 +1,33 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		size    int
+		want    [][]byte
+		wantErr bool
+	}{
+		{name: "two records", data: []byte{1, 2, 3, 4}, size: 2, want: [][]byte{{1, 2}, {3, 4}}},
+		{name: "empty", data: nil, size: 4, want: [][]byte{}},
+		{name: "truncated", data: []byte{1, 2, 3}, size: 2, wantErr: true},
+		{name: "zero size", data: []byte{1}, size: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitRecords(tt.data, tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitRecords() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
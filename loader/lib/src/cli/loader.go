@@ -1,12 +1,13 @@
 package loader
 
 import (
+	"context"
+	"crypto/ed25519"
 	"fmt"
-	"os"
-	"os/signal"
+	"maps"
 	"path/filepath"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/container"
@@ -20,6 +21,7 @@ import (
 )
 
 // Loader 定义加载器接口
+// 分阶段的生命周期接口，保留用于兼容，新代码建议使用 BPFLoader.Run
 type Loader interface {
 	Init() error
 	Load() error
@@ -40,31 +42,53 @@ type BPFLoader struct {
 	MapHandlers      []MapHandler
 	BTFContainer     *container.BTFContainer
 	Links            []link.Link
+	ProgLinks        map[string]link.Link
 	done             chan struct{}
+	stopOnce         sync.Once
+	progMu           sync.Mutex
 	StatsCollector   metrics.Collector
 	ProgAttachStatus map[string]meta.ProgAttachStatus
+	Recorder         *skeleton.Recorder
+	// baseProperties 合并程序包清单前的配置，升级到新的程序包时在其上合并新的清单
+	baseProperties meta.Properties
 }
 
 // Config 配置结构
 type Config struct {
 	ObjectPath  string
 	ObjectBytes []byte
+	// PackagePath 签名的程序包路径，与 ObjectPath、ObjectBytes 互斥
+	PackagePath string
+	// TrustedKeys 可信的包签名公钥，已签名的包必须由其中一个公钥签名
+	TrustedKeys []ed25519.PublicKey
+	// RequireSignature 拒绝加载未签名或签名无法校验的包
+	RequireSignature bool
+	// BTFPath 内核 BTF 文件，或 btfhub 风格的 BTF 归档目录，为空时在对象文件所在目录查找
 	BTFPath     string
 	Logger      *zap.Logger
 	PollTimeout time.Duration
 	Properties  meta.Properties
+	// RecordPath 不为空时将 perf event 和 ring buffer 中的原始事件追加录制到该文件，可通过 Replay 回放
+	RecordPath string
 }
 
-func NewBPFLoader(cfg *Config) *BPFLoader {
+var _ Loader = (*BPFLoader)(nil)
+
+// NewBPFLoader 校验配置并创建加载器
+func NewBPFLoader(cfg *Config) (*BPFLoader, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is required")
+	}
+
 	if err := ValidateAndMutateConfig(cfg); err != nil {
-		cfg.Logger.Error("failed to validate and mutate config", zap.Error(err))
-		os.Exit(1)
+		return nil, fmt.Errorf("validate config failed: %w", err)
 	}
 
 	loader := &BPFLoader{
 		Logger:      cfg.Logger,
 		Config:      cfg,
 		MapHandlers: make([]MapHandler, 0),
+		done:        make(chan struct{}),
 	}
 
 	if cfg.Properties.Stats != nil {
@@ -81,6 +105,7 @@ func NewBPFLoader(cfg *Config) *BPFLoader {
 		BaseMapHandler: BaseMapHandler{
 			Logger: cfg.Logger,
 			Config: cfg,
+			Stats:  loader.StatsCollector,
 		},
 	})
 
@@ -88,6 +113,7 @@ func NewBPFLoader(cfg *Config) *BPFLoader {
 		BaseMapHandler: BaseMapHandler{
 			Logger: cfg.Logger,
 			Config: cfg,
+			Stats:  loader.StatsCollector,
 		},
 	})
 
@@ -95,37 +121,137 @@ func NewBPFLoader(cfg *Config) *BPFLoader {
 		BaseMapHandler: BaseMapHandler{
 			Logger: cfg.Logger,
 			Config: cfg,
+			Stats:  loader.StatsCollector,
 		},
 	})
 
-	return loader
+	return loader, nil
+}
+
+// Run 依次执行 Init、Load、Start、Stats 和 Metrics，阻塞直到 ctx 被取消后执行 Stop
+// 任一阶段失败时会清理已创建的资源并返回错误
+func (l *BPFLoader) Run(ctx context.Context) error {
+	if err := l.Init(); err != nil {
+		return err
+	}
+
+	steps := []func() error{l.Load, l.Start, l.Stats, l.Metrics}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			if stopErr := l.Stop(); stopErr != nil {
+				l.Logger.Error("stop failed", zap.Error(stopErr))
+			}
+			return err
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		l.Logger.Info("context canceled, stopping BPF loader", zap.Error(ctx.Err()))
+	case <-l.done:
+		// 已通过 Stop 停止
+		return nil
+	}
+
+	return l.Stop()
 }
 
 // Init 初始化阶段
 func (l *BPFLoader) Init() (err error) {
 	l.Logger.Info("initializing BPF loader...")
 
-	var pkg *meta.ComposedObject
-	if l.Config.ObjectBytes != nil {
-		pkg, err = meta.GenerateComposedObjectWithBytes(l.Config.ObjectBytes, l.Config.Properties)
+	l.baseProperties = cloneProperties(l.Config.Properties)
+
+	objectPath, objectBytes := l.Config.ObjectPath, l.Config.ObjectBytes
+	var embeddedBTF map[string][]byte
+	if l.Config.PackagePath != "" {
+		pkg, err := l.loadPackage(l.Config.PackagePath)
 		if err != nil {
-			return fmt.Errorf("generate composed object failed: %w", err)
+			return err
+		}
+		pkg.MergeProperties(&l.Config.Properties)
+		objectPath, objectBytes, embeddedBTF = l.Config.PackagePath, pkg.Object, pkg.BTF
+	}
+
+	l.PreLoadSkeleton, err = l.buildPreLoadSkeleton(objectPath, objectBytes, embeddedBTF, l.Config.Properties)
+	return err
+}
+
+// cloneProperties 复制配置，合并程序包清单时会修改其中的变量和命令行参数
+func cloneProperties(properties meta.Properties) meta.Properties {
+	properties.Variables = maps.Clone(properties.Variables)
+	properties.CmdArgs = maps.Clone(properties.CmdArgs)
+	return properties
+}
+
+// loadPackage 读取程序包，按配置的签名策略校验签名并检查架构
+func (l *BPFLoader) loadPackage(path string) (*meta.Package, error) {
+	pkg, err := meta.ReadPackage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pkg.Verify(l.Config.TrustedKeys, l.Config.RequireSignature); err != nil {
+		return nil, fmt.Errorf("verify package %s failed: %w", path, err)
+	}
+
+	switch {
+	case len(pkg.Signature) == 0:
+		l.Logger.Warn("loading unsigned package", zap.String("package", path))
+	case len(l.Config.TrustedKeys) == 0:
+		l.Logger.Warn("package signature not verified, no trusted keys configured",
+			zap.String("package", path))
+	}
+
+	if err := pkg.CheckArch(); err != nil {
+		return nil, err
+	}
+
+	l.Logger.Info("package loaded",
+		zap.String("name", pkg.Manifest.Name),
+		zap.String("version", pkg.Manifest.Version),
+		zap.Bool("signed", len(pkg.Signature) > 0),
+		zap.Int("embedded btf", len(pkg.BTF)))
+
+	return pkg, nil
+}
+
+// buildPreLoadSkeleton 从对象文件或对象字节构建预加载骨架
+// embeddedBTF 为程序包内嵌的最小化 BTF，找不到内核 BTF 时使用
+func (l *BPFLoader) buildPreLoadSkeleton(
+	objectPath string,
+	objectBytes []byte,
+	embeddedBTF map[string][]byte,
+	properties meta.Properties,
+) (*skeleton.PreLoadBpfSkeleton, error) {
+	var (
+		pkg *meta.ComposedObject
+		err error
+	)
+	if objectBytes != nil {
+		pkg, err = meta.GenerateComposedObjectWithBytes(objectBytes, properties)
+		if err != nil {
+			return nil, fmt.Errorf("generate composed object failed: %w", err)
 		}
 	} else {
-		pkg, err = meta.GenerateComposedObject(l.Config.ObjectPath, l.Config.Properties)
+		pkg, err = meta.GenerateComposedObject(objectPath, properties)
 		if err != nil {
-			return fmt.Errorf("generate composed object failed: %w", err)
+			return nil, fmt.Errorf("generate composed object failed: %w", err)
 		}
 	}
 
 	// 构建预加载骨架
-	preLoadSkeleton, err := skeleton.FromJsonPackage(pkg, filepath.Dir(l.Config.ObjectPath)).Build()
+	btfPath := l.Config.BTFPath
+	if btfPath == "" {
+		btfPath = filepath.Dir(objectPath)
+	}
+
+	preLoadSkeleton, err := skeleton.FromJsonPackage(pkg, btfPath).SetEmbeddedBTF(embeddedBTF).Build()
 	if err != nil {
-		return fmt.Errorf("build preload skeleton failed: %w", err)
+		return nil, fmt.Errorf("build preload skeleton failed: %w", err)
 	}
 
-	l.PreLoadSkeleton = preLoadSkeleton
-	return nil
+	return preLoadSkeleton, nil
 }
 
 // Load 加载阶段
@@ -134,19 +260,32 @@ func (l *BPFLoader) Load() error {
 
 	// 加载并附加 eBPF 程序
 	skel, attachStatus, err := l.PreLoadSkeleton.LoadAndAttach()
+	l.ProgAttachStatus = attachStatus
 	if err != nil {
 		return fmt.Errorf("load and attach BPF programs failed: %w", err)
 	}
 
+	for name, status := range attachStatus {
+		if status.Status == meta.TaskStatusFailed {
+			l.Logger.Warn("optional program not attached", zap.String("prog name", name), zap.String("error", status.Error))
+		}
+	}
+
+	l.setSkeleton(skel)
+	return nil
+}
+
+// setSkeleton 使用已加载的骨架更新加载器状态
+func (l *BPFLoader) setSkeleton(skel *skeleton.BpfSkeleton) {
+	l.Skeleton = skel
 	l.Collection = skel.Collection
 	l.BTFContainer = skel.Btf
 	l.Links = skel.Links
-	l.ProgAttachStatus = attachStatus
+	l.ProgLinks = skel.ProgLinks
 	for _, handler := range l.MapHandlers {
 		handler.SetCollection(l.Collection)
 		handler.SetBTFContainer(l.BTFContainer)
 	}
-	return nil
 }
 
 // RegisterMapHandler 注册 Map 处理器
@@ -228,6 +367,22 @@ func isSkipMap(name string) bool {
 func (l *BPFLoader) Start() error {
 	l.Logger.Info("starting BPF programs...")
 
+	return l.startPollers()
+}
+
+// startPollers 为导出数据的 map 和 socket filter 启动轮询
+func (l *BPFLoader) startPollers() error {
+	if l.Config.RecordPath != "" && l.Recorder == nil {
+		recorder, err := skeleton.NewRecorder(l.Config.RecordPath)
+		if err != nil {
+			return err
+		}
+		l.Recorder = recorder
+		for _, handler := range l.MapHandlers {
+			handler.SetRecorder(recorder)
+		}
+	}
+
 	for mapName, mapMeta := range l.PreLoadSkeleton.Meta.BpfSkel.Maps {
 		m := l.GetMapCollectionByType(mapName)
 		if m == nil {
@@ -260,6 +415,7 @@ func (l *BPFLoader) Start() error {
 		}
 
 		handler.SetEventHandler(mapMeta.ExportHandler)
+		handler.SetObjectMeta(l.PreLoadSkeleton.Meta)
 
 		poller, err := handler.Setup(spec, m)
 		if err != nil {
@@ -270,28 +426,30 @@ func (l *BPFLoader) Start() error {
 		}
 	}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	l.startSocketPollers()
 
-	// 创建 done channel
-	l.done = make(chan struct{})
-
-	go func() {
-		sig := <-sigChan
-		l.Logger.Info("received signal", zap.String("signal", sig.String()))
-
-		if err := l.Stop(); err != nil {
-			l.Logger.Error("stop failed", zap.Error(err))
-		}
+	return nil
+}
 
-		// 通知主程序可以退出
-		close(l.done)
+// stopPollers 停止所有轮询并关闭 map handlers 持有的 reader
+func (l *BPFLoader) stopPollers() {
+	l.Logger.Info("stopping pollers")
+	for _, p := range l.Pollers {
+		p.Stop()
+	}
+	l.Pollers = nil
 
-		// 停止接收更多信号
-		signal.Stop(sigChan)
-	}()
+	l.Logger.Info("closing map handlers")
+	for _, handler := range l.MapHandlers {
+		handler.Close()
+	}
 
-	return nil
+	if l.Recorder != nil {
+		if err := l.Recorder.Close(); err != nil {
+			l.Logger.Error("failed to close recorder", zap.Error(err))
+		}
+		l.Recorder = nil
+	}
 }
 
 // Stop 停止阶段
@@ -306,23 +464,22 @@ func (l *BPFLoader) Stop() error {
 		}
 	}
 
-	// 1. 先停止所有 poller，因为它们在使用 maps
-	l.Logger.Info("stopping pollers")
-	for _, p := range l.Pollers {
-		p.Stop()
-	}
+	// 链接和 collection 的关闭与禁用、升级、变量修改和迭代器互斥
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
 
+	// 1. 先停止所有 poller，因为它们在使用 maps
 	// 2. 关闭所有 map handlers，它们持有 map readers
-	l.Logger.Info("closing map handlers")
-	for _, handler := range l.MapHandlers {
-		handler.Close()
-	}
+	l.stopPollers()
 
 	// 3. 关闭所有 links，因为它们引用了 programs
+	// 只移除本加载器创建的 pin，复用的他人 pin 保持不变
 	l.Logger.Info("closing links")
 	for _, link := range l.Links {
-		if err := link.Unpin(); err != nil {
-			l.Logger.Error("failed to unpin link", zap.Error(err))
+		if l.ownsPin(link) {
+			if err := link.Unpin(); err != nil {
+				l.Logger.Error("failed to unpin link", zap.Error(err))
+			}
 		}
 
 		if err := link.Close(); err != nil {
@@ -342,11 +499,18 @@ func (l *BPFLoader) Stop() error {
 	}
 
 	// 5. 清空所有引用
+	l.Skeleton = nil
 	l.Pollers = nil
 	l.MapHandlers = nil
 	l.Links = nil
+	l.ProgLinks = nil
 	l.Collection = nil
 
+	// 通知等待方加载器已停止
+	l.stopOnce.Do(func() {
+		close(l.done)
+	})
+
 	return nil
 }
 
@@ -358,6 +522,15 @@ func (l *BPFLoader) Stats() error {
 		return nil
 	}
 
+	if err := l.setAttachedPrograms(); err != nil {
+		return err
+	}
+
+	return l.StatsCollector.Start()
+}
+
+// setAttachedPrograms 将当前 collection 中的程序交给统计收集器
+func (l *BPFLoader) setAttachedPrograms() error {
 	attachedPros := make(map[uint32]*ebpf.Program)
 	for _, prog := range l.Collection.Programs {
 		info, err := prog.Info()
@@ -372,11 +545,7 @@ func (l *BPFLoader) Stats() error {
 		attachedPros[uint32(id)] = prog
 	}
 
-	if err := l.StatsCollector.SetAttachedPros(attachedPros); err != nil {
-		return err
-	}
-
-	return l.StatsCollector.Start()
+	return l.StatsCollector.SetAttachedPros(attachedPros)
 }
 
 func (l *BPFLoader) Metrics() error {
@@ -393,7 +562,7 @@ func (h *BaseMapHandler) handlePollingError(err error) {
 	h.Logger.Error("polling error", zap.Error(err))
 }
 
-// Done 返回完成信号通道
+// Done 返回完成信号通道，Stop 完成后关闭
 func (l *BPFLoader) Done() <-chan struct{} {
 	return l.done
 }
@@ -2,6 +2,7 @@ package loader
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -167,7 +168,11 @@ func TestBPFLoader_Init(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			bpfLoader := NewBPFLoader(tt.fields.Config)
+			bpfLoader, err := NewBPFLoader(tt.fields.Config)
+			if err != nil {
+				logger.Fatal("创建 BPF 加载器失败", zap.Error(err))
+				return
+			}
 
 			err = bpfLoader.Init()
 			if err != nil {
@@ -181,6 +186,7 @@ func TestBPFLoader_Init(t *testing.T) {
 				return
 			}
 
+			bpfLoader.StopOnSignal()
 			if err := bpfLoader.Start(); err != nil {
 				logger.Fatal("start failed", zap.Error(err))
 			}
@@ -200,6 +206,45 @@ func TestBPFLoader_Init(t *testing.T) {
 	}
 }
 
+func TestNewBPFLoader_InvalidConfig(t *testing.T) {
+	logger := zap.NewNop()
+
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{name: "nil config", config: nil},
+		{name: "missing object", config: &Config{Logger: logger}},
+		{name: "object path and bytes", config: &Config{Logger: logger, ObjectPath: "a.o", ObjectBytes: []byte{0}}},
+		{name: "missing logger", config: &Config{ObjectPath: "a.o"}},
+		{name: "package and object path", config: &Config{Logger: logger, ObjectPath: "a.o", PackagePath: "a.beepf"}},
+		{name: "require signature without keys", config: &Config{Logger: logger, PackagePath: "a.beepf", RequireSignature: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bpfLoader, err := NewBPFLoader(tt.config)
+			if err == nil {
+				t.Fatalf("NewBPFLoader() = %v, want error", bpfLoader)
+			}
+		})
+	}
+}
+
+func TestBPFLoader_RunInitError(t *testing.T) {
+	bpfLoader, err := NewBPFLoader(&Config{ObjectPath: "not-exist.o", Logger: zap.NewNop()})
+	if err != nil {
+		t.Fatalf("NewBPFLoader() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := bpfLoader.Run(ctx); err == nil {
+		t.Fatal("Run() with missing object want error")
+	}
+}
+
 // detectCgroupPath returns the first-found mount point of type cgroup2
 // and stores it in the cgroupPath global variable.
 func detectCgroupPath() (string, error) {
@@ -0,0 +1,152 @@
+package loader
+
+import (
+	"fmt"
+	"maps"
+	"os"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf/link"
+	"go.uber.org/zap"
+)
+
+// DisableProgram 解除单个程序的附加，collection 和 map 保持不变
+func (l *BPFLoader) DisableProgram(name string) error {
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	progLink, ok := l.ProgLinks[name]
+	if !ok {
+		return fmt.Errorf("program %s is not attached", name)
+	}
+
+	// 加载器打开的 socket 由轮询器使用，关闭后无法恢复
+	if socketLink, ok := progLink.(*meta.SocketFilterLink); ok && socketLink.Owned() {
+		return fmt.Errorf("disable socket filter %s: %w", name, link.ErrNotSupported)
+	}
+
+	if l.ownsPin(progLink) {
+		if err := progLink.Unpin(); err != nil {
+			l.Logger.Warn("failed to unpin link", zap.String("prog name", name), zap.Error(err))
+		}
+	}
+
+	if err := progLink.Close(); err != nil {
+		return fmt.Errorf("detach program %s failed: %w", name, err)
+	}
+
+	l.removeLink(name, progLink)
+
+	status := l.ProgAttachStatus[name]
+	status.ProgName = name
+	status.Status = meta.TaskStatusDisabled
+	status.Error = ""
+	status.AttachCount = 0
+	l.ProgAttachStatus[name] = status
+
+	l.Logger.Info("program disabled", zap.String("prog name", name))
+	return nil
+}
+
+// EnableProgram 重新附加被禁用或附加失败的程序
+func (l *BPFLoader) EnableProgram(name string) error {
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	if _, ok := l.ProgLinks[name]; ok {
+		return fmt.Errorf("program %s is already attached", name)
+	}
+
+	if l.Collection == nil || l.PreLoadSkeleton == nil {
+		return fmt.Errorf("program %s: BPF programs are not loaded", name)
+	}
+
+	progMeta, ok := l.PreLoadSkeleton.Meta.BpfSkel.Progs[name]
+	if !ok || !progMeta.Link {
+		return fmt.Errorf("program %s not found", name)
+	}
+
+	prog := l.Collection.Programs[name]
+	progSpec := l.PreLoadSkeleton.Spec.Programs[name]
+	if prog == nil || progSpec == nil {
+		return fmt.Errorf("program %s is not loaded", name)
+	}
+
+	// XDP 替换模式会直接使用已 pin 的链接，这类链接的 pin 不归本加载器所有
+	pinPath := progMeta.Properties.LinkPinPath
+	pinExisted := false
+	if pinPath != "" {
+		_, statErr := os.Stat(pinPath)
+		pinExisted = statErr == nil
+	}
+
+	progLink, err := progMeta.AttachProgram(progSpec, prog)
+	if err != nil {
+		return fmt.Errorf("attach program %s failed: %w", name, err)
+	}
+
+	if pinPath != "" {
+		if err := progLink.Pin(pinPath); err != nil {
+			progLink.Close()
+			return fmt.Errorf("pin program %s failed: %w", name, err)
+		}
+		if !pinExisted && l.Skeleton != nil {
+			if l.Skeleton.PinnedLinks == nil {
+				l.Skeleton.PinnedLinks = make(map[link.Link]struct{})
+			}
+			l.Skeleton.PinnedLinks[progLink] = struct{}{}
+		}
+	}
+
+	l.Links = append(l.Links, progLink)
+	l.ProgLinks[name] = progLink
+	if l.Skeleton != nil {
+		l.Skeleton.Links = l.Links
+	}
+
+	status := l.ProgAttachStatus[name]
+	status.ProgName = name
+	status.Status = meta.TaskStatusSuccess
+	status.Error = ""
+	status.Mechanism = meta.AttachMechanism(progLink)
+	status.AttachCount = meta.AttachCount(progLink)
+	l.ProgAttachStatus[name] = status
+
+	l.Logger.Info("program enabled", zap.String("prog name", name))
+	return nil
+}
+
+// ownsPin 判断链接的 pin 是否由本加载器创建
+func (l *BPFLoader) ownsPin(progLink link.Link) bool {
+	if l.Skeleton == nil {
+		return false
+	}
+	_, ok := l.Skeleton.PinnedLinks[progLink]
+	return ok
+}
+
+// ProgStatus 返回程序附加状态的快照，可以与启用、禁用和升级并发调用
+func (l *BPFLoader) ProgStatus() map[string]meta.ProgAttachStatus {
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	return maps.Clone(l.ProgAttachStatus)
+}
+
+// removeLink 从加载器持有的链接中移除程序的链接
+func (l *BPFLoader) removeLink(name string, progLink link.Link) {
+	delete(l.ProgLinks, name)
+
+	links := l.Links[:0]
+	for _, lk := range l.Links {
+		if lk != progLink {
+			links = append(links, lk)
+		}
+	}
+	l.Links = links
+
+	if l.Skeleton != nil {
+		l.Skeleton.Links = l.Links
+		delete(l.Skeleton.PinnedLinks, progLink)
+	}
+}
@@ -0,0 +1,123 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton"
+	"github.com/cilium/ebpf/link"
+	"go.uber.org/zap"
+)
+
+// closeRecorder 记录 Close 和 Unpin 调用的链接
+type closeRecorder struct {
+	link.Link
+	closed   bool
+	unpinned bool
+}
+
+func (l *closeRecorder) Unpin() error {
+	l.unpinned = true
+	return nil
+}
+
+func (l *closeRecorder) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestBPFLoader_DisableProgram(t *testing.T) {
+	target, other := &closeRecorder{}, &closeRecorder{}
+	l := &BPFLoader{
+		Logger:    zap.NewNop(),
+		Links:     []link.Link{target, other},
+		ProgLinks: map[string]link.Link{"target": target, "other": other},
+		ProgAttachStatus: map[string]meta.ProgAttachStatus{
+			"target": {ProgName: "target", Status: meta.TaskStatusSuccess, AttachCount: 1},
+		},
+	}
+
+	if err := l.DisableProgram("target"); err != nil {
+		t.Fatalf("DisableProgram() error = %v", err)
+	}
+
+	if !target.closed || other.closed {
+		t.Errorf("closed target = %v, other = %v, want only target closed", target.closed, other.closed)
+	}
+	if _, ok := l.ProgLinks["target"]; ok || len(l.Links) != 1 || l.Links[0] != other {
+		t.Errorf("links after disable = %v, %v", l.Links, l.ProgLinks)
+	}
+	if got := l.ProgAttachStatus["target"]; got.Status != meta.TaskStatusDisabled || got.AttachCount != 0 {
+		t.Errorf("status after disable = %+v", got)
+	}
+
+	if err := l.DisableProgram("target"); err == nil {
+		t.Error("DisableProgram() on a detached program want error")
+	}
+}
+
+func TestBPFLoader_StopKeepsForeignPins(t *testing.T) {
+	// foreign 模拟 XDP 替换模式下复用的他人 pin 的链接
+	owned, foreign := &closeRecorder{}, &closeRecorder{}
+	l := &BPFLoader{
+		Logger:   zap.NewNop(),
+		Config:   &Config{},
+		Links:    []link.Link{owned, foreign},
+		Skeleton: &skeleton.BpfSkeleton{PinnedLinks: map[link.Link]struct{}{owned: {}}},
+		done:     make(chan struct{}),
+	}
+
+	if err := l.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if !owned.unpinned || !owned.closed {
+		t.Error("link pinned by the loader must be unpinned and closed")
+	}
+	if foreign.unpinned || !foreign.closed {
+		t.Error("link reused from an existing pin must keep its pin")
+	}
+}
+
+func TestBPFLoader_EnableProgramAttached(t *testing.T) {
+	l := &BPFLoader{
+		Logger:    zap.NewNop(),
+		ProgLinks: map[string]link.Link{"target": &closeRecorder{}},
+	}
+
+	if err := l.EnableProgram("target"); err == nil {
+		t.Error("EnableProgram() on an attached program want error")
+	}
+}
+
+func TestBPFLoader_disabledPrograms(t *testing.T) {
+	l := &BPFLoader{
+		ProgAttachStatus: map[string]meta.ProgAttachStatus{
+			"on":     {ProgName: "on", Status: meta.TaskStatusSuccess},
+			"off":    {ProgName: "off", Status: meta.TaskStatusDisabled},
+			"failed": {ProgName: "failed", Status: meta.TaskStatusFailed},
+		},
+	}
+
+	got := l.disabledPrograms()
+	if _, ok := got["off"]; !ok || len(got) != 1 {
+		t.Errorf("disabledPrograms() = %v, want only off", got)
+	}
+}
+
+func TestBPFLoader_ProgStatus(t *testing.T) {
+	l := &BPFLoader{
+		Logger: zap.NewNop(),
+		ProgAttachStatus: map[string]meta.ProgAttachStatus{
+			"target": {ProgName: "target", Status: meta.TaskStatusSuccess},
+		},
+	}
+
+	// 返回的是快照，之后的禁用不影响调用方持有的结果
+	status := l.ProgStatus()
+	l.ProgAttachStatus["target"] = meta.ProgAttachStatus{ProgName: "target", Status: meta.TaskStatusDisabled}
+
+	if got := status["target"]; got.Status != meta.TaskStatusSuccess {
+		t.Errorf("ProgStatus() snapshot = %+v, want success", got)
+	}
+}
@@ -0,0 +1,88 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"go.uber.org/zap"
+)
+
+// Replay 将 Config.RecordPath 录制的原始事件送入与 Start 相同的导出器和 EventHandler
+// 只解析对象文件，不加载 eBPF 程序，无需 root 权限，需要先调用 Init
+func (l *BPFLoader) Replay(ctx context.Context, path string) error {
+	if l.PreLoadSkeleton == nil {
+		return fmt.Errorf("loader is not initialized")
+	}
+
+	processors, err := l.replayProcessors()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open recording %s error: %w", path, err)
+	}
+	defer f.Close()
+
+	reader, err := skeleton.NewRecordReader(f)
+	if err != nil {
+		return err
+	}
+
+	source := skeleton.NewReplaySource(reader, processors)
+	if err := source.Run(ctx); err != nil {
+		return err
+	}
+
+	if source.Skipped > 0 {
+		l.Logger.Warn("skipped records of unknown maps", zap.Int("count", source.Skipped))
+	}
+
+	return nil
+}
+
+// replayProcessors 为对象中的 perf event 和 ring buffer map 创建事件处理器，键为 map 名称
+func (l *BPFLoader) replayProcessors() (map[string]skeleton.EventProcessor, error) {
+	spec := l.PreLoadSkeleton.Spec
+
+	vars := make([]btf.Type, 0, len(spec.Variables))
+	for _, v := range spec.Variables {
+		vars = append(vars, v.Type())
+	}
+
+	processors := make(map[string]skeleton.EventProcessor)
+	for mapName, mapMeta := range l.PreLoadSkeleton.Meta.BpfSkel.Maps {
+		mapSpec, ok := spec.Maps[mapName]
+		if !ok || (mapSpec.Type != ebpf.PerfEventArray && mapSpec.Type != ebpf.RingBuf) {
+			continue
+		}
+
+		h := &BaseMapHandler{
+			Logger:       l.Logger,
+			Config:       l.Config,
+			BTFContainer: l.PreLoadSkeleton.Btf,
+			EventHandler: mapMeta.ExportHandler,
+			ExportTypes:  l.PreLoadSkeleton.Meta.ExportTypes,
+		}
+
+		structType, err := h.findExportStruct(vars)
+		if err != nil {
+			return nil, err
+		}
+
+		exporter, err := h.setupExporter(structType)
+		if err != nil {
+			return nil, err
+		}
+
+		processors[mapName] = export.NewJsonExportEventHandler(exporter)
+	}
+
+	return processors, nil
+}
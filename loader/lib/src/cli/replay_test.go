@@ -0,0 +1,91 @@
+package loader
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"go.uber.org/zap/zaptest"
+)
+
+// collectHandler 记录收到的事件
+type collectHandler struct {
+	mu     sync.Mutex
+	events []*meta.ReceivedEventData
+}
+
+func (h *collectHandler) HandleEvent(ctx *meta.UserContext, data *meta.ReceivedEventData) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, data)
+	return nil
+}
+
+func TestBPFLoader_Replay(t *testing.T) {
+	handler := &collectHandler{}
+	l, err := NewBPFLoader(&Config{
+		ObjectPath: "../../../testdata/shepherd_x86_bpfel.o",
+		Logger:     zaptest.NewLogger(t),
+		Properties: meta.Properties{EventHandler: handler},
+	})
+	if err != nil {
+		t.Fatalf("NewBPFLoader() error = %v", err)
+	}
+
+	if err := l.Init(); err != nil {
+		t.Skipf("Init() error = %v", err)
+	}
+
+	var mapName string
+	for name, m := range l.PreLoadSkeleton.Spec.Maps {
+		if m.Type == ebpf.RingBuf || m.Type == ebpf.PerfEventArray {
+			mapName = name
+			break
+		}
+	}
+	if mapName == "" {
+		t.Fatal("no event map in object")
+	}
+
+	var vars []btf.Type
+	for _, v := range l.PreLoadSkeleton.Spec.Variables {
+		vars = append(vars, v.Type())
+	}
+	structType, err := (&BaseMapHandler{ExportTypes: l.PreLoadSkeleton.Meta.ExportTypes}).findExportStruct(vars)
+	if err != nil {
+		t.Fatalf("findExportStruct() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "events.rec")
+	recorder, err := skeleton.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := recorder.Record(mapName, i, make([]byte, structType.Size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Record("unknown", 0, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	recorder.Close()
+
+	if err := l.Replay(context.Background(), path); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	if len(handler.events) != 3 {
+		t.Fatalf("got %d events, want 3", len(handler.events))
+	}
+	for _, event := range handler.events {
+		if event.Type != meta.TypeJsonText || event.JsonText == "" {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package loader
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// SignalContext 返回在收到 SIGINT 或 SIGTERM 时取消的 context，可直接传给 BPFLoader.Run
+func SignalContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, syscall.SIGINT, syscall.SIGTERM)
+}
+
+// StopOnSignal 在收到 SIGINT 或 SIGTERM 时停止加载器，停止完成后 Done 关闭
+// 用于分阶段调用 Start 的独立进程，嵌入到其他进程时不要使用
+func (l *BPFLoader) StopOnSignal() {
+	ctx, cancel := SignalContext(context.Background())
+
+	go func() {
+		defer cancel()
+
+		select {
+		case <-ctx.Done():
+			l.Logger.Info("received signal, stopping BPF loader")
+		case <-l.done:
+			return
+		}
+
+		if err := l.Stop(); err != nil {
+			l.Logger.Error("stop failed", zap.Error(err))
+		}
+	}()
+}
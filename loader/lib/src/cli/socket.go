@@ -0,0 +1,45 @@
+package loader
+
+import (
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
+	"go.uber.org/zap"
+)
+
+// startSocketPollers 为 socket filter 套接字启动报文轮询
+// 加载器打开的套接字总会轮询，调用方提供的套接字只在设置 PollFd 时轮询
+// 匹配的报文以原始数据的形式交给事件处理器
+func (l *BPFLoader) startSocketPollers() {
+	for name, progLink := range l.ProgLinks {
+		socketLink, ok := progLink.(*meta.SocketFilterLink)
+		if !ok {
+			continue
+		}
+
+		var sock *meta.SocketProperties
+		if progMeta, ok := l.PreLoadSkeleton.Meta.BpfSkel.Progs[name]; ok && progMeta.Properties != nil {
+			sock = progMeta.Properties.Socket
+		}
+		if !socketLink.Owned() && (sock == nil || !sock.PollFd) {
+			continue
+		}
+
+		handler := l.Config.Properties.EventHandler
+		if sock != nil && sock.ExportHandler != nil {
+			handler = sock.ExportHandler
+		}
+
+		exporter := &export.EventExporter{
+			UserExportEventHandler: handler,
+			UserCtx:                meta.NewUserContext(0),
+		}
+		poller := skeleton.NewSocketPoller(socketLink.FD(), export.NewRawExportEventHandler(exporter))
+
+		programPoller := skeleton.NewProgramPoller(l.Config.PollTimeout)
+		programPoller.StartPolling(name, poller.GetPollFunc(), func(err error) {
+			l.Logger.Error("socket polling error", zap.String("prog name", name), zap.Error(err))
+		})
+		l.Pollers = append(l.Pollers, programPoller)
+	}
+}
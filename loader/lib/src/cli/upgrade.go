@@ -0,0 +1,110 @@
+package loader
+
+import (
+	"fmt"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"go.uber.org/zap"
+)
+
+// Upgrade 不停机地将正在运行的对象升级为 newObject 指定的对象文件
+// 兼容的 map 会被沿用以保留状态，支持 link.Update 的附加点原地替换程序，其余附加点先附加新程序再关闭旧链接，
+// 轮询切换到新的 collection。升级失败时旧版本保持运行
+// 配置了 RequireSignature 时对象文件无法校验签名，只能通过 UpgradePackage 升级
+func (l *BPFLoader) Upgrade(newObject string) error {
+	return l.upgrade(newObject, nil, "")
+}
+
+// UpgradeWithBytes 与 Upgrade 相同，对象以字节形式提供
+func (l *BPFLoader) UpgradeWithBytes(newObject []byte) error {
+	return l.upgrade("", newObject, "")
+}
+
+// UpgradePackage 与 Upgrade 相同，新版本以程序包形式提供
+// 程序包与 Init 一样校验签名和架构，内嵌的最小化 BTF 和清单中的变量、命令行参数同样生效
+func (l *BPFLoader) UpgradePackage(packagePath string) error {
+	return l.upgrade("", nil, packagePath)
+}
+
+func (l *BPFLoader) upgrade(objectPath string, objectBytes []byte, packagePath string) error {
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	if l.Skeleton == nil {
+		return fmt.Errorf("upgrade: BPF programs are not loaded")
+	}
+
+	if packagePath == "" && l.Config.RequireSignature {
+		return fmt.Errorf("upgrade: signature required, upgrade with a signed package: %w", meta.ErrUnsignedPackage)
+	}
+
+	l.Logger.Info("upgrading BPF programs...", zap.String("object", objectPath), zap.String("package", packagePath))
+
+	// 旧程序包清单中的值不带入新版本，只在用户配置上合并新的清单
+	properties := cloneProperties(l.baseProperties)
+	var embeddedBTF map[string][]byte
+	if packagePath != "" {
+		pkg, err := l.loadPackage(packagePath)
+		if err != nil {
+			return fmt.Errorf("upgrade: %w", err)
+		}
+		pkg.MergeProperties(&properties)
+		objectPath, objectBytes, embeddedBTF = packagePath, pkg.Object, pkg.BTF
+	}
+
+	preLoadSkeleton, err := l.buildPreLoadSkeleton(objectPath, objectBytes, embeddedBTF, properties)
+	if err != nil {
+		return fmt.Errorf("upgrade: %w", err)
+	}
+
+	// 运行中被禁用的程序升级后仍保持禁用
+	preLoadSkeleton.Disabled = l.disabledPrograms()
+
+	skel, attachStatus, err := preLoadSkeleton.Upgrade(l.Skeleton)
+	if err != nil {
+		return fmt.Errorf("upgrade: load and attach BPF programs failed: %w", err)
+	}
+
+	// 新程序已经附加，停止旧 collection 上的轮询
+	l.stopPollers()
+
+	previous := l.Skeleton
+	if packagePath != "" {
+		l.Config.ObjectPath, l.Config.ObjectBytes, l.Config.PackagePath = "", nil, packagePath
+	} else {
+		l.Config.ObjectPath, l.Config.ObjectBytes, l.Config.PackagePath = objectPath, objectBytes, ""
+	}
+	l.Config.Properties = properties
+	l.PreLoadSkeleton = preLoadSkeleton
+	l.ProgAttachStatus = attachStatus
+	l.setSkeleton(skel)
+
+	// 关闭被取代的旧链接和旧 collection，沿用的 map 由新 collection 持有
+	if err := previous.CloseReplaced(skel); err != nil {
+		l.Logger.Warn("failed to close replaced links", zap.Error(err))
+	}
+
+	if err := l.startPollers(); err != nil {
+		return fmt.Errorf("upgrade: rewire pollers failed: %w", err)
+	}
+
+	if l.StatsCollector != nil {
+		if err := l.setAttachedPrograms(); err != nil {
+			return fmt.Errorf("upgrade: update stats collector failed: %w", err)
+		}
+	}
+
+	l.Logger.Info("BPF programs upgraded")
+	return nil
+}
+
+// disabledPrograms 返回当前被禁用的程序
+func (l *BPFLoader) disabledPrograms() map[string]struct{} {
+	disabled := make(map[string]struct{})
+	for name, status := range l.ProgAttachStatus {
+		if status.Status == meta.TaskStatusDisabled {
+			disabled[name] = struct{}{}
+		}
+	}
+	return disabled
+}
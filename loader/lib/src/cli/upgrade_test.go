@@ -0,0 +1,80 @@
+package loader
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton"
+	"go.uber.org/zap"
+)
+
+// writeTestPackage 将对象写成未签名的程序包
+func writeTestPackage(t *testing.T, manifest meta.PackageManifest) string {
+	t.Helper()
+
+	pkg, err := meta.NewPackage([]byte("object"), manifest)
+	if err != nil {
+		t.Fatalf("NewPackage() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), manifest.Name+".beepf")
+	if err := meta.WritePackage(path, pkg); err != nil {
+		t.Fatalf("WritePackage() error = %v", err)
+	}
+	return path
+}
+
+func TestBPFLoader_UpgradePackageChecksArch(t *testing.T) {
+	path := writeTestPackage(t, meta.PackageManifest{Name: "shepherd", Version: "2.0.0", Archs: []string{"not-an-arch"}})
+
+	l := &BPFLoader{
+		Logger:   zap.NewNop(),
+		Config:   &Config{PackagePath: "shepherd-1.0.0.beepf"},
+		Skeleton: &skeleton.BpfSkeleton{},
+	}
+
+	// 升级与 Init 走同样的程序包校验，失败时配置保持不变
+	if err := l.UpgradePackage(path); !errors.Is(err, meta.ErrUnsupportedArch) {
+		t.Errorf("UpgradePackage() error = %v, want %v", err, meta.ErrUnsupportedArch)
+	}
+	if l.Config.PackagePath != "shepherd-1.0.0.beepf" {
+		t.Errorf("PackagePath after failed upgrade = %q", l.Config.PackagePath)
+	}
+}
+
+func TestBPFLoader_UpgradeRequiresSignature(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsigned := writeTestPackage(t, meta.PackageManifest{Name: "shepherd", Version: "2.0.0"})
+	config := &Config{
+		PackagePath:      "shepherd-1.0.0.beepf",
+		TrustedKeys:      []ed25519.PublicKey{public},
+		RequireSignature: true,
+	}
+	l := &BPFLoader{Logger: zap.NewNop(), Config: config, Skeleton: &skeleton.BpfSkeleton{}}
+
+	tests := []struct {
+		name    string
+		upgrade func() error
+	}{
+		{name: "object", upgrade: func() error { return l.Upgrade("shepherd.o") }},
+		{name: "object bytes", upgrade: func() error { return l.UpgradeWithBytes([]byte("object")) }},
+		{name: "unsigned package", upgrade: func() error { return l.UpgradePackage(unsigned) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.upgrade(); !errors.Is(err, meta.ErrUnsignedPackage) {
+				t.Errorf("upgrade error = %v, want %v", err, meta.ErrUnsignedPackage)
+			}
+			if config.PackagePath != "shepherd-1.0.0.beepf" || config.ObjectPath != "" || config.ObjectBytes != nil {
+				t.Errorf("config changed by refused upgrade: %+v", config)
+			}
+		})
+	}
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func ValidateAndMutateConfig(cfg *Config) error {
-	if cfg.ObjectBytes == nil && cfg.ObjectPath == "" {
+	if cfg.ObjectBytes == nil && cfg.ObjectPath == "" && cfg.PackagePath == "" {
 		return fmt.Errorf("object file is required")
 	}
 
@@ -16,6 +16,14 @@ func ValidateAndMutateConfig(cfg *Config) error {
 		return fmt.Errorf("object file and object bytes cannot both be set")
 	}
 
+	if cfg.PackagePath != "" && (cfg.ObjectBytes != nil || cfg.ObjectPath != "") {
+		return fmt.Errorf("package and object file cannot both be set")
+	}
+
+	if cfg.RequireSignature && len(cfg.TrustedKeys) == 0 {
+		return fmt.Errorf("trusted keys are required to verify package signatures")
+	}
+
 	if cfg.Logger == nil {
 		return fmt.Errorf("logger is required")
 	}
@@ -24,7 +32,7 @@ func ValidateAndMutateConfig(cfg *Config) error {
 		cfg.PollTimeout = 1 * time.Second
 	}
 
-	if cfg.Properties.Maps == nil || cfg.Properties.EventHandler == nil {
+	if cfg.Properties.EventHandler == nil {
 		cfg.Properties.EventHandler = &export.MyCustomHandler{Logger: cfg.Logger}
 	}
 
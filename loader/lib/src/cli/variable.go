@@ -0,0 +1,145 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"go.uber.org/zap"
+)
+
+// VariableValue 运行中程序的全局变量
+type VariableValue struct {
+	// Name 变量名称
+	Name string `json:"name"`
+
+	// Type 变量的 C 类型
+	Type string `json:"type"`
+
+	// ReadOnly 变量位于 .rodata，加载后不可修改
+	ReadOnly bool `json:"read_only"`
+
+	// Value 按 BTF 解码的变量值
+	Value json.RawMessage `json:"value"`
+}
+
+// ListVariables 读取所有全局变量的当前值
+func (l *BPFLoader) ListVariables() ([]VariableValue, error) {
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	if l.Collection == nil {
+		return nil, fmt.Errorf("BPF programs are not loaded")
+	}
+
+	names := make([]string, 0, len(l.Collection.Variables))
+	for name := range l.Collection.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]VariableValue, 0, len(names))
+	for _, name := range names {
+		value, err := readVariable(name, l.Collection.Variables[name])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, *value)
+	}
+
+	return values, nil
+}
+
+// GetVariable 读取单个全局变量的当前值
+func (l *BPFLoader) GetVariable(name string) (*VariableValue, error) {
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	variable, err := l.variable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return readVariable(name, variable)
+}
+
+// SetVariable 修改 .data/.bss 中的全局变量，运行中的程序立即可见
+// 结构体和数组只覆盖 raw 中提供的成员和元素，其余保持当前值
+func (l *BPFLoader) SetVariable(name string, raw json.RawMessage) error {
+	l.progMu.Lock()
+	defer l.progMu.Unlock()
+
+	variable, err := l.variable(name)
+	if err != nil {
+		return err
+	}
+
+	if variable.ReadOnly() {
+		return fmt.Errorf("variable %s is read-only after load", name)
+	}
+
+	if variable.Type() == nil {
+		return fmt.Errorf("variable %s has no BTF", name)
+	}
+
+	// 从当前值开始编码，只覆盖提供的结构体成员和数组元素
+	current := make([]byte, variable.Size())
+	if err := variable.Get(current); err != nil {
+		return fmt.Errorf("get variable %s failed: %w", name, err)
+	}
+
+	buf, err := meta.EncodeVariable(variable.Type().Type, raw, current)
+	if err != nil {
+		return fmt.Errorf("encode variable %s failed: %w", name, err)
+	}
+
+	if err := variable.Set(buf); err != nil {
+		return fmt.Errorf("set variable %s failed: %w", name, err)
+	}
+
+	l.Logger.Info("variable updated", zap.String("name", name), zap.ByteString("value", raw))
+	return nil
+}
+
+// variable 返回已加载的全局变量
+func (l *BPFLoader) variable(name string) (*ebpf.Variable, error) {
+	if l.Collection == nil {
+		return nil, fmt.Errorf("BPF programs are not loaded")
+	}
+
+	variable, ok := l.Collection.Variables[name]
+	if !ok {
+		return nil, fmt.Errorf("variable %s: %w", name, meta.ErrUnknownVariable)
+	}
+
+	return variable, nil
+}
+
+// readVariable 读取变量内存并按 BTF 解码
+func readVariable(name string, variable *ebpf.Variable) (*VariableValue, error) {
+	if variable.Type() == nil {
+		return nil, fmt.Errorf("variable %s has no BTF", name)
+	}
+
+	buf := make([]byte, variable.Size())
+	if err := variable.Get(buf); err != nil {
+		return nil, fmt.Errorf("get variable %s failed: %w", name, err)
+	}
+
+	typ := btf.UnderlyingType(variable.Type().Type)
+	value, err := export.DumpToJson(typ, buf)
+	if err != nil {
+		return nil, fmt.Errorf("decode variable %s failed: %w", name, err)
+	}
+
+	return &VariableValue{
+		Name:     name,
+		Type:     variable.Type().Type.TypeName(),
+		ReadOnly: variable.ReadOnly(),
+		Value:    value,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// skelDoc 骨架 JSON 中与命令行相关的部分
+type skelDoc struct {
+	BpfSkel struct {
+		Doc          *BpfSkelDoc       `json:"doc"`
+		DataSections []DataSectionMeta `json:"data_sections"`
+	} `json:"bpf_skel"`
+}
+
+// MergeSkelDoc 从骨架 JSON 中读取 doc 和变量的 cmdarg，已有的配置优先
+func (p *Properties) MergeSkelDoc(data []byte) error {
+	var doc skelDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse skeleton doc: %w", err)
+	}
+
+	if p.Doc == nil {
+		p.Doc = doc.BpfSkel.Doc
+	}
+
+	for _, section := range doc.BpfSkel.DataSections {
+		for _, variable := range section.Variables {
+			if _, ok := p.CmdArgs[variable.Name]; ok {
+				continue
+			}
+
+			cmdArg := variable.CmdArg
+			if cmdArg.Help == "" {
+				cmdArg.Help = variable.Description
+			}
+
+			if cmdArg == (VariableCommandArgument{}) {
+				continue
+			}
+
+			if p.CmdArgs == nil {
+				p.CmdArgs = make(map[string]VariableCommandArgument)
+			}
+			p.CmdArgs[variable.Name] = cmdArg
+		}
+	}
+
+	return nil
+}
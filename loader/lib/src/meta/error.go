@@ -28,4 +28,12 @@ var (
 	ErrMapNotRunning           = errors.New("the map is not running")
 	ErrLoopbackDisabled        = errors.New("loopback is disabled")
 	ErrMissingEditorFlags      = errors.New("missing editor flags in map editor")
+	ErrLsmHookNotFound         = errors.New("lsm hook not found")
+	ErrBpfLsmDisabled          = errors.New("bpf lsm is not enabled")
+	ErrUnknownVariable         = errors.New("unknown global variable")
+	ErrXDPReplaceWithoutPin    = errors.New("xdp replace requires a link pin path")
+	ErrUnsignedPackage         = errors.New("package is not signed")
+	ErrUntrustedPackage        = errors.New("package signature is not trusted")
+	ErrPackageTampered         = errors.New("package object does not match its manifest")
+	ErrUnsupportedArch         = errors.New("package does not support the current architecture")
 )
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/cilium/ebpf"
@@ -51,15 +52,59 @@ func GenerateMeta(objectFile []byte, properties Properties) (*EunomiaObjectMeta,
 		return nil, fmt.Errorf("加载 ELF 文件失败: %v", err)
 	}
 
-	// 解析数据段信息
+	// 检查配置的变量值与 BTF Datasec 是否匹配
+	for name, raw := range properties.Variables {
+		varSpec, err := CheckVariable(spec, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := EncodeVariable(varSpec.Type().Type, raw, nil); err != nil {
+			return nil, fmt.Errorf("encode variable %s: %w", name, err)
+		}
+	}
+
+	for name, cmdArg := range properties.CmdArgs {
+		varSpec, err := CheckVariable(spec, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if cmdArg.Default == nil {
+			continue
+		}
+
+		if _, err := EncodeVariable(varSpec.Type().Type, *cmdArg.Default, nil); err != nil {
+			return nil, fmt.Errorf("encode default value of variable %s: %w", name, err)
+		}
+	}
+
+	// 解析数据段信息，按所属的数据段分组
+	sectionIndex := make(map[string]int)
 	dataSections := make([]DataSectionMeta, 0)
 
 	exportTypes := make([]ExportedTypesStructMeta, 0)
 	// 处理全局变量
-	for name, varSpec := range spec.Variables {
-		section := DataSectionMeta{
-			Name:      name,
-			Variables: make([]DataSectionVariableMeta, 0),
+	names := make([]string, 0, len(spec.Variables))
+	for name := range spec.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		varSpec := spec.Variables[name]
+		if varSpec.Type() == nil {
+			continue
+		}
+
+		idx, ok := sectionIndex[varSpec.MapName()]
+		if !ok {
+			idx = len(dataSections)
+			sectionIndex[varSpec.MapName()] = idx
+			dataSections = append(dataSections, DataSectionMeta{
+				Name:      varSpec.MapName(),
+				Variables: make([]DataSectionVariableMeta, 0),
+			})
 		}
 
 		// 获取类型信息
@@ -98,11 +143,16 @@ func GenerateMeta(objectFile []byte, properties Properties) (*EunomiaObjectMeta,
 
 		// 添加变量
 		varMeta := DataSectionVariableMeta{
-			Name: name,
-			Type: typeName,
+			Name:   name,
+			Type:   typeName,
+			CmdArg: properties.CmdArgs[name],
+		}
+		varMeta.Description = varMeta.CmdArg.Help
+		if raw, ok := properties.Variables[name]; ok {
+			value := raw
+			varMeta.Value = &value
 		}
-		section.Variables = append(section.Variables, varMeta)
-		dataSections = append(dataSections, section)
+		dataSections[idx].Variables = append(dataSections[idx].Variables, varMeta)
 	}
 
 	// 创建元数据结构
@@ -112,8 +162,9 @@ func GenerateMeta(objectFile []byte, properties Properties) (*EunomiaObjectMeta,
 			Maps:         convertMaps(spec.Maps, properties),
 			Progs:        convertProgs(spec.Programs, properties.Programs),
 			DataSections: dataSections,
+			Doc:          properties.Doc,
 		},
-		PerfBufferPages:  64,  // 默认值
+		PerfBufferPages:  DefaultPerfBufferPages,
 		PerfBufferTimeMs: 10,  // 默认值
 		PollTimeoutMs:    100, // 默认值
 	}
@@ -224,6 +275,7 @@ func needsLink(progType ebpf.ProgramType) bool {
 		ebpf.SkLookup,
 		ebpf.Syscall,
 		ebpf.Tracing,
+		ebpf.Extension,
 		ebpf.PerfEvent,
 		ebpf.CGroupSKB,
 		ebpf.CGroupDevice,
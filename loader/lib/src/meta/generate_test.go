@@ -2,6 +2,7 @@ package meta
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -104,8 +105,8 @@ func TestGenerateComposedObject(t *testing.T) {
 				return
 			}
 
-			// 将结果写入JSON文件
-			jsonFile := "../../../testdata/shepherd_x86_bpfel.json"
+			// 将结果写入临时的 JSON 文件，不覆盖 testdata 中的样例
+			jsonFile := filepath.Join(t.TempDir(), "shepherd_x86_bpfel.json")
 
 			if err := os.WriteFile(jsonFile, inner, 0644); err != nil {
 				t.Errorf("Failed to write JSON file: %v", err)
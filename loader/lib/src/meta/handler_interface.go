@@ -5,8 +5,22 @@ type EventHandler interface {
 	HandleEvent(ctx *UserContext, data *ReceivedEventData) error
 }
 
+// BatchEventHandler 可选的批量事件处理接口
+// EventHandler 同时实现该接口时，轮询器一次唤醒读到的所有事件会通过 HandleEvents 一次交付
+type BatchEventHandler interface {
+	EventHandler
+	HandleEvents(ctx *UserContext, data []*ReceivedEventData) error
+}
+
 // MetricsHandler 用于处理 eBPF 程序的运行时统计信息
 type MetricsHandler interface {
 	// Handle 处理统计信息
 	Handle(stats *MetricsStats) error
 }
+
+// MapMetricsHandler 可选的 map 事件统计处理接口
+// 指标处理器同时实现该接口时，定期收到每个 perf event 和 ring buffer map 的事件、丢失和丢弃计数
+type MapMetricsHandler interface {
+	MetricsHandler
+	HandleMapStats(stats *MapMetricsStats) error
+}
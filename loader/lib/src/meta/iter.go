@@ -0,0 +1,47 @@
+package meta
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// IterTarget 返回迭代器目标，例如 iter/task 返回 task
+func (p *ProgMeta) IterTarget() string {
+	_, target, _ := strings.Cut(p.Attach, "/")
+	return target
+}
+
+// ResolveIterMap 根据 MapName 从已加载的 map 中查找迭代器遍历的 map
+func (p *ProgMeta) ResolveIterMap(maps map[string]*ebpf.Map) error {
+	if p.Properties == nil || p.Properties.Iter == nil || p.Properties.Iter.MapName == "" {
+		return nil
+	}
+
+	m, ok := maps[p.Properties.Iter.MapName]
+	if !ok {
+		return fmt.Errorf("iterator %s map %s: %w", p.Name, p.Properties.Iter.MapName, ErrUnknownMap)
+	}
+
+	p.Properties.Iter.Map = m
+	return nil
+}
+
+func (p *ProgMeta) attachIter(program *ebpf.Program) (link.Link, error) {
+	opts := link.IterOptions{Program: program}
+	if p.Properties.Iter != nil {
+		opts.Map = p.Properties.Iter.Map
+	}
+
+	if p.IterTarget() == "bpf_map_elem" && opts.Map == nil {
+		return nil, fmt.Errorf("iterator %s requires Iter.MapName", p.Name)
+	}
+
+	iter, err := link.AttachIter(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error:%v, couldn't create iterator %s, matchFuncName:%s", err, p.Attach, p.Name)
+	}
+	return iter, nil
+}
@@ -0,0 +1,117 @@
+package meta
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	btfutils "github.com/cen-ngc5139/BeePF/loader/lib/src/btf"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+var (
+	// progNamePattern cilium/ebpf 加载集合失败时以 "program <name>: " 包装错误
+	progNamePattern = regexp.MustCompile(`program ([^\s:]+): `)
+
+	// verifierInsnPattern 校验器日志中的指令行，如 "12: (85) call bpf_probe_read#4"
+	verifierInsnPattern = regexp.MustCompile(`^(\d+): \([0-9a-f]{2}\)`)
+)
+
+// LoadError 程序加载失败的结构化信息，保留校验器日志、失败指令和 CO-RE 重定位失败
+type LoadError struct {
+	// ProgName 加载失败的程序，失败与具体程序无关时为空
+	ProgName string `json:"prog_name,omitempty"`
+
+	// Message 原始错误信息
+	Message string `json:"message"`
+
+	// VerifierLog 完整的校验器日志
+	VerifierLog []string `json:"verifier_log,omitempty"`
+
+	// FailedInsn 校验器报错的指令下标，-1 表示未知
+	FailedInsn int `json:"failed_insn"`
+
+	// Source 失败指令对应的源码行，对象文件没有 BTF 行信息时为空
+	Source *SourceLine `json:"source,omitempty"`
+
+	// CORE 在当前内核上无法完成的 CO-RE 重定位
+	CORE []btfutils.CORERelocationFailure `json:"core,omitempty"`
+
+	cause error
+}
+
+// SourceLine 指令对应的源码位置
+type SourceLine struct {
+	File   string `json:"file"`
+	Line   uint32 `json:"line"`
+	Column uint32 `json:"column"`
+	Text   string `json:"text"`
+}
+
+// Error 实现 error 接口
+func (e *LoadError) Error() string {
+	return e.Message
+}
+
+// Unwrap 返回原始错误
+func (e *LoadError) Unwrap() error {
+	return e.cause
+}
+
+// NewLoadError 从 ebpf.NewCollectionWithOptions 返回的错误中提取结构化信息
+// kernel 为 CO-RE 重定位使用的内核 BTF，为空时不分析 CO-RE 重定位
+func NewLoadError(spec *ebpf.CollectionSpec, kernel *btf.Spec, err error) *LoadError {
+	loadErr := &LoadError{
+		Message:    err.Error(),
+		FailedInsn: -1,
+		cause:      err,
+	}
+
+	if match := progNamePattern.FindStringSubmatch(err.Error()); match != nil {
+		loadErr.ProgName = match[1]
+	}
+
+	var verifierErr *ebpf.VerifierError
+	if errors.As(err, &verifierErr) {
+		loadErr.VerifierLog = verifierErr.Log
+		loadErr.FailedInsn = failedInsn(verifierErr.Log)
+	}
+
+	progSpec := spec.Programs[loadErr.ProgName]
+	if progSpec == nil {
+		return loadErr
+	}
+
+	if line, ok := btfutils.FindSourceLine(progSpec.Instructions, loadErr.FailedInsn); ok && loadErr.FailedInsn >= 0 {
+		loadErr.Source = &SourceLine{
+			File:   line.FileName(),
+			Line:   line.LineNumber(),
+			Column: line.LineColumn(),
+			Text:   line.Line(),
+		}
+	}
+
+	// 分析失败不影响原始错误
+	loadErr.CORE, _ = btfutils.CORERelocationFailures(progSpec, spec.Types, kernel)
+
+	return loadErr
+}
+
+// failedInsn 返回校验器日志中最后一条指令的下标，即校验器报错的指令
+func failedInsn(log []string) int {
+	for i := len(log) - 1; i >= 0; i-- {
+		match := verifierInsnPattern.FindStringSubmatch(log[i])
+		if match == nil {
+			continue
+		}
+
+		insn, err := strconv.Atoi(match[1])
+		if err != nil {
+			return -1
+		}
+		return insn
+	}
+
+	return -1
+}
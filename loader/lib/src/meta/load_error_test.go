@@ -0,0 +1,78 @@
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+func TestNewLoadError(t *testing.T) {
+	spec, err := ebpf.LoadCollectionSpec("../../../testdata/shepherd_x86_bpfel.o")
+	if err != nil {
+		t.Fatalf("LoadCollectionSpec() error = %v", err)
+	}
+
+	// 内核中的 task_struct 缺少程序访问的字段
+	builder, err := btf.NewBuilder([]btf.Type{&btf.Struct{Name: "task_struct", Size: 8}})
+	if err != nil {
+		t.Fatalf("NewBuilder() error = %v", err)
+	}
+	raw, err := builder.Marshal(nil, nil)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	kernel, err := btf.LoadSpecFromReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("LoadSpecFromReader() error = %v", err)
+	}
+
+	verifierErr := &ebpf.VerifierError{
+		Cause: errors.New("bad CO-RE relocation"),
+		Log: []string{
+			"0: R1=ctx() R10=fp0",
+			"0: (79) r1 = *(u64 *)(r1 +8)",
+			"1: (61) r1 = *(u32 *)(r1 +1456)",
+			"invalid func unknown#195896080",
+		},
+	}
+	err = fmt.Errorf("program sched_wakeup: load program: %w", verifierErr)
+
+	loadErr := NewLoadError(spec, kernel, err)
+	if loadErr.ProgName != "sched_wakeup" {
+		t.Errorf("ProgName = %q, want sched_wakeup", loadErr.ProgName)
+	}
+	if loadErr.FailedInsn != 1 {
+		t.Errorf("FailedInsn = %d, want 1", loadErr.FailedInsn)
+	}
+	if len(loadErr.VerifierLog) != 4 {
+		t.Errorf("VerifierLog = %v", loadErr.VerifierLog)
+	}
+	if loadErr.Source == nil || loadErr.Source.Line == 0 {
+		t.Errorf("Source = %+v, want source line", loadErr.Source)
+	}
+	if !errors.Is(loadErr, verifierErr) {
+		t.Errorf("LoadError does not wrap the verifier error")
+	}
+
+	if len(loadErr.CORE) == 0 {
+		t.Fatalf("CORE is empty, want relocation failures")
+	}
+	failure := loadErr.CORE[0]
+	if failure.Type != "task_struct" || failure.Field != "task_struct.pid" || failure.Kind != "byte_off" {
+		t.Errorf("CORE[0] = %+v, want byte_off on task_struct.pid", failure)
+	}
+}
+
+func TestNewLoadError_NotProgram(t *testing.T) {
+	loadErr := NewLoadError(&ebpf.CollectionSpec{}, nil, errors.New("map events: create: operation not permitted"))
+	if loadErr.ProgName != "" || loadErr.FailedInsn != -1 || loadErr.Source != nil {
+		t.Errorf("NewLoadError() = %+v, want message only", loadErr)
+	}
+	if loadErr.Error() != "map events: create: operation not permitted" {
+		t.Errorf("Error() = %q", loadErr.Error())
+	}
+}
@@ -0,0 +1,83 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+const (
+	// LsmHookPrefix 内核中 BPF LSM 钩子函数的名称前缀
+	LsmHookPrefix = "bpf_lsm_"
+
+	// LsmConfigPath 当前启用的 LSM 列表
+	LsmConfigPath = "/sys/kernel/security/lsm"
+)
+
+// LsmHook 从附加点中解析 LSM 钩子名称，支持 lsm/<hook> 和 lsm.s/<hook>
+func (p *ProgMeta) LsmHook() (string, error) {
+	section, hook, ok := strings.Cut(p.Attach, "/")
+	if !ok || hook == "" || (section != "lsm" && section != "lsm.s") {
+		return "", fmt.Errorf("error:%v, expected SEC(\"lsm/[hook]\") got %s", ErrSectionFormat, p.Attach)
+	}
+
+	return hook, nil
+}
+
+// CheckLsm 在加载前检查 LSM 程序能否附加：
+// 1. 钩子对应的 bpf_lsm_* 函数存在于内核 BTF 中
+// 2. bpf 已在 /sys/kernel/security/lsm 中启用
+func (p *ProgMeta) CheckLsm(kernelSpec *btf.Spec) error {
+	hook, err := p.LsmHook()
+	if err != nil {
+		return err
+	}
+
+	if err := checkLsmHook(kernelSpec, hook); err != nil {
+		return err
+	}
+
+	return checkBpfLsmEnabled(LsmConfigPath)
+}
+
+// checkLsmHook 检查内核 BTF 中是否存在钩子对应的函数
+func checkLsmHook(kernelSpec *btf.Spec, hook string) error {
+	if kernelSpec == nil {
+		var err error
+		kernelSpec, err = btf.LoadKernelSpec()
+		if err != nil {
+			return fmt.Errorf("load kernel BTF error: %w", err)
+		}
+	}
+
+	var fn *btf.Func
+	err := kernelSpec.TypeByName(LsmHookPrefix+hook, &fn)
+	if errors.Is(err, btf.ErrNotFound) {
+		return fmt.Errorf("%w: %s%s is not defined in kernel BTF", ErrLsmHookNotFound, LsmHookPrefix, hook)
+	}
+	if err != nil {
+		return fmt.Errorf("lookup lsm hook %s error: %w", hook, err)
+	}
+
+	return nil
+}
+
+// checkBpfLsmEnabled 检查 bpf 是否在启用的 LSM 列表中
+func checkBpfLsmEnabled(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: read %s error: %v", ErrBpfLsmDisabled, path, err)
+	}
+
+	lsms := strings.Split(strings.TrimSpace(string(data)), ",")
+	for _, lsm := range lsms {
+		if lsm == "bpf" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: active lsm list is %q, add \"bpf\" to the lsm= boot parameter", ErrBpfLsmDisabled, strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,98 @@
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+func TestProgMeta_LsmHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		attach  string
+		want    string
+		wantErr bool
+	}{
+		{name: "lsm", attach: "lsm/file_open", want: "file_open"},
+		{name: "sleepable", attach: "lsm.s/bprm_committed_creds", want: "bprm_committed_creds"},
+		{name: "no hook", attach: "lsm/", wantErr: true},
+		{name: "not lsm", attach: "kprobe/file_open", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ProgMeta{Name: tt.name, Attach: tt.attach}
+			got, err := p.LsmHook()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LsmHook() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("LsmHook() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLsmHook(t *testing.T) {
+	builder, err := btf.NewBuilder([]btf.Type{
+		&btf.Func{Name: "bpf_lsm_file_open", Type: &btf.FuncProto{Return: &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}}},
+	})
+	if err != nil {
+		t.Fatalf("NewBuilder() error = %v", err)
+	}
+
+	raw, err := builder.Marshal(nil, nil)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	spec, err := btf.LoadSpecFromReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("LoadSpecFromReader() error = %v", err)
+	}
+
+	if err := checkLsmHook(spec, "file_open"); err != nil {
+		t.Errorf("checkLsmHook() error = %v, want nil", err)
+	}
+
+	if err := checkLsmHook(spec, "not_exist"); !errors.Is(err, ErrLsmHookNotFound) {
+		t.Errorf("checkLsmHook() error = %v, want %v", err, ErrLsmHookNotFound)
+	}
+}
+
+func TestCheckBpfLsmEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "enabled", content: "lockdown,capability,landlock,yama,apparmor,bpf\n"},
+		{name: "disabled", content: "lockdown,capability,yama,apparmor\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "lsm")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			err := checkBpfLsmEnabled(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkBpfLsmEnabled() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, ErrBpfLsmDisabled) {
+				t.Errorf("checkBpfLsmEnabled() error = %v, want %v", err, ErrBpfLsmDisabled)
+			}
+		})
+	}
+
+	if err := checkBpfLsmEnabled(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, ErrBpfLsmDisabled) {
+		t.Errorf("checkBpfLsmEnabled() error = %v, want %v", err, ErrBpfLsmDisabled)
+	}
+}
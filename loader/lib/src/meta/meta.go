@@ -20,6 +20,9 @@ type EunomiaObjectMeta struct {
 	// PerfBufferPages perf 缓冲区的页数，默认为 64
 	PerfBufferPages uint `json:"perf_buffer_pages,omitempty"`
 
+	// PerfWatermark perf 缓冲区积累到该字节数时才唤醒读取，默认为 0，即每条事件都唤醒
+	PerfWatermark uint `json:"perf_watermark,omitempty"`
+
 	// PerfBufferTimeMs perf 缓冲区的超时时间（毫秒），默认为 10
 	PerfBufferTimeMs uint `json:"perf_buffer_time_ms,omitempty"`
 
@@ -38,6 +41,52 @@ type EunomiaObjectMeta struct {
 	EnableMultiExportTypes bool `json:"enable_multiple_export_types,omitempty"`
 }
 
+// DefaultPerfBufferPages perf 缓冲区默认的页数
+const DefaultPerfBufferPages = 64
+
+// PerfBufferOptions perf event map 的读取配置
+type PerfBufferOptions struct {
+	// Pages 每个 CPU 缓冲区的页数
+	Pages int
+
+	// Watermark 缓冲区积累到该字节数时才唤醒读取
+	Watermark int
+
+	// WakeupEvents 缓冲区积累到该事件数时才唤醒读取，与 Watermark 互斥
+	WakeupEvents int
+}
+
+// PerfBufferOptions 返回 map 的 perf 缓冲区配置，map 的 MapProperties 优先于全局元数据
+func (m *EunomiaObjectMeta) PerfBufferOptions(mapName string) PerfBufferOptions {
+	opts := PerfBufferOptions{
+		Pages:     int(m.PerfBufferPages),
+		Watermark: int(m.PerfWatermark),
+	}
+
+	if mapMeta, ok := m.BpfSkel.Maps[mapName]; ok && mapMeta.Properties != nil {
+		props := mapMeta.Properties
+		if props.PerfBufferPages > 0 {
+			opts.Pages = props.PerfBufferPages
+		}
+		if props.PerfWatermark > 0 {
+			opts.Watermark = props.PerfWatermark
+		}
+		// map 只配置了 WakeupEvents 时覆盖全局的 Watermark
+		if props.PerfWakeupEvents > 0 {
+			opts.WakeupEvents = props.PerfWakeupEvents
+			if props.PerfWatermark == 0 {
+				opts.Watermark = 0
+			}
+		}
+	}
+
+	if opts.Pages <= 0 {
+		opts.Pages = DefaultPerfBufferPages
+	}
+
+	return opts
+}
+
 // ExportedTypesStructMeta 导出类型结构定义
 // 描述导出到用户空间的数据结构类型
 type ExportedTypesStructMeta struct {
@@ -166,7 +215,7 @@ type MapMeta struct {
 // MapSampleMeta Map 采样元数据
 // 用于 map 采样的额外配置
 type MapSampleMeta struct {
-	// Interval 采样间隔（毫秒）
+	// Interval 采样间隔（毫秒），每个 map 按自己的间隔独立采样，为 0 时为 1000
 	Interval uint `json:"interval"`
 
 	// Type map 类型
@@ -175,10 +224,42 @@ type MapSampleMeta struct {
 	// Unit 打印直方图时的单位
 	Unit string `json:"unit"`
 
-	// ClearMap 采样完成后是否清理 map
+	// ClearMap 读取后是否清理 map，hash 类 map 删除条目，array 类 map 将值置零
 	ClearMap bool `json:"clear_map"`
+
+	// PerCPU per-CPU map 各 CPU 值的输出方式，为空时为 PerCPUSum
+	PerCPU PerCPUMode `json:"per_cpu,omitempty"`
+
+	// Mode 采样方式，为空时每次输出完整快照
+	Mode SampleMode `json:"mode,omitempty"`
 }
 
+// SampleMode map 采样方式
+type SampleMode string
+
+const (
+	// SampleModeSnapshot 每次采样输出所有键的当前值
+	SampleModeSnapshot SampleMode = "snapshot"
+
+	// SampleModeDelta 保留上一次快照，输出数值字段的差值和每秒速率，消失的键作为淘汰输出
+	// 用于不能清理的共享或 pin 住的计数 map
+	SampleModeDelta SampleMode = "delta"
+)
+
+// PerCPUMode per-CPU map 的输出方式
+type PerCPUMode string
+
+const (
+	// PerCPUSum 输出各 CPU 数值字段之和
+	PerCPUSum PerCPUMode = "sum"
+
+	// PerCPUMax 输出各 CPU 数值字段的最大值
+	PerCPUMax PerCPUMode = "max"
+
+	// PerCPUBreakdown 按 CPU 顺序输出每个 CPU 的值
+	PerCPUBreakdown PerCPUMode = "cpus"
+)
+
 // SampleMapType 采样 Map 类型
 type SampleMapType string
 
@@ -359,6 +440,7 @@ const (
 	TaskStatusRunning
 	TaskStatusSuccess
 	TaskStatusFailed
+	TaskStatusDisabled
 )
 
 // prog attach status
@@ -367,6 +449,15 @@ type ProgAttachStatus struct {
 	AttachID uint32     `json:"attach_id"`
 	Status   TaskStatus `json:"status"`
 	Error    string     `json:"error"`
+
+	// Mechanism 实际使用的附加机制，例如 tcx 或 netlink
+	Mechanism string `json:"mechanism,omitempty"`
+
+	// AttachCount 附加的函数数量，kprobe.multi 等一个程序附加多个函数时大于 1
+	AttachCount int `json:"attach_count,omitempty"`
+
+	// Optional 程序是否可选，可选程序附加失败不影响其余程序
+	Optional bool `json:"optional,omitempty"`
 }
 
 // FindMapByIdent 通过标识符查找 Map
@@ -0,0 +1,40 @@
+package meta
+
+import "testing"
+
+func TestEunomiaObjectMeta_PerfBufferOptions(t *testing.T) {
+	objectMeta := &EunomiaObjectMeta{
+		BpfSkel: BpfSkeletonMeta{
+			Maps: map[string]*MapMeta{
+				"plain":  {Name: "plain"},
+				"pages":  {Name: "pages", Properties: &MapProperties{PerfBufferPages: 8, PerfWatermark: 512}},
+				"events": {Name: "events", Properties: &MapProperties{PerfWakeupEvents: 16}},
+			},
+		},
+		PerfBufferPages: 32,
+		PerfWatermark:   128,
+	}
+
+	tests := []struct {
+		name    string
+		mapName string
+		want    PerfBufferOptions
+	}{
+		{name: "global", mapName: "plain", want: PerfBufferOptions{Pages: 32, Watermark: 128}},
+		{name: "unknown map", mapName: "missing", want: PerfBufferOptions{Pages: 32, Watermark: 128}},
+		{name: "map overrides", mapName: "pages", want: PerfBufferOptions{Pages: 8, Watermark: 512}},
+		{name: "wakeup events replace global watermark", mapName: "events", want: PerfBufferOptions{Pages: 32, WakeupEvents: 16}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := objectMeta.PerfBufferOptions(tt.mapName); got != tt.want {
+				t.Errorf("PerfBufferOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (&EunomiaObjectMeta{}).PerfBufferOptions("plain"); got.Pages != DefaultPerfBufferPages {
+		t.Errorf("PerfBufferOptions() pages = %d, want default %d", got.Pages, DefaultPerfBufferPages)
+	}
+}
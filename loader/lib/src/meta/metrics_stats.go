@@ -80,3 +80,56 @@ func (s *MetricsStats) Clone() *MetricsStats {
 	clone := *s
 	return &clone
 }
+
+// MapMetricsStats perf event 或 ring buffer map 的事件统计，计数从轮询开始累计
+type MapMetricsStats struct {
+	// Name map 名称
+	Name string `json:"name"`
+
+	// Type map 类型
+	Type string `json:"type"`
+
+	// Received 读到的事件数
+	Received uint64 `json:"received"`
+
+	// Lost 内核因缓冲区已满丢弃的事件数，只有 perf event map 能够统计
+	Lost uint64 `json:"lost"`
+
+	// Dropped 读到后处理失败而丢弃的事件数
+	Dropped uint64 `json:"dropped"`
+
+	// CPUs 按 CPU 的统计，ring buffer 不区分 CPU，只有一项 CPU 为 -1
+	CPUs []MapCPUStats `json:"cpus"`
+
+	// Pipeline 事件处理流水线的统计，未配置流水线时为空
+	Pipeline *PipelineStats `json:"pipeline,omitempty"`
+
+	// LastUpdate 最后更新时间
+	LastUpdate time.Time `json:"last_update"`
+}
+
+// MapCPUStats 单个 CPU 上的事件统计
+type MapCPUStats struct {
+	CPU      int    `json:"cpu"`
+	Received uint64 `json:"received"`
+	Lost     uint64 `json:"lost"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// PipelineStats 事件处理流水线的队列统计
+type PipelineStats struct {
+	// QueueDepth 当前排队的事件数
+	QueueDepth int `json:"queue_depth"`
+
+	// QueueCapacity 队列容量
+	QueueCapacity int `json:"queue_capacity"`
+
+	// Workers 解码工作协程数
+	Workers int `json:"workers"`
+
+	// Dropped 队列已满时按策略丢弃的事件数
+	Dropped uint64 `json:"dropped"`
+
+	// Failed 工作协程处理失败的事件数
+	Failed uint64 `json:"failed"`
+}
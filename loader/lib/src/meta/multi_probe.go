@@ -0,0 +1,440 @@
+package meta
+
+import (
+	"bufio"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+)
+
+// AvailableFilterFunctionsPaths 可被 kprobe 附加的内核函数列表，依次尝试 tracefs 和 debugfs
+var AvailableFilterFunctionsPaths = []string{
+	"/sys/kernel/tracing/available_filter_functions",
+	"/sys/kernel/debug/tracing/available_filter_functions",
+}
+
+// ProbeSetLink 一组通过同一程序附加的探针
+// 内核支持 kprobe.multi/uprobe.multi 时只包含一个 multi link，否则每个函数一个 link
+type ProbeSetLink struct {
+	// 嵌入接口仅用于满足 link.Link，所有方法均由 ProbeSetLink 自行实现
+	link.Link
+
+	links     []link.Link
+	symbols   []string
+	failed    int
+	multi     bool
+	mechanism string
+}
+
+// Count 返回成功附加的函数数量
+func (l *ProbeSetLink) Count() int {
+	if l.multi {
+		return len(l.symbols)
+	}
+	return len(l.links)
+}
+
+// Failed 返回回退到逐个附加时附加失败的函数数量
+func (l *ProbeSetLink) Failed() int {
+	return l.failed
+}
+
+// Symbols 返回匹配到的函数列表
+func (l *ProbeSetLink) Symbols() []string {
+	return l.symbols
+}
+
+// Mechanism 返回实际使用的附加机制
+func (l *ProbeSetLink) Mechanism() string {
+	return l.mechanism
+}
+
+// Update multi link 与 perf event link 均不支持替换程序
+func (l *ProbeSetLink) Update(*ebpf.Program) error {
+	return fmt.Errorf("update probe set: %w", link.ErrNotSupported)
+}
+
+// Pin 仅 multi link 支持 pin
+func (l *ProbeSetLink) Pin(fileName string) error {
+	if !l.multi {
+		return fmt.Errorf("pin per-function probes: %w", link.ErrNotSupported)
+	}
+	return l.links[0].Pin(fileName)
+}
+
+// Unpin 仅 multi link 可能被 pin
+func (l *ProbeSetLink) Unpin() error {
+	if !l.multi {
+		return nil
+	}
+	return l.links[0].Unpin()
+}
+
+// Info 仅 multi link 有 bpf_link 信息
+func (l *ProbeSetLink) Info() (*link.Info, error) {
+	if !l.multi {
+		return nil, fmt.Errorf("per-function probes info: %w", link.ErrNotSupported)
+	}
+	return l.links[0].Info()
+}
+
+// Close 关闭所有探针
+func (l *ProbeSetLink) Close() error {
+	var errs []error
+	for _, pl := range l.links {
+		if err := pl.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	l.links = nil
+	return errors.Join(errs...)
+}
+
+// PrepareMultiProbes 在加载前决定 kprobe.multi/uprobe.multi 程序的附加方式
+// 内核不支持 multi link 时清除程序的 multi 附加类型，程序以普通 kprobe 加载，附加时为每个函数创建一个 link
+func PrepareMultiProbes(spec *ebpf.CollectionSpec) {
+	for _, progSpec := range spec.Programs {
+		switch progSpec.AttachType {
+		case ebpf.AttachTraceKprobeMulti, ebpf.AttachTraceUprobeMulti:
+			if !useMultiLink(progSpec.AttachType) {
+				progSpec.AttachType = ebpf.AttachNone
+			}
+		}
+	}
+}
+
+var (
+	multiLinkMu      sync.Mutex
+	multiLinkSupport = make(map[ebpf.AttachType]error)
+)
+
+// useMultiLink 内核是否支持 attachType 对应的 multi link
+// 探测结果会被缓存，加载前和附加时得到相同的结论；探测出现其他错误时仍使用 multi link，由附加报告错误
+func useMultiLink(attachType ebpf.AttachType) bool {
+	multiLinkMu.Lock()
+	defer multiLinkMu.Unlock()
+
+	err, ok := multiLinkSupport[attachType]
+	if !ok {
+		err = probeMultiLink(attachType)
+		multiLinkSupport[attachType] = err
+	}
+
+	return !errors.Is(err, link.ErrNotSupported)
+}
+
+// probeMultiLink 使用空程序创建一次 multi link，探测内核是否支持
+func probeMultiLink(attachType ebpf.AttachType) error {
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:       "probe_multi",
+		Type:       ebpf.Kprobe,
+		AttachType: attachType,
+		Instructions: asm.Instructions{
+			asm.Mov.Imm(asm.R0, 0),
+			asm.Return(),
+		},
+		License: "MIT",
+	})
+	if errors.Is(err, unix.E2BIG) {
+		// 内核不支持 expected_attach_type
+		return link.ErrNotSupported
+	}
+	if err != nil {
+		return fmt.Errorf("load probe program error: %w", err)
+	}
+	defer prog.Close()
+
+	var l link.Link
+	switch attachType {
+	case ebpf.AttachTraceKprobeMulti:
+		l, err = link.KprobeMulti(prog, link.KprobeMultiOptions{Symbols: []string{"vprintk"}})
+	case ebpf.AttachTraceUprobeMulti:
+		var ex *link.Executable
+		ex, err = link.OpenExecutable("/proc/self/exe")
+		if err != nil {
+			return err
+		}
+		// 支持 uprobe.multi 的内核在检查 pid 时返回 ESRCH，旧内核不认识该附加类型，直接返回 EINVAL
+		l, err = ex.UprobeMulti(nil, prog, &link.UprobeMultiOptions{Addresses: []uint64{1}, PID: math.MaxInt32})
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("attach type %s is not a multi probe", attachType)
+	}
+
+	if errors.Is(err, unix.EINVAL) {
+		return link.ErrNotSupported
+	}
+	if err != nil {
+		return err
+	}
+
+	return l.Close()
+}
+
+// attachProbeSet attachMulti 不为空时使用 multi link 附加所有函数，否则为每个函数创建一个 link
+// 逐个附加时跳过无法附加的函数，只有全部失败时才返回错误
+func attachProbeSet(symbols []string, attachMulti func([]string) (link.Link, error), attachOne func(string) (link.Link, error), mechanism string) (*ProbeSetLink, error) {
+	if attachMulti != nil {
+		l, err := attachMulti(symbols)
+		if err != nil {
+			return nil, err
+		}
+		return &ProbeSetLink{links: []link.Link{l}, symbols: symbols, multi: true, mechanism: mechanism}, nil
+	}
+
+	set := &ProbeSetLink{symbols: symbols, mechanism: AttachMechanismPerfEvent}
+	var firstErr error
+	for _, symbol := range symbols {
+		pl, err := attachOne(symbol)
+		if err != nil {
+			set.failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("symbol %s: %w", symbol, err)
+			}
+			continue
+		}
+		set.links = append(set.links, pl)
+	}
+
+	if len(set.links) == 0 {
+		return nil, fmt.Errorf("no function attached in fallback mode, first error: %w", firstErr)
+	}
+
+	return set, nil
+}
+
+func (p *ProgMeta) attachKprobeMulti(program *ebpf.Program, isRet bool) (link.Link, error) {
+	var patterns []string
+	if _, pattern, ok := strings.Cut(p.Attach, "/"); ok && pattern != "" {
+		patterns = append(patterns, pattern)
+	}
+	if p.Properties.KprobeMulti != nil {
+		patterns = append(patterns, p.Properties.KprobeMulti.Symbols...)
+	}
+
+	symbols, err := resolveKernelSymbols(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("resolve kprobe.multi symbols of %s error: %w", p.Name, err)
+	}
+
+	var attachMulti func([]string) (link.Link, error)
+	if useMultiLink(ebpf.AttachTraceKprobeMulti) {
+		attachMulti = func(symbols []string) (link.Link, error) {
+			opts := link.KprobeMultiOptions{Symbols: symbols}
+			if isRet {
+				return link.KretprobeMulti(program, opts)
+			}
+			return link.KprobeMulti(program, opts)
+		}
+	}
+	attachOne := func(symbol string) (link.Link, error) {
+		if isRet {
+			return link.Kretprobe(symbol, program, nil)
+		}
+		return link.Kprobe(symbol, program, nil)
+	}
+
+	set, err := attachProbeSet(symbols, attachMulti, attachOne, AttachMechanismKprobeMulti)
+	if err != nil {
+		return nil, fmt.Errorf("opening Kprobe multi: %s, functions:%d, isRet:%t, section:%s", err, len(symbols), isRet, p.Attach)
+	}
+
+	return set, nil
+}
+
+func (p *ProgMeta) attachUprobeMulti(program *ebpf.Program, isRet bool) (link.Link, error) {
+	var binPath string
+	var patterns []string
+
+	// 节名称格式为 uprobe.multi/path:pattern，也可以只通过 Properties.Uprobe 配置
+	if _, target, ok := strings.Cut(p.Attach, "/"); ok && target != "" {
+		if i := strings.LastIndex(target, ":"); i > 0 {
+			binPath = target[:i]
+			patterns = append(patterns, target[i+1:])
+		} else {
+			binPath = target
+		}
+	}
+
+	var pid int
+	if up := p.Properties.Uprobe; up != nil {
+		if up.BinPath != "" {
+			binPath = up.BinPath
+		}
+		if up.Symbol != "" {
+			patterns = append(patterns, up.Symbol)
+		}
+		patterns = append(patterns, up.Symbols...)
+		pid = up.PID
+	}
+
+	if binPath == "" {
+		return nil, fmt.Errorf("prog %s invalid uprobe.multi properties: binary path is required", p.Name)
+	}
+
+	symbols, err := resolveBinarySymbols(binPath, patterns)
+	if err != nil {
+		return nil, fmt.Errorf("resolve uprobe.multi symbols of %s error: %w", p.Name, err)
+	}
+
+	ex, err := link.OpenExecutable(binPath)
+	if err != nil {
+		return nil, fmt.Errorf("error:%v , couldn't enable uprobe.multi %s", err, binPath)
+	}
+
+	var attachMulti func([]string) (link.Link, error)
+	if useMultiLink(ebpf.AttachTraceUprobeMulti) {
+		attachMulti = func(symbols []string) (link.Link, error) {
+			opts := &link.UprobeMultiOptions{PID: uint32(pid)}
+			if isRet {
+				return ex.UretprobeMulti(symbols, program, opts)
+			}
+			return ex.UprobeMulti(symbols, program, opts)
+		}
+	}
+	attachOne := func(symbol string) (link.Link, error) {
+		opts := &link.UprobeOptions{PID: pid}
+		if isRet {
+			return ex.Uretprobe(symbol, program, opts)
+		}
+		return ex.Uprobe(symbol, program, opts)
+	}
+
+	set, err := attachProbeSet(symbols, attachMulti, attachOne, AttachMechanismUprobeMulti)
+	if err != nil {
+		return nil, fmt.Errorf("opening Uprobe multi failed: %v, binary:%s, functions:%d, isRet:%t", err, binPath, len(symbols), isRet)
+	}
+
+	return set, nil
+}
+
+// resolveKernelSymbols 将 glob 模式解析为 available_filter_functions 中的内核函数
+func resolveKernelSymbols(patterns []string) ([]string, error) {
+	if !hasGlob(patterns) {
+		return matchSymbols(nil, patterns)
+	}
+
+	var lastErr error
+	for _, p := range AvailableFilterFunctionsPaths {
+		candidates, err := readFilterFunctions(p)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return matchSymbols(candidates, patterns)
+	}
+
+	return nil, fmt.Errorf("read available_filter_functions error: %w", lastErr)
+}
+
+// readFilterFunctions 读取可附加的内核函数，格式为 "name" 或 "name [module]"
+func readFilterFunctions(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var functions []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, _, _ := strings.Cut(scanner.Text(), " ")
+		if name != "" {
+			functions = append(functions, name)
+		}
+	}
+
+	return functions, scanner.Err()
+}
+
+// resolveBinarySymbols 将 glob 模式解析为二进制文件中的函数符号
+func resolveBinarySymbols(binPath string, patterns []string) ([]string, error) {
+	if !hasGlob(patterns) {
+		return matchSymbols(nil, patterns)
+	}
+
+	f, err := elf.Open(filepath.Clean(binPath))
+	if err != nil {
+		return nil, fmt.Errorf("open binary %s error: %w", binPath, err)
+	}
+	defer f.Close()
+
+	var candidates []string
+	for _, load := range []func() ([]elf.Symbol, error){f.Symbols, f.DynamicSymbols} {
+		syms, err := load()
+		if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+			return nil, fmt.Errorf("read symbols of %s error: %w", binPath, err)
+		}
+
+		for _, sym := range syms {
+			if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Value != 0 {
+				candidates = append(candidates, sym.Name)
+			}
+		}
+	}
+
+	return matchSymbols(candidates, patterns)
+}
+
+// matchSymbols 返回匹配任一模式的去重符号列表，不含通配符的模式原样保留
+func matchSymbols(candidates []string, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no symbol or pattern provided")
+	}
+
+	seen := make(map[string]struct{})
+	var symbols []string
+	add := func(s string) {
+		if _, ok := seen[s]; !ok {
+			seen[s] = struct{}{}
+			symbols = append(symbols, s)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if !hasGlob([]string{pattern}) {
+			add(pattern)
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		for _, c := range candidates {
+			if ok, _ := path.Match(pattern, c); ok {
+				add(c)
+			}
+		}
+	}
+
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("%w: no function matches %v", ErrSymbolNotFound, patterns)
+	}
+
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+func hasGlob(patterns []string) bool {
+	for _, p := range patterns {
+		if strings.ContainsAny(p, "*?[") {
+			return true
+		}
+	}
+	return false
+}
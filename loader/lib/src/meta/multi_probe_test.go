@@ -0,0 +1,123 @@
+package meta
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+func TestMatchSymbols(t *testing.T) {
+	candidates := []string{"tcp_sendmsg", "tcp_recvmsg", "tcp_v4_connect", "udp_sendmsg", "tcp_sendmsg"}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "glob", patterns: []string{"tcp_*msg"}, want: []string{"tcp_recvmsg", "tcp_sendmsg"}},
+		{name: "glob and exact", patterns: []string{"tcp_v4_*", "do_sys_open"}, want: []string{"do_sys_open", "tcp_v4_connect"}},
+		{name: "dedup", patterns: []string{"*_sendmsg", "tcp_sendmsg"}, want: []string{"tcp_sendmsg", "udp_sendmsg"}},
+		{name: "no match", patterns: []string{"ext4_*"}, wantErr: true},
+		{name: "bad pattern", patterns: []string{"tcp_["}, wantErr: true},
+		{name: "empty", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchSymbols(candidates, tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("matchSymbols() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchSymbols() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveBinarySymbols(t *testing.T) {
+	libs, _ := filepath.Glob("/usr/lib/*/libc.so.6")
+	if len(libs) == 0 {
+		t.Skip("libc.so.6 not found")
+	}
+
+	got, err := resolveBinarySymbols(libs[0], []string{"mallo?"})
+	if err != nil {
+		t.Fatalf("resolveBinarySymbols() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, []string{"malloc"}) {
+		t.Errorf("resolveBinarySymbols() got = %v, want [malloc]", got)
+	}
+}
+
+func TestAttachProbeSet(t *testing.T) {
+	symbols := []string{"a", "b", "c"}
+	fake := func(string) (link.Link, error) { return &ProbeSetLink{}, nil }
+
+	t.Run("multi", func(t *testing.T) {
+		set, err := attachProbeSet(symbols, func([]string) (link.Link, error) { return &ProbeSetLink{}, nil }, fake, AttachMechanismKprobeMulti)
+		if err != nil {
+			t.Fatalf("attachProbeSet() error = %v", err)
+		}
+
+		if set.Count() != 3 || set.Mechanism() != AttachMechanismKprobeMulti {
+			t.Errorf("attachProbeSet() count = %d, mechanism = %s", set.Count(), set.Mechanism())
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		partial := func(s string) (link.Link, error) {
+			if s == "b" {
+				return nil, errors.New("not traceable")
+			}
+			return &ProbeSetLink{}, nil
+		}
+
+		set, err := attachProbeSet(symbols, nil, partial, AttachMechanismKprobeMulti)
+		if err != nil {
+			t.Fatalf("attachProbeSet() error = %v", err)
+		}
+
+		if set.Count() != 2 || set.Failed() != 1 || set.Mechanism() != AttachMechanismPerfEvent {
+			t.Errorf("attachProbeSet() count = %d, failed = %d, mechanism = %s", set.Count(), set.Failed(), set.Mechanism())
+		}
+
+		if err := set.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+
+	t.Run("multi error", func(t *testing.T) {
+		failed := func([]string) (link.Link, error) { return nil, errors.New("EINVAL") }
+		if _, err := attachProbeSet(symbols, failed, fake, AttachMechanismKprobeMulti); err == nil {
+			t.Errorf("attachProbeSet() error = nil, want error")
+		}
+	})
+}
+
+func TestPrepareMultiProbes(t *testing.T) {
+	spec := &ebpf.CollectionSpec{Programs: map[string]*ebpf.ProgramSpec{
+		"kprobe": {Type: ebpf.Kprobe, AttachType: ebpf.AttachTraceKprobeMulti},
+		"uprobe": {Type: ebpf.Kprobe, AttachType: ebpf.AttachTraceUprobeMulti},
+		"single": {Type: ebpf.Kprobe},
+	}}
+
+	PrepareMultiProbes(spec)
+
+	// 加载前的决定与附加时一致：支持 multi link 时保留附加类型，否则清除
+	for name, want := range map[string]ebpf.AttachType{"kprobe": ebpf.AttachTraceKprobeMulti, "uprobe": ebpf.AttachTraceUprobeMulti} {
+		got := spec.Programs[name].AttachType
+		if useMultiLink(want) && got != want || !useMultiLink(want) && got != ebpf.AttachNone {
+			t.Errorf("%s attach type = %s, multi link supported = %t", name, got, useMultiLink(want))
+		}
+	}
+	if spec.Programs["single"].AttachType != ebpf.AttachNone {
+		t.Errorf("single attach type = %s, want none", spec.Programs["single"].AttachType)
+	}
+}
@@ -0,0 +1,395 @@
+package meta
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+)
+
+// PackageFormat 包文件格式版本
+const PackageFormat = "beepf-package/v1"
+
+// PackageManifest 包的描述信息，签名覆盖整个清单
+// 清单中记录了对象文件的摘要，因此签名同时保护对象文件
+type PackageManifest struct {
+	// Name 包名称
+	Name string `json:"name"`
+
+	// Version 包版本
+	Version string `json:"version"`
+
+	// Archs 支持的 GOARCH 列表，为空表示不限制
+	Archs []string `json:"archs,omitempty"`
+
+	// CreatedAt 打包时间
+	CreatedAt time.Time `json:"created_at"`
+
+	// ObjectSHA256 对象文件的 SHA256 摘要
+	ObjectSHA256 string `json:"object_sha256"`
+
+	// Doc 程序文档
+	Doc *BpfSkelDoc `json:"doc,omitempty"`
+
+	// CmdArgs 全局变量的命令行参数配置
+	CmdArgs map[string]VariableCommandArgument `json:"cmdargs,omitempty"`
+
+	// Variables 全局变量的默认值
+	Variables map[string]json.RawMessage `json:"variables,omitempty"`
+
+	// BTF 内嵌的最小化 BTF，键为 btfhub 目录结构中的相对路径，值为 SHA256 摘要
+	BTF map[string]string `json:"btf,omitempty"`
+}
+
+// packageFile 包文件的 JSON 结构
+//
+//	{
+//	   "format": "beepf-package/v1",
+//	   "manifest": {}, // 清单，签名的内容即该字段的原始字节
+//	   "bpf_object": "", // base64编码、zlib压缩的对象文件
+//	   "bpf_object_size": 0, // 未压缩的对象文件大小（字节）
+//	   "btf": {}, // 相对路径到 base64编码、zlib压缩的最小化 BTF
+//	   "signature": "" // base64编码的 ed25519 签名
+//	}
+type packageFile struct {
+	Format        string            `json:"format"`
+	Manifest      json.RawMessage   `json:"manifest"`
+	BpfObject     string            `json:"bpf_object"`
+	BpfObjectSize uint              `json:"bpf_object_size"`
+	BTF           map[string]string `json:"btf,omitempty"`
+	Signature     string            `json:"signature,omitempty"`
+}
+
+// Package 可分发的程序包，包含对象文件、清单和签名
+// 清单在 NewPackage 时序列化，之后修改 Manifest 不会改变签名和写入的内容
+type Package struct {
+	// Manifest 清单
+	Manifest PackageManifest
+
+	// Object 对象文件
+	Object []byte
+
+	// BTF 内嵌的最小化 BTF，用于没有 /sys/kernel/btf/vmlinux 的内核
+	BTF map[string][]byte
+
+	// Signature 清单的 ed25519 签名，未签名时为空
+	Signature []byte
+
+	// rawManifest 签名对应的清单字节
+	rawManifest []byte
+}
+
+// NewPackage 创建包并计算对象文件摘要
+func NewPackage(object []byte, manifest PackageManifest) (*Package, error) {
+	if manifest.Name == "" || manifest.Version == "" {
+		return nil, fmt.Errorf("package name and version are required")
+	}
+
+	if manifest.CreatedAt.IsZero() {
+		manifest.CreatedAt = time.Now().UTC()
+	}
+	manifest.ObjectSHA256 = objectDigest(object)
+	manifest.BTF = nil
+
+	pkg := &Package{Manifest: manifest, Object: object}
+	if err := pkg.marshalManifest(); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// SetBTF 设置内嵌的最小化 BTF 并重新生成清单，已有的签名随之失效
+func (p *Package) SetBTF(btfs map[string][]byte) error {
+	p.BTF = btfs
+	p.Manifest.BTF = nil
+	for path, data := range btfs {
+		if p.Manifest.BTF == nil {
+			p.Manifest.BTF = make(map[string]string, len(btfs))
+		}
+		p.Manifest.BTF[path] = objectDigest(data)
+	}
+
+	p.Signature = nil
+	return p.marshalManifest()
+}
+
+// marshalManifest 序列化清单，签名的内容即序列化结果
+func (p *Package) marshalManifest() error {
+	raw, err := json.Marshal(p.Manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest error: %w", err)
+	}
+	p.rawManifest = raw
+	return nil
+}
+
+// Sign 使用私钥签名清单
+func (p *Package) Sign(key ed25519.PrivateKey) {
+	p.Signature = ed25519.Sign(key, p.rawManifest)
+}
+
+// Verify 校验对象文件摘要和签名
+// 已签名的包在 trustedKeys 不为空时必须由其中一个公钥签名；
+// requireSignature 为 true 时拒绝未签名或无法校验签名的包
+func (p *Package) Verify(trustedKeys []ed25519.PublicKey, requireSignature bool) error {
+	if objectDigest(p.Object) != p.Manifest.ObjectSHA256 {
+		return ErrPackageTampered
+	}
+
+	if len(p.BTF) != len(p.Manifest.BTF) {
+		return ErrPackageTampered
+	}
+	for path, data := range p.BTF {
+		if digest, ok := p.Manifest.BTF[path]; !ok || objectDigest(data) != digest {
+			return ErrPackageTampered
+		}
+	}
+
+	if len(p.Signature) == 0 {
+		if requireSignature {
+			return ErrUnsignedPackage
+		}
+		return nil
+	}
+
+	if len(trustedKeys) == 0 {
+		if requireSignature {
+			return ErrUntrustedPackage
+		}
+		return nil
+	}
+
+	for _, key := range trustedKeys {
+		if ed25519.Verify(key, p.rawManifest, p.Signature) {
+			return nil
+		}
+	}
+
+	return ErrUntrustedPackage
+}
+
+// CheckArch 检查包是否支持当前架构
+func (p *Package) CheckArch() error {
+	if len(p.Manifest.Archs) == 0 || slices.Contains(p.Manifest.Archs, runtime.GOARCH) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s not in %v", ErrUnsupportedArch, runtime.GOARCH, p.Manifest.Archs)
+}
+
+// MergeProperties 将清单中的文档和变量合并到 properties，已有的配置优先
+func (p *Package) MergeProperties(properties *Properties) {
+	if properties.Doc == nil {
+		properties.Doc = p.Manifest.Doc
+	}
+
+	for name, cmdArg := range p.Manifest.CmdArgs {
+		if _, ok := properties.CmdArgs[name]; ok {
+			continue
+		}
+		if properties.CmdArgs == nil {
+			properties.CmdArgs = make(map[string]VariableCommandArgument)
+		}
+		properties.CmdArgs[name] = cmdArg
+	}
+
+	for name, value := range p.Manifest.Variables {
+		if _, ok := properties.Variables[name]; ok {
+			continue
+		}
+		if properties.Variables == nil {
+			properties.Variables = make(map[string]json.RawMessage)
+		}
+		properties.Variables[name] = value
+	}
+}
+
+// MarshalJSON 实现 json.Marshaler 接口
+func (p *Package) MarshalJSON() ([]byte, error) {
+	compressed, err := CompressZlib(p.Object)
+	if err != nil {
+		return nil, fmt.Errorf("compress object error: %w", err)
+	}
+
+	file := packageFile{
+		Format:        PackageFormat,
+		Manifest:      p.rawManifest,
+		BpfObject:     base64.StdEncoding.EncodeToString(compressed),
+		BpfObjectSize: uint(len(p.Object)),
+	}
+
+	for path, data := range p.BTF {
+		compressed, err := CompressZlib(data)
+		if err != nil {
+			return nil, fmt.Errorf("compress BTF %s error: %w", path, err)
+		}
+		if file.BTF == nil {
+			file.BTF = make(map[string]string, len(p.BTF))
+		}
+		file.BTF[path] = base64.StdEncoding.EncodeToString(compressed)
+	}
+
+	if len(p.Signature) > 0 {
+		file.Signature = base64.StdEncoding.EncodeToString(p.Signature)
+	}
+
+	return json.Marshal(file)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，不校验签名
+func (p *Package) UnmarshalJSON(data []byte) error {
+	var file packageFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("unmarshal package error: %w", err)
+	}
+
+	if file.Format != PackageFormat {
+		return fmt.Errorf("unsupported package format %q", file.Format)
+	}
+
+	var manifest PackageManifest
+	if err := json.Unmarshal(file.Manifest, &manifest); err != nil {
+		return fmt.Errorf("unmarshal manifest error: %w", err)
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(file.BpfObject)
+	if err != nil {
+		return fmt.Errorf("decode base64 error: %w", err)
+	}
+
+	object, err := DecompressZlib(compressed)
+	if err != nil {
+		return fmt.Errorf("decompress error: %w", err)
+	}
+
+	if uint(len(object)) != file.BpfObjectSize {
+		return fmt.Errorf("size mismatch: got %d, want %d", len(object), file.BpfObjectSize)
+	}
+
+	var btfs map[string][]byte
+	for path, encoded := range file.BTF {
+		compressed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("decode BTF %s error: %w", path, err)
+		}
+
+		data, err := DecompressZlib(compressed)
+		if err != nil {
+			return fmt.Errorf("decompress BTF %s error: %w", path, err)
+		}
+
+		if btfs == nil {
+			btfs = make(map[string][]byte, len(file.BTF))
+		}
+		btfs[path] = data
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(file.Signature)
+	if err != nil {
+		return fmt.Errorf("decode signature error: %w", err)
+	}
+
+	p.Manifest = manifest
+	p.Object = object
+	p.BTF = btfs
+	p.Signature = signature
+	p.rawManifest = bytes.Clone(file.Manifest)
+	return nil
+}
+
+// ReadPackage 读取包文件
+func ReadPackage(path string) (*Package, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read package error: %w", err)
+	}
+
+	pkg := &Package{}
+	if err := json.Unmarshal(data, pkg); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// WritePackage 写入包文件
+func WritePackage(path string, pkg *Package) error {
+	data, err := json.Marshal(pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// GenerateSigningKey 生成 PEM 编码的 ed25519 密钥对
+func GenerateSigningKey() (publicPEM, privatePEM []byte, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	privatePEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	return publicPEM, privatePEM, nil
+}
+
+// ParsePublicKey 解析 PEM 编码的 ed25519 公钥
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("no PEM public key found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key error: %w", err)
+	}
+
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is %T, not ed25519", key)
+	}
+	return public, nil
+}
+
+// ParsePrivateKey 解析 PEM 编码的 ed25519 私钥
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("no PEM private key found")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key error: %w", err)
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is %T, not ed25519", key)
+	}
+	return private, nil
+}
+
+func objectDigest(object []byte) string {
+	sum := sha256.Sum256(object)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,139 @@
+package meta
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestPackage_Verify(t *testing.T) {
+	publicPEM, privatePEM, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("GenerateSigningKey() error = %v", err)
+	}
+
+	public, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error = %v", err)
+	}
+
+	private, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	object := []byte("\x7fELF fake object")
+
+	// 序列化后再解析，模拟分发的包文件
+	roundTrip := func(t *testing.T, sign bool, mutate func(file map[string]json.RawMessage)) *Package {
+		pkg, err := NewPackage(object, PackageManifest{Name: "shepherd", Version: "1.0.0"})
+		if err != nil {
+			t.Fatalf("NewPackage() error = %v", err)
+		}
+		if err := pkg.SetBTF(map[string][]byte{"ubuntu/20.04/x86_64/5.4.0-42-generic.btf": []byte("minimized btf")}); err != nil {
+			t.Fatalf("SetBTF() error = %v", err)
+		}
+		if sign {
+			pkg.Sign(private)
+		}
+
+		data, err := json.Marshal(pkg)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+
+		var file map[string]json.RawMessage
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if mutate != nil {
+			mutate(file)
+		}
+		data, _ = json.Marshal(file)
+
+		got := &Package{}
+		if err := json.Unmarshal(data, got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		return got
+	}
+
+	tamperManifest := func(file map[string]json.RawMessage) {
+		file["manifest"] = bytes.Replace(file["manifest"], []byte(`"1.0.0"`), []byte(`"1.0.1"`), 1)
+	}
+
+	tamperObject := func(file map[string]json.RawMessage) {
+		pkg := Package{Object: []byte("\x7fELF evil object")}
+		data, _ := pkg.MarshalJSON()
+		var evil map[string]json.RawMessage
+		_ = json.Unmarshal(data, &evil)
+		file["bpf_object"] = evil["bpf_object"]
+		file["bpf_object_size"] = evil["bpf_object_size"]
+	}
+
+	tamperBTF := func(file map[string]json.RawMessage) {
+		pkg := Package{BTF: map[string][]byte{"ubuntu/20.04/x86_64/5.4.0-42-generic.btf": []byte("evil btf")}}
+		data, _ := pkg.MarshalJSON()
+		var evil map[string]json.RawMessage
+		_ = json.Unmarshal(data, &evil)
+		file["btf"] = evil["btf"]
+	}
+
+	tests := []struct {
+		name             string
+		sign             bool
+		mutate           func(file map[string]json.RawMessage)
+		keys             []ed25519.PublicKey
+		requireSignature bool
+		wantErr          error
+	}{
+		{name: "signed and trusted", sign: true, keys: []ed25519.PublicKey{public}, requireSignature: true},
+		{name: "signed by another key", sign: true, keys: []ed25519.PublicKey{other}, wantErr: ErrUntrustedPackage},
+		{name: "signed without trusted keys", sign: true},
+		{name: "signed without trusted keys required", sign: true, requireSignature: true, wantErr: ErrUntrustedPackage},
+		{name: "unsigned allowed", keys: []ed25519.PublicKey{public}},
+		{name: "unsigned required", keys: []ed25519.PublicKey{public}, requireSignature: true, wantErr: ErrUnsignedPackage},
+		{name: "tampered manifest", sign: true, mutate: tamperManifest, keys: []ed25519.PublicKey{public}, wantErr: ErrUntrustedPackage},
+		{name: "tampered object", sign: true, mutate: tamperObject, keys: []ed25519.PublicKey{public}, wantErr: ErrPackageTampered},
+		{name: "tampered unsigned object", mutate: tamperObject, wantErr: ErrPackageTampered},
+		{name: "tampered btf", sign: true, mutate: tamperBTF, keys: []ed25519.PublicKey{public}, wantErr: ErrPackageTampered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := roundTrip(t, tt.sign, tt.mutate)
+
+			err := pkg.Verify(tt.keys, tt.requireSignature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPackage_CheckArch(t *testing.T) {
+	tests := []struct {
+		name    string
+		archs   []string
+		wantErr bool
+	}{
+		{name: "any", archs: nil},
+		{name: "current", archs: []string{"riscv64", runtime.GOARCH}},
+		{name: "other", archs: []string{"riscv64"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := &Package{Manifest: PackageManifest{Archs: tt.archs}}
+			if err := pkg.CheckArch(); (err != nil) != tt.wantErr {
+				t.Errorf("CheckArch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
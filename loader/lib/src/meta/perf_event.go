@@ -0,0 +1,74 @@
+package meta
+
+import (
+	"fmt"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/observability/profile"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// PerfEventLink perf_event 程序在多个 CPU 上的附加点集合
+// 每个 CPU 对应一个 perf event 和一个 bpf_link，这里将其包装为单个 link.Link 以便统一管理
+type PerfEventLink struct {
+	// 嵌入接口仅用于满足 link.Link，所有方法均由 PerfEventLink 自行实现
+	link.Link
+
+	event *profile.PerfEvent
+}
+
+// Count 返回附加的 perf event 数量
+func (l *PerfEventLink) Count() int {
+	return l.event.Count()
+}
+
+// Update perf event 链接不支持原地替换程序
+func (l *PerfEventLink) Update(*ebpf.Program) error {
+	return fmt.Errorf("update perf event link: %w", link.ErrNotSupported)
+}
+
+// Pin perf event 链接由多个链接组成，不支持 pin
+func (l *PerfEventLink) Pin(string) error {
+	return fmt.Errorf("pin perf event link: %w", link.ErrNotSupported)
+}
+
+// Unpin perf event 链接从未被 pin，直接返回
+func (l *PerfEventLink) Unpin() error {
+	return nil
+}
+
+// Info perf event 链接由多个链接组成，没有单一的链接信息
+func (l *PerfEventLink) Info() (*link.Info, error) {
+	return nil, fmt.Errorf("perf event link info: %w", link.ErrNotSupported)
+}
+
+// Close 解除所有 CPU 上的附加并关闭 perf event
+func (l *PerfEventLink) Close() error {
+	return l.event.Close()
+}
+
+// options 转换为 profile 包的 perf event 配置
+func (p *PerfEventProperties) options() profile.PerfEventOptions {
+	if p == nil {
+		return profile.DefaultPerfEventOptions()
+	}
+
+	return profile.PerfEventOptions{
+		Type:         p.Type,
+		Config:       p.Config,
+		SampleFreq:   p.SampleFreq,
+		SamplePeriod: p.SamplePeriod,
+		CPUs:         p.CPUs,
+		PID:          p.PID,
+		CgroupPath:   p.CgroupPath,
+	}
+}
+
+func (p *ProgMeta) attachPerfEvent(program *ebpf.Program) (link.Link, error) {
+	event, err := profile.NewPerfEventWithOptions(program, p.Properties.PerfEvent.options())
+	if err != nil {
+		return nil, fmt.Errorf("attach perf event %s: %w", p.Name, err)
+	}
+
+	return &PerfEventLink{event: event}, nil
+}
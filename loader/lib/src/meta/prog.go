@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -9,10 +10,14 @@ import (
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
 )
 
 // AttachProgram 根据程序类型选择合适的 attach 方式
 func (p *ProgMeta) AttachProgram(spec *ebpf.ProgramSpec, program *ebpf.Program) (link link.Link, err error) {
+	if p.Properties == nil {
+		p.Properties = &ProgramProperties{}
+	}
 
 	switch spec.Type {
 	case ebpf.UnspecifiedProgram:
@@ -25,17 +30,25 @@ func (p *ProgMeta) AttachProgram(spec *ebpf.ProgramSpec, program *ebpf.Program)
 	case ebpf.CGroupDevice, ebpf.CGroupSKB, ebpf.CGroupSock, ebpf.SockOps, ebpf.CGroupSockAddr, ebpf.CGroupSockopt, ebpf.CGroupSysctl:
 		link, err = p.attachCGroup(program, spec.AttachType, p.Properties.CGroupPath)
 	case ebpf.SocketFilter:
-		link, err = p.attachSocket()
+		link, err = p.attachSocket(program)
 	case ebpf.SchedCLS:
 		link, err = p.attachTCCLS(program)
 	case ebpf.XDP:
-		link, err = p.attachXDP()
+		link, err = p.attachXDP(program)
 	case ebpf.RawTracepoint:
 		link, err = p.attachRawTracepoint(program)
 	case ebpf.Tracing:
-		link, err = p.attachTracing(program)
+		if spec.AttachType == ebpf.AttachTraceIter {
+			link, err = p.attachIter(program)
+		} else {
+			link, err = p.attachTracing(program)
+		}
+	case ebpf.Extension:
+		link, err = p.attachFreplace(program)
 	case ebpf.LSM:
-		link, err = p.attachLsm()
+		link, err = p.attachLsm(program)
+	case ebpf.PerfEvent:
+		link, err = p.attachPerfEvent(program)
 	default:
 		err = fmt.Errorf("program type %s not implemented yet", spec.Type)
 	}
@@ -63,7 +76,24 @@ func (p *ProgMeta) attachTracing(program *ebpf.Program) (link.Link, error) {
 	return tracing, nil
 }
 
+// attachFreplace 附加 freplace 程序，目标程序和函数已在加载时通过 AttachTarget 指定
+func (p *ProgMeta) attachFreplace(program *ebpf.Program) (link.Link, error) {
+	freplace, err := link.AttachFreplace(nil, "", program)
+	if err != nil {
+		return nil, fmt.Errorf("error:%v, couldn't activate freplace %s, matchFuncName:%s", err, p.Attach, p.Name)
+	}
+	return freplace, nil
+}
+
 func (p *ProgMeta) attachKprobe(program *ebpf.Program) (link.Link, error) {
+	section, _, _ := strings.Cut(p.Attach, "/")
+	switch section {
+	case "kprobe.multi", "kretprobe.multi":
+		return p.attachKprobeMulti(program, section == "kretprobe.multi")
+	case "uprobe.multi", "uretprobe.multi", "uprobe.multi.s", "uretprobe.multi.s":
+		return p.attachUprobeMulti(program, strings.HasPrefix(section, "uretprobe"))
+	}
+
 	// Prepare kprobe_events line parameters
 	var err error
 	attachPoint := strings.SplitN(p.Attach, "/", 2)
@@ -175,9 +205,36 @@ func (p *ProgMeta) attachCGroup(program *ebpf.Program, typ ebpf.AttachType, cgro
 	return kp, nil
 }
 
-func (p *ProgMeta) attachSocket() (link.Link, error) {
-	// todo: 需要实现
-	return nil, nil
+func (p *ProgMeta) attachSocket(program *ebpf.Program) (link.Link, error) {
+	if p.Properties.Socket == nil {
+		return nil, fmt.Errorf("prog %s invalid socket properties", p.Name)
+	}
+
+	sock := p.Properties.Socket
+	if sock.Fd > 0 {
+		if err := sockAttach(sock.Fd, program.FD()); err != nil {
+			return nil, fmt.Errorf("error:%v , couldn't attach socket filter to fd %d, matchFuncName:%s", err, sock.Fd, p.Name)
+		}
+
+		return &SocketFilterLink{fd: sock.Fd, prog: program}, nil
+	}
+
+	ifindex, err := resolveIfindex(sock.Ifindex, sock.Ifname)
+	if err != nil {
+		return nil, fmt.Errorf("prog %s invalid socket properties: %w", p.Name, err)
+	}
+
+	fd, err := openPacketSocket(ifindex)
+	if err != nil {
+		return nil, fmt.Errorf("error:%v , couldn't open packet socket on ifindex %d, matchFuncName:%s", err, ifindex, p.Name)
+	}
+
+	if err := sockAttach(fd, program.FD()); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("error:%v , couldn't attach socket filter on ifindex %d, matchFuncName:%s", err, ifindex, p.Name)
+	}
+
+	return &SocketFilterLink{fd: fd, prog: program, owned: true}, nil
 }
 
 func (p *ProgMeta) attachTCCLS(program *ebpf.Program) (link.Link, error) {
@@ -185,31 +242,114 @@ func (p *ProgMeta) attachTCCLS(program *ebpf.Program) (link.Link, error) {
 		return nil, fmt.Errorf("prog %s invalid tc properties", p.Name)
 	}
 
-	if p.Properties.Tc.Ifindex == 0 && p.Properties.Tc.Ifname == "" {
-		return nil, fmt.Errorf("prog %s invalid tc properties", p.Name)
+	tc := p.Properties.Tc
+	ifindex, err := resolveIfindex(int(tc.Ifindex), tc.Ifname)
+	if err != nil {
+		return nil, fmt.Errorf("prog %s invalid tc properties: %w", p.Name, err)
 	}
 
-	ntl, err := net.InterfaceByName(p.Properties.Tc.Ifname)
+	// 优先使用 TCX（内核 6.6+），不支持时回退到 clsact qdisc + direct-action 过滤器
+	l, err := link.AttachTCX(link.TCXOptions{
+		Interface: ifindex,
+		Program:   program,
+		Attach:    tc.AttachType,
+	})
+	if err == nil {
+		return l, nil
+	}
+
+	if !errors.Is(err, link.ErrNotSupported) {
+		return nil, fmt.Errorf("error:%v , couldn't activate tcx on ifindex %d, matchFuncName:%s", err, ifindex, p.Name)
+	}
+
+	filter, err := attachTCFilter(program, ifindex, tc.AttachType, tc.Opts, p.Name)
 	if err != nil {
-		return nil, fmt.Errorf("prog %s invalid tc properties", p.Name)
+		return nil, fmt.Errorf("error:%v , couldn't activate tc filter on ifindex %d, matchFuncName:%s", err, ifindex, p.Name)
 	}
 
-	// Attach the program to Ingress TC.
-	link, err := link.AttachTCX(link.TCXOptions{
-		Interface: ntl.Index,
+	return filter, nil
+}
+
+func (p *ProgMeta) attachXDP(program *ebpf.Program) (link.Link, error) {
+	if p.Properties.Xdp == nil {
+		return nil, fmt.Errorf("prog %s invalid xdp properties", p.Name)
+	}
+
+	xdp := p.Properties.Xdp
+	ifindex, err := resolveIfindex(xdp.Ifindex, xdp.Ifname)
+	if err != nil {
+		return nil, fmt.Errorf("prog %s invalid xdp properties: %w", p.Name, err)
+	}
+
+	flags, err := xdp.Mode.attachFlags()
+	if err != nil {
+		return nil, fmt.Errorf("prog %s invalid xdp properties: %w", p.Name, err)
+	}
+
+	// 替换模式下，优先复用已 pin 的 link，通过 Update 原子替换程序
+	if xdp.Replace {
+		if p.Properties.LinkPinPath == "" {
+			return nil, fmt.Errorf("prog %s invalid xdp properties: %w", p.Name, ErrXDPReplaceWithoutPin)
+		}
+
+		pinned, err := link.LoadPinnedLink(p.Properties.LinkPinPath, nil)
+		if err == nil {
+			if err := pinned.Update(program); err != nil {
+				pinned.Close()
+				return nil, fmt.Errorf("error:%v , couldn't replace xdp program on ifindex %d, matchFuncName:%s", err, ifindex, p.Name)
+			}
+			return pinned, nil
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("error:%v , couldn't load pinned xdp link %s, matchFuncName:%s", err, p.Properties.LinkPinPath, p.Name)
+		}
+	}
+
+	l, err := link.AttachXDP(link.XDPOptions{
 		Program:   program,
-		Attach:    p.Properties.Tc.AttachType,
+		Interface: ifindex,
+		Flags:     flags,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error:%v , couldn's activate tc ingress %s, matchFuncName:%s", err, p.Attach, p.Name)
+		return nil, fmt.Errorf("error:%v , couldn't activate xdp on ifindex %d, mode:%q, matchFuncName:%s", err, ifindex, xdp.Mode, p.Name)
 	}
 
-	return link, nil
+	return l, nil
+}
+
+// attachFlags 将 XDP 模式转换为 link 附加标志
+func (m XDPMode) attachFlags() (link.XDPAttachFlags, error) {
+	switch m {
+	case "":
+		return 0, nil
+	case XDPModeGeneric:
+		return link.XDPGenericMode, nil
+	case XDPModeNative:
+		return link.XDPDriverMode, nil
+	case XDPModeOffload:
+		return link.XDPOffloadMode, nil
+	default:
+		return 0, fmt.Errorf("unknown xdp mode %q", m)
+	}
 }
 
-func (p *ProgMeta) attachXDP() (link.Link, error) {
-	// todo: 需要实现
-	return nil, nil
+// resolveIfindex 根据接口索引或名称获取接口索引，索引优先
+func resolveIfindex(ifindex int, ifname string) (int, error) {
+	if ifindex > 0 {
+		return ifindex, nil
+	}
+
+	if ifname == "" {
+		return 0, ErrInterfaceNotSet
+	}
+
+	iface, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return 0, fmt.Errorf("lookup interface %s: %w", ifname, err)
+	}
+
+	return iface.Index, nil
 }
 
 func (p *ProgMeta) attachRawTracepoint(program *ebpf.Program) (link.Link, error) {
@@ -225,8 +365,13 @@ func (p *ProgMeta) attachRawTracepoint(program *ebpf.Program) (link.Link, error)
 	return link, nil
 }
 
-func (p *ProgMeta) attachLsm() (link.Link, error) {
-	return nil, nil
+func (p *ProgMeta) attachLsm(program *ebpf.Program) (link.Link, error) {
+	lsm, err := link.AttachLSM(link.LSMOptions{Program: program})
+	if err != nil {
+		return nil, fmt.Errorf("error:%v , couldn't activate lsm %s, matchFuncName:%s", err, p.Attach, p.Name)
+	}
+
+	return lsm, nil
 }
 
 // PinProgram 将程序固定到文件系统
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"testing"
 
@@ -95,3 +96,73 @@ func TestProgMeta_AttachProgram(t *testing.T) {
 		})
 	}
 }
+
+func TestXDPMode_attachFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    XDPMode
+		want    link.XDPAttachFlags
+		wantErr bool
+	}{
+		{name: "auto", mode: "", want: 0},
+		{name: "generic", mode: XDPModeGeneric, want: link.XDPGenericMode},
+		{name: "native", mode: XDPModeNative, want: link.XDPDriverMode},
+		{name: "offload", mode: XDPModeOffload, want: link.XDPOffloadMode},
+		{name: "unknown", mode: "skb", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.mode.attachFlags()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("attachFlags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("attachFlags() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgMeta_attachXDPReplaceWithoutPin(t *testing.T) {
+	p := &ProgMeta{
+		Name: "xdp_prog",
+		Properties: &ProgramProperties{
+			Xdp: &XDPProperties{Ifindex: 1, Replace: true},
+		},
+	}
+
+	// 替换模式没有 pin 路径时不能退化为普通附加
+	if _, err := p.attachXDP(nil); !errors.Is(err, ErrXDPReplaceWithoutPin) {
+		t.Errorf("attachXDP() error = %v, want %v", err, ErrXDPReplaceWithoutPin)
+	}
+}
+
+func TestResolveIfindex(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifindex int
+		ifname  string
+		want    int
+		wantErr bool
+	}{
+		{name: "index first", ifindex: 7, ifname: "not-exist", want: 7},
+		{name: "loopback", ifname: "lo", want: 1},
+		{name: "not set", wantErr: true},
+		{name: "not exist", ifname: "not-exist0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveIfindex(tt.ifindex, tt.ifname)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveIfindex() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("resolveIfindex() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package meta
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/cilium/ebpf"
@@ -16,6 +17,15 @@ type Properties struct {
 	// Programs 程序列表
 	Programs map[string]*Program
 
+	// Variables 全局变量的值（变量名 → JSON 值），加载前写入 .rodata/.data/.bss
+	Variables map[string]json.RawMessage
+
+	// Doc 程序文档，用于生成命令行帮助信息
+	Doc *BpfSkelDoc
+
+	// CmdArgs 全局变量的命令行参数配置（变量名 → 参数配置）
+	CmdArgs map[string]VariableCommandArgument
+
 	// Stats 统计配置
 	Stats *Stats
 
@@ -40,6 +50,48 @@ type Map struct {
 type MapProperties struct {
 	// PinPath 用于指定 eBPF 映射的 pin 路径，下次加载时从该路径加载
 	PinPath string
+
+	// PerfBufferPages perf event map 每个 CPU 缓冲区的页数，为 0 时使用全局的 PerfBufferPages
+	PerfBufferPages int
+
+	// PerfWatermark perf event map 缓冲区积累到该字节数时才唤醒读取，为 0 时使用全局的 PerfWatermark
+	PerfWatermark int
+
+	// PerfWakeupEvents perf event map 缓冲区积累到该事件数时才唤醒读取，与 PerfWatermark 互斥
+	PerfWakeupEvents int
+
+	// Pipeline perf event 和 ring buffer map 的事件处理流水线，为空时在轮询中直接处理事件
+	Pipeline *PipelineProperties
+}
+
+// DropPolicy 流水线队列已满时的处理策略
+type DropPolicy string
+
+const (
+	// DropOldest 丢弃队列中最早的事件，保留最新的事件
+	DropOldest DropPolicy = "drop-oldest"
+
+	// DropNewest 丢弃新读到的事件
+	DropNewest DropPolicy = "drop-newest"
+
+	// Block 阻塞轮询直到队列有空位，事件积压在内核缓冲区中
+	Block DropPolicy = "block"
+)
+
+// PipelineProperties 轮询和事件处理器之间的有界队列和解码工作协程
+// 读取内核缓冲区的轮询只负责入队，解码和 EventHandler 在工作协程中执行，慢速的输出不会阻塞读取
+type PipelineProperties struct {
+	// QueueSize 队列容量，为 0 时默认为 4096
+	QueueSize int
+
+	// Workers 解码工作协程数，为 0 时默认为 1，大于 1 时 EventHandler 需要支持并发调用
+	Workers int
+
+	// DropPolicy 队列已满时的处理策略，为空时默认为 DropNewest
+	DropPolicy DropPolicy
+
+	// OrderKey 导出结构体中的字段名称，设置后该字段相同的事件由同一个工作协程按读取顺序处理
+	OrderKey string
 }
 
 type Stats struct {
@@ -59,6 +111,12 @@ type Program struct {
 }
 
 type ProgramProperties struct {
+	// Optional 附加失败时只记录到 ProgAttachStatus，其余程序继续加载，用于同一组件兼容不同内核
+	Optional bool
+
+	// Required 附加失败时撤销整个加载，未设置 Optional 的程序默认即为必需，同时设置时以 Required 为准
+	Required bool
+
 	// CGroupPath 用于 cgroup 程序的 cgroup 路径
 	CGroupPath string
 
@@ -73,6 +131,63 @@ type ProgramProperties struct {
 
 	// Uprobe 用户态探针配置
 	Uprobe *UprobeProperties
+
+	// KprobeMulti kprobe.multi 程序配置
+	KprobeMulti *KprobeMultiProperties
+
+	// Tracing 以其他 BPF 程序为目标的 fentry/fexit/freplace 配置
+	Tracing *TracingProperties
+
+	// Xdp XDP 程序配置
+	Xdp *XDPProperties
+
+	// Socket socket filter 程序配置
+	Socket *SocketProperties
+
+	// Iter bpf_iter 程序配置
+	Iter *IterProperties
+
+	// PerfEvent perf_event 程序配置，未设置时以 200Hz 在所有在线 CPU 上采样 CPU 时钟
+	PerfEvent *PerfEventProperties
+}
+
+// IsOptional 判断程序附加失败时是否可以跳过
+func (p *ProgramProperties) IsOptional() bool {
+	return p != nil && p.Optional && !p.Required
+}
+
+type IterProperties struct {
+	// MapName iter/bpf_map_elem 等 map 迭代器遍历的 map 名称
+	MapName string
+
+	// StructName bpf_seq_write 输出的结构体名称，设置后按该结构体解码输出，否则按 bpf_seq_printf 文本返回
+	StructName string
+
+	// Map 由加载器根据 MapName 填充
+	Map *ebpf.Map
+}
+
+type PerfEventProperties struct {
+	// Type perf 事件类型，例如 PERF_TYPE_SOFTWARE(1)、PERF_TYPE_HARDWARE(0)
+	Type uint32
+
+	// Config perf 事件配置，例如 PERF_COUNT_SW_CPU_CLOCK(0)
+	Config uint64
+
+	// SampleFreq 采样频率（Hz），优先于 SamplePeriod
+	SampleFreq uint64
+
+	// SamplePeriod 采样周期，每发生 SamplePeriod 次事件采样一次
+	SamplePeriod uint64
+
+	// CPUs 采样的 CPU 列表，为空时使用所有在线 CPU
+	CPUs []int
+
+	// PID 只采样指定进程，与 CgroupPath 互斥
+	PID int
+
+	// CgroupPath 只采样指定 cgroup 中的进程
+	CgroupPath string
 }
 
 type UprobeProperties struct {
@@ -82,6 +197,9 @@ type UprobeProperties struct {
 	// Symbol 用于指定用户态探针的符号
 	Symbol string
 
+	// Symbols 用于 uprobe.multi 的符号列表，支持 glob 模式
+	Symbols []string
+
 	// Offset 用于指定用户态探针的偏移
 	Offset uint64
 
@@ -92,6 +210,25 @@ type UprobeProperties struct {
 	PID int
 }
 
+type KprobeMultiProperties struct {
+	// Symbols 内核函数列表，支持 glob 模式，与节名称中的模式合并
+	Symbols []string
+}
+
+type TracingProperties struct {
+	// TargetProgID 目标 BPF 程序的 ID
+	TargetProgID uint32
+
+	// TargetProgPinPath 目标 BPF 程序的 pin 路径
+	TargetProgPinPath string
+
+	// TargetProgName 同一对象中目标程序的名称，目标程序加载完成后再加载该程序
+	TargetProgName string
+
+	// TargetFunc 目标程序中的函数名称，为空时使用节名称中的函数名
+	TargetFunc string
+}
+
 type TCCLS struct {
 	// Ifindex 接口索引
 	Ifindex int32
@@ -99,6 +236,57 @@ type TCCLS struct {
 	// Ifname 接口名称
 	Ifname string
 
-	// AttachType 附加点类型
+	// AttachType 附加点类型，AttachTCXIngress 或 AttachTCXEgress
 	AttachType ebpf.AttachType
+
+	// Opts 使用 netlink 附加时过滤器的 handle 和优先级，为 0 时默认为 1
+	Opts TCOpts
+}
+
+// XDPMode XDP 附加模式
+type XDPMode string
+
+const (
+	// XDPModeGeneric 通用模式（SKB），适用于不支持原生 XDP 的驱动
+	XDPModeGeneric XDPMode = "generic"
+
+	// XDPModeNative 原生模式，程序运行在驱动的接收路径中
+	XDPModeNative XDPMode = "native"
+
+	// XDPModeOffload 卸载模式，程序运行在网卡硬件上
+	XDPModeOffload XDPMode = "offload"
+)
+
+type XDPProperties struct {
+	// Ifindex 接口索引，优先于 Ifname
+	Ifindex int
+
+	// Ifname 接口名称
+	Ifname string
+
+	// Mode 附加模式，为空时由内核自动选择 native 或 generic
+	Mode XDPMode
+
+	// Replace 是否替换已附加的 XDP 程序
+	// 必须同时设置 LinkPinPath，否则附加失败；若该路径下存在已 pin 的 link，则原子替换其程序
+	Replace bool
+}
+
+type SocketProperties struct {
+	// Fd 调用方提供的 socket 文件描述符，大于 0 时直接附加到该 socket
+	// 该 socket 的生命周期由调用方管理，默认由调用方自行读取其中的报文
+	Fd int
+
+	// PollFd 为 true 时加载器也读取 Fd 中的报文并交给事件处理器
+	// 读取会与调用方竞争同一个 socket，只在调用方自己不读取时开启
+	PollFd bool
+
+	// Ifindex 接口索引，未提供 Fd 时在该接口上打开原始套接字
+	Ifindex int
+
+	// Ifname 接口名称，Ifindex 为 0 时使用
+	Ifname string
+
+	// ExportHandler 匹配报文的事件处理器，为空时使用全局事件处理器
+	ExportHandler EventHandler
 }
@@ -0,0 +1,81 @@
+package meta
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+)
+
+// SocketFilterLink socket filter 程序的附加点
+// socket filter 通过 SO_ATTACH_BPF 附加，不会生成 bpf_link，这里将其包装为 link.Link 以便统一管理
+type SocketFilterLink struct {
+	// 嵌入接口仅用于满足 link.Link，所有方法均由 SocketFilterLink 自行实现
+	link.Link
+
+	fd    int
+	prog  *ebpf.Program
+	owned bool
+}
+
+// FD 返回程序附加的 socket 文件描述符
+func (l *SocketFilterLink) FD() int {
+	return l.fd
+}
+
+// Owned 返回 socket 是否由加载器打开
+// 只有加载器打开的 socket 会在 Close 时关闭，调用方提供的 socket 仅解除附加
+func (l *SocketFilterLink) Owned() bool {
+	return l.owned
+}
+
+// Update 将 socket 上的过滤程序替换为新程序
+func (l *SocketFilterLink) Update(prog *ebpf.Program) error {
+	if prog == nil {
+		return fmt.Errorf("update socket filter: nil program")
+	}
+
+	if err := sockAttach(l.fd, prog.FD()); err != nil {
+		return fmt.Errorf("update socket filter on fd %d: %w", l.fd, err)
+	}
+
+	l.prog = prog
+	return nil
+}
+
+// Pin socket filter 不支持 pin
+func (l *SocketFilterLink) Pin(string) error {
+	return fmt.Errorf("pin socket filter: %w", link.ErrNotSupported)
+}
+
+// Unpin socket filter 从未被 pin，直接返回
+func (l *SocketFilterLink) Unpin() error {
+	return nil
+}
+
+// Info socket filter 没有 bpf_link 信息
+func (l *SocketFilterLink) Info() (*link.Info, error) {
+	return nil, fmt.Errorf("socket filter info: %w", link.ErrNotSupported)
+}
+
+// Close 解除附加，如果 socket 由加载器打开则一并关闭
+func (l *SocketFilterLink) Close() error {
+	if l.fd < 0 {
+		return nil
+	}
+
+	if l.owned {
+		err := unix.Close(l.fd)
+		l.fd = -1
+		return err
+	}
+
+	err := sockDetach(l.fd, l.prog.FD())
+	l.fd = -1
+	if err != nil {
+		return fmt.Errorf("detach socket filter: %w", err)
+	}
+
+	return nil
+}
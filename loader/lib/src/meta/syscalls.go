@@ -1,10 +1,38 @@
 package meta
 
-//
-//func sockAttach(sockFd int, progFd int) error {
-//	return syscall.SetsockoptInt(sockFd, syscall.SOL_SOCKET, unix.SO_ATTACH_BPF, progFd)
-//}
-//
-//func sockDetach(sockFd int, progFd int) error {
-//	return syscall.SetsockoptInt(sockFd, syscall.SOL_SOCKET, unix.SO_DETACH_BPF, progFd)
-//}
+import (
+	"encoding/binary"
+
+	"golang.org/x/sys/unix"
+)
+
+func sockAttach(sockFd int, progFd int) error {
+	return unix.SetsockoptInt(sockFd, unix.SOL_SOCKET, unix.SO_ATTACH_BPF, progFd)
+}
+
+func sockDetach(sockFd int, progFd int) error {
+	return unix.SetsockoptInt(sockFd, unix.SOL_SOCKET, unix.SO_DETACH_BPF, progFd)
+}
+
+// openPacketSocket 在指定接口上打开接收所有协议报文的 AF_PACKET 原始套接字
+func openPacketSocket(ifindex int) (int, error) {
+	proto := htons(unix.ETH_P_ALL)
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, int(proto))
+	if err != nil {
+		return -1, err
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: proto, Ifindex: ifindex}); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+
+	return fd, nil
+}
+
+// htons 将主机字节序转换为网络字节序
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
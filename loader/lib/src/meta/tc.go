@@ -0,0 +1,288 @@
+package meta
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+)
+
+// 以下常量来自 linux/pkt_sched.h 和 linux/pkt_cls.h，x/sys/unix 未全部导出
+const (
+	tcaKind    = 1
+	tcaOptions = 2
+
+	tcaBpfFd    = 6
+	tcaBpfName  = 7
+	tcaBpfFlags = 8
+
+	tcaBpfFlagActDirect = 1 << 0
+
+	tcHClsact     = 0xfffffff1
+	tcHMinIngress = 0xfff2
+	tcHMinEgress  = 0xfff3
+	tcHMajMask    = 0xffff0000
+	tcHMinMask    = 0x0000ffff
+
+	sizeofTcMsg = 20
+
+	// 未指定时使用的 handle 和优先级
+	defaultTCHandle   = 1
+	defaultTCPriority = 1
+)
+
+// 附加机制，记录在 ProgAttachStatus 中
+const (
+	AttachMechanismBpfLink = "bpf_link"
+	AttachMechanismTCX     = "tcx"
+	AttachMechanismNetlink = "netlink"
+	AttachMechanismSockopt = "setsockopt"
+
+	AttachMechanismKprobeMulti = "kprobe_multi"
+	AttachMechanismUprobeMulti = "uprobe_multi"
+	AttachMechanismPerfEvent   = "perf_event"
+	AttachMechanismIter        = "bpf_iter"
+)
+
+// AttachMechanism 返回链接使用的附加机制
+func AttachMechanism(l link.Link) string {
+	switch v := l.(type) {
+	case *TCFilterLink:
+		return AttachMechanismNetlink
+	case *SocketFilterLink:
+		return AttachMechanismSockopt
+	case *ProbeSetLink:
+		return v.Mechanism()
+	case *PerfEventLink:
+		return AttachMechanismPerfEvent
+	}
+
+	if _, ok := l.(*link.Iter); ok {
+		return AttachMechanismIter
+	}
+
+	if info, err := l.Info(); err == nil && info.Type == link.TCXType {
+		return AttachMechanismTCX
+	}
+
+	return AttachMechanismBpfLink
+}
+
+// AttachCount 返回链接包含的附加点数量，单个附加点的链接返回 1
+func AttachCount(l link.Link) int {
+	if set, ok := l.(interface{ Count() int }); ok {
+		return set.Count()
+	}
+	return 1
+}
+
+// TCFilterLink 通过 netlink 在 clsact qdisc 上创建的 direct-action bpf 过滤器
+// 用于不支持 TCX 的内核（6.6 以下），关闭时删除过滤器，clsact qdisc 可能被其他过滤器共享，因此保留
+type TCFilterLink struct {
+	// 嵌入接口仅用于满足 link.Link，所有方法均由 TCFilterLink 自行实现
+	link.Link
+
+	ifindex  int
+	parent   uint32
+	handle   uint32
+	priority uint32
+	name     string
+	closed   atomic.Bool
+}
+
+// attachTCFilter 确保接口上存在 clsact qdisc，并附加 direct-action bpf 过滤器
+func attachTCFilter(program *ebpf.Program, ifindex int, attachType ebpf.AttachType, opts TCOpts, name string) (*TCFilterLink, error) {
+	var minor uint32
+	switch attachType {
+	case ebpf.AttachTCXIngress:
+		minor = tcHMinIngress
+	case ebpf.AttachTCXEgress:
+		minor = tcHMinEgress
+	default:
+		return nil, fmt.Errorf("unsupported tc attach type %s", attachType)
+	}
+
+	if opts.Handle == 0 {
+		opts.Handle = defaultTCHandle
+	}
+	if opts.Priority == 0 {
+		opts.Priority = defaultTCPriority
+	}
+
+	if err := addClsactQdisc(ifindex); err != nil {
+		return nil, fmt.Errorf("add clsact qdisc error: %w", err)
+	}
+
+	l := &TCFilterLink{
+		ifindex:  ifindex,
+		parent:   (tcHClsact & tcHMajMask) | (minor & tcHMinMask),
+		handle:   opts.Handle,
+		priority: opts.Priority,
+		name:     name,
+	}
+
+	if err := l.replaceFilter(program, unix.NLM_F_CREATE|unix.NLM_F_EXCL); err != nil {
+		return nil, fmt.Errorf("add bpf filter error: %w", err)
+	}
+
+	return l, nil
+}
+
+// Update 原子替换过滤器中的程序
+func (l *TCFilterLink) Update(prog *ebpf.Program) error {
+	if prog == nil {
+		return fmt.Errorf("update tc filter: nil program")
+	}
+
+	return l.replaceFilter(prog, unix.NLM_F_REPLACE)
+}
+
+// Pin netlink 过滤器不支持 pin
+func (l *TCFilterLink) Pin(string) error {
+	return fmt.Errorf("pin tc filter: %w", link.ErrNotSupported)
+}
+
+// Unpin netlink 过滤器从未被 pin，直接返回
+func (l *TCFilterLink) Unpin() error {
+	return nil
+}
+
+// Info netlink 过滤器没有 bpf_link 信息
+func (l *TCFilterLink) Info() (*link.Info, error) {
+	return nil, fmt.Errorf("tc filter info: %w", link.ErrNotSupported)
+}
+
+// Close 删除过滤器
+func (l *TCFilterLink) Close() error {
+	if !l.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	msg := l.tcMsg(l.parent)
+	err := netlinkRequest(unix.RTM_DELTFILTER, 0, msg, nil)
+	if err != nil && !errors.Is(err, unix.ENOENT) {
+		return fmt.Errorf("delete tc filter on ifindex %d error: %w", l.ifindex, err)
+	}
+
+	return nil
+}
+
+func (l *TCFilterLink) replaceFilter(prog *ebpf.Program, flags uint16) error {
+	options := netlinkAttr(tcaBpfFd, uint32Bytes(uint32(prog.FD())))
+	options = append(options, netlinkAttr(tcaBpfName, zeroTerminated(l.name))...)
+	options = append(options, netlinkAttr(tcaBpfFlags, uint32Bytes(tcaBpfFlagActDirect))...)
+
+	attrs := netlinkAttr(tcaKind, zeroTerminated("bpf"))
+	attrs = append(attrs, netlinkAttr(tcaOptions|unix.NLA_F_NESTED, options)...)
+
+	return netlinkRequest(unix.RTM_NEWTFILTER, flags, l.tcMsg(l.parent), attrs)
+}
+
+// tcMsg 构造过滤器的 tcmsg，info 的高 16 位为优先级，低 16 位为网络字节序的协议
+func (l *TCFilterLink) tcMsg(parent uint32) []byte {
+	info := l.priority<<16 | uint32(htons(unix.ETH_P_ALL))
+	return tcMsgBytes(l.ifindex, l.handle, parent, info)
+}
+
+// addClsactQdisc 在接口上创建 clsact qdisc，已存在时忽略
+func addClsactQdisc(ifindex int) error {
+	msg := tcMsgBytes(ifindex, tcHClsact&tcHMajMask, tcHClsact, 0)
+	err := netlinkRequest(unix.RTM_NEWQDISC, unix.NLM_F_CREATE|unix.NLM_F_EXCL, msg, netlinkAttr(tcaKind, zeroTerminated("clsact")))
+	if errors.Is(err, unix.EEXIST) {
+		return nil
+	}
+
+	return err
+}
+
+func tcMsgBytes(ifindex int, handle, parent, info uint32) []byte {
+	b := make([]byte, sizeofTcMsg)
+	b[0] = unix.AF_UNSPEC
+	binary.NativeEndian.PutUint32(b[4:], uint32(int32(ifindex)))
+	binary.NativeEndian.PutUint32(b[8:], handle)
+	binary.NativeEndian.PutUint32(b[12:], parent)
+	binary.NativeEndian.PutUint32(b[16:], info)
+	return b
+}
+
+// netlinkRequest 发送 rtnetlink 请求并等待内核确认
+func netlinkRequest(msgType uint16, flags uint16, body []byte, attrs []byte) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("open netlink socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("bind netlink socket: %w", err)
+	}
+
+	payload := append(append([]byte{}, body...), attrs...)
+	msg := make([]byte, unix.SizeofNlMsghdr, unix.SizeofNlMsghdr+len(payload))
+	binary.NativeEndian.PutUint32(msg[0:], uint32(unix.SizeofNlMsghdr+len(payload)))
+	binary.NativeEndian.PutUint16(msg[4:], msgType)
+	binary.NativeEndian.PutUint16(msg[6:], unix.NLM_F_REQUEST|unix.NLM_F_ACK|flags)
+	binary.NativeEndian.PutUint32(msg[8:], 1)
+	msg = append(msg, payload...)
+
+	if err := unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("send netlink request: %w", err)
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return fmt.Errorf("receive netlink response: %w", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return fmt.Errorf("parse netlink response: %w", err)
+		}
+
+		for _, m := range msgs {
+			if m.Header.Type != unix.NLMSG_ERROR {
+				continue
+			}
+
+			if len(m.Data) < 4 {
+				return fmt.Errorf("short netlink error message")
+			}
+
+			if errno := -int32(binary.NativeEndian.Uint32(m.Data[:4])); errno != 0 {
+				return unix.Errno(errno)
+			}
+			return nil
+		}
+	}
+}
+
+// netlinkAttr 编码一个 4 字节对齐的 netlink 属性
+func netlinkAttr(attrType uint16, data []byte) []byte {
+	length := unix.SizeofRtAttr + len(data)
+	b := make([]byte, rtaAlign(length))
+	binary.NativeEndian.PutUint16(b[0:], uint16(length))
+	binary.NativeEndian.PutUint16(b[2:], attrType)
+	copy(b[unix.SizeofRtAttr:], data)
+	return b
+}
+
+func rtaAlign(length int) int {
+	return (length + unix.RTA_ALIGNTO - 1) &^ (unix.RTA_ALIGNTO - 1)
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.NativeEndian.PutUint32(b, v)
+	return b
+}
+
+func zeroTerminated(s string) []byte {
+	return append([]byte(s), 0)
+}
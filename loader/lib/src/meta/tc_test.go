@@ -0,0 +1,64 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+func TestNetlinkAttr(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantLen int
+	}{
+		{name: "aligned", data: uint32Bytes(7), wantLen: 8},
+		{name: "padded", data: zeroTerminated("bpf"), wantLen: 8},
+		{name: "padded long", data: zeroTerminated("clsact"), wantLen: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := netlinkAttr(tcaKind, tt.data)
+			if len(got) != tt.wantLen {
+				t.Fatalf("netlinkAttr() len = %d, want %d", len(got), tt.wantLen)
+			}
+
+			if l := binary.NativeEndian.Uint16(got[0:]); int(l) != unix.SizeofRtAttr+len(tt.data) {
+				t.Errorf("netlinkAttr() rta_len = %d, want %d", l, unix.SizeofRtAttr+len(tt.data))
+			}
+
+			if !bytes.Equal(got[unix.SizeofRtAttr:unix.SizeofRtAttr+len(tt.data)], tt.data) {
+				t.Errorf("netlinkAttr() payload = %v, want %v", got[unix.SizeofRtAttr:], tt.data)
+			}
+		})
+	}
+}
+
+func TestTCFilterLink_tcMsg(t *testing.T) {
+	l := &TCFilterLink{
+		ifindex:  3,
+		parent:   (tcHClsact & tcHMajMask) | tcHMinIngress,
+		handle:   1,
+		priority: 2,
+	}
+
+	msg := l.tcMsg(l.parent)
+	if len(msg) != sizeofTcMsg {
+		t.Fatalf("tcMsg() len = %d, want %d", len(msg), sizeofTcMsg)
+	}
+
+	if got := binary.NativeEndian.Uint32(msg[12:]); got != 0xfffffff2 {
+		t.Errorf("tcMsg() parent = %#x, want %#x", got, 0xfffffff2)
+	}
+
+	if got := binary.NativeEndian.Uint32(msg[16:]) >> 16; got != 2 {
+		t.Errorf("tcMsg() priority = %d, want %d", got, 2)
+	}
+
+	if _, err := attachTCFilter(nil, 3, ebpf.AttachCGroupInetIngress, TCOpts{}, "test"); err == nil {
+		t.Errorf("attachTCFilter() with unsupported attach type error = nil")
+	}
+}
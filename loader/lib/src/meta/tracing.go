@@ -0,0 +1,33 @@
+package meta
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+)
+
+// HasProgramTarget 是否以其他 BPF 程序为附加目标
+func (t *TracingProperties) HasProgramTarget() bool {
+	return t != nil && (t.TargetProgID != 0 || t.TargetProgPinPath != "" || t.TargetProgName != "")
+}
+
+// LoadTarget 加载通过 ID 或 pin 路径指定的外部目标程序
+// 目标在同一对象中时返回 nil，由调用方在目标加载后设置
+func (t *TracingProperties) LoadTarget() (*ebpf.Program, error) {
+	switch {
+	case t.TargetProgID != 0:
+		prog, err := ebpf.NewProgramFromID(ebpf.ProgramID(t.TargetProgID))
+		if err != nil {
+			return nil, fmt.Errorf("load target program by id %d error: %w", t.TargetProgID, err)
+		}
+		return prog, nil
+	case t.TargetProgPinPath != "":
+		prog, err := ebpf.LoadPinnedProgram(t.TargetProgPinPath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("load target program from %s error: %w", t.TargetProgPinPath, err)
+		}
+		return prog, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,321 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// EncodeVariable 按 BTF 类型将 JSON 值编码为变量的内存布局（主机字节序）
+// 支持整数、bool、枚举、浮点数、数组（char 数组可使用字符串）和结构体
+// current 为变量当前的内存，JSON 中未提供的结构体成员和数组元素保留 current 中的值，current 为空时置零
+func EncodeVariable(typ btf.Type, raw json.RawMessage, current []byte) ([]byte, error) {
+	size, err := btf.Sizeof(typ)
+	if err != nil {
+		return nil, fmt.Errorf("size of %s: %w", typ, err)
+	}
+
+	buf := make([]byte, size)
+	if current != nil {
+		if len(current) != size {
+			return nil, fmt.Errorf("current value has %d bytes, want %d", len(current), size)
+		}
+		copy(buf, current)
+	}
+
+	if err := encodeValue(typ, raw, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// CheckVariable 检查变量是否在所属数据段的 BTF Datasec 中声明
+func CheckVariable(spec *ebpf.CollectionSpec, name string) (*ebpf.VariableSpec, error) {
+	varSpec, ok := spec.Variables[name]
+	if !ok {
+		return nil, fmt.Errorf("variable %s: %w", name, ErrUnknownVariable)
+	}
+
+	if varSpec.Type() == nil {
+		return nil, fmt.Errorf("variable %s: object has no BTF", name)
+	}
+
+	mapSpec, ok := spec.Maps[varSpec.MapName()]
+	if !ok {
+		return nil, fmt.Errorf("variable %s: data section %s not found", name, varSpec.MapName())
+	}
+
+	datasec, ok := mapSpec.Value.(*btf.Datasec)
+	if !ok {
+		return nil, fmt.Errorf("variable %s: data section %s has no Datasec", name, varSpec.MapName())
+	}
+
+	for _, vsi := range datasec.Vars {
+		v, ok := vsi.Type.(*btf.Var)
+		if !ok || v.Name != name {
+			continue
+		}
+
+		if uint64(vsi.Offset) != varSpec.Offset() || uint64(vsi.Size) != varSpec.Size() {
+			return nil, fmt.Errorf("variable %s: layout mismatch with Datasec %s", name, datasec.Name)
+		}
+
+		return varSpec, nil
+	}
+
+	return nil, fmt.Errorf("variable %s: not declared in Datasec %s", name, datasec.Name)
+}
+
+// ApplyVariables 将变量值写入 spec 中的数据段，必须在加载前调用
+func ApplyVariables(spec *ebpf.CollectionSpec, values map[string]json.RawMessage) error {
+	for name, raw := range values {
+		varSpec, err := CheckVariable(spec, name)
+		if err != nil {
+			return err
+		}
+
+		// 从对象中的初始值开始编码，保留未配置成员的默认值
+		current := make([]byte, varSpec.Size())
+		if err := varSpec.Get(current); err != nil {
+			return fmt.Errorf("get variable %s: %w", name, err)
+		}
+
+		buf, err := EncodeVariable(varSpec.Type().Type, raw, current)
+		if err != nil {
+			return fmt.Errorf("encode variable %s: %w", name, err)
+		}
+
+		if err := varSpec.Set(buf); err != nil {
+			return fmt.Errorf("set variable %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Variables 返回数据段中设置了值的变量
+func (s *BpfSkeletonMeta) Variables() map[string]json.RawMessage {
+	values := make(map[string]json.RawMessage)
+	for _, section := range s.DataSections {
+		for _, variable := range section.Variables {
+			if variable.Value != nil {
+				values[variable.Name] = *variable.Value
+			}
+		}
+	}
+	return values
+}
+
+func encodeValue(typ btf.Type, raw json.RawMessage, buf []byte) error {
+	switch t := btf.UnderlyingType(typ).(type) {
+	case *btf.Int:
+		return encodeInt(t, raw, buf)
+	case *btf.Enum:
+		return encodeEnum(t, raw, buf)
+	case *btf.Float:
+		return encodeFloat(t, raw, buf)
+	case *btf.Array:
+		return encodeArray(t, raw, buf)
+	case *btf.Struct:
+		return encodeStruct(t, raw, buf)
+	default:
+		return fmt.Errorf("unsupported variable type: %T", t)
+	}
+}
+
+// decodeNumber 解析 JSON 数字，保留 64 位整数精度
+func decodeNumber(raw json.RawMessage) (json.Number, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var num json.Number
+	if err := decoder.Decode(&num); err != nil {
+		return "", fmt.Errorf("expect number, got %s", raw)
+	}
+	return num, nil
+}
+
+func putInt(buf []byte, size uint32, signed bool, num json.Number) error {
+	var value uint64
+	if signed {
+		v, err := strconv.ParseInt(num.String(), 0, int(size)*8)
+		if err != nil {
+			return fmt.Errorf("parse int%d %s: %w", size*8, num, err)
+		}
+		value = uint64(v)
+	} else {
+		v, err := strconv.ParseUint(num.String(), 0, int(size)*8)
+		if err != nil {
+			return fmt.Errorf("parse uint%d %s: %w", size*8, num, err)
+		}
+		value = v
+	}
+
+	switch size {
+	case 1:
+		buf[0] = uint8(value)
+	case 2:
+		binary.NativeEndian.PutUint16(buf, uint16(value))
+	case 4:
+		binary.NativeEndian.PutUint32(buf, uint32(value))
+	case 8:
+		binary.NativeEndian.PutUint64(buf, value)
+	default:
+		return fmt.Errorf("unsupported int size: %d", size)
+	}
+	return nil
+}
+
+func encodeInt(t *btf.Int, raw json.RawMessage, buf []byte) error {
+	if t.Encoding == btf.Bool {
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fmt.Errorf("expect bool, got %s", raw)
+		}
+		buf[0] = 0
+		if value {
+			buf[0] = 1
+		}
+		return nil
+	}
+
+	num, err := decodeNumber(raw)
+	if err != nil {
+		return err
+	}
+	return putInt(buf, t.Size, t.Encoding == btf.Signed, num)
+}
+
+func encodeEnum(t *btf.Enum, raw json.RawMessage, buf []byte) error {
+	// 允许使用枚举名
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		for _, value := range t.Values {
+			if value.Name == name {
+				return putInt(buf, t.Size, false, json.Number(strconv.FormatUint(value.Value, 10)))
+			}
+		}
+		return fmt.Errorf("unknown enum value %s of %s", name, t.Name)
+	}
+
+	num, err := decodeNumber(raw)
+	if err != nil {
+		return err
+	}
+	return putInt(buf, t.Size, t.Signed, num)
+}
+
+func encodeFloat(t *btf.Float, raw json.RawMessage, buf []byte) error {
+	var value float64
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("expect float, got %s", raw)
+	}
+
+	switch t.Size {
+	case 4:
+		binary.NativeEndian.PutUint32(buf, math.Float32bits(float32(value)))
+	case 8:
+		binary.NativeEndian.PutUint64(buf, math.Float64bits(value))
+	default:
+		return fmt.Errorf("unsupported float size: %d", t.Size)
+	}
+	return nil
+}
+
+func encodeArray(t *btf.Array, raw json.RawMessage, buf []byte) error {
+	elemSize, err := btf.Sizeof(t.Type)
+	if err != nil {
+		return fmt.Errorf("size of %s: %w", t.Type, err)
+	}
+
+	// char 数组接受字符串，需要保留结尾的 '\0'
+	if elem, ok := btf.UnderlyingType(t.Type).(*btf.Int); ok && elem.Size == 1 {
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			if len(str) >= int(t.Nelems) {
+				return fmt.Errorf("string %q too long for char[%d]", str, t.Nelems)
+			}
+			// 字符串替换整个数组，剩余部分置零
+			clear(buf)
+			copy(buf, str)
+			return nil
+		}
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return fmt.Errorf("expect array, got %s", raw)
+	}
+
+	if len(items) > int(t.Nelems) {
+		return fmt.Errorf("array has %d elements, want at most %d", len(items), t.Nelems)
+	}
+
+	for i, item := range items {
+		if err := encodeValue(t.Type, item, buf[i*elemSize:(i+1)*elemSize]); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func encodeStruct(t *btf.Struct, raw json.RawMessage, buf []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return fmt.Errorf("expect object, got %s", raw)
+	}
+
+	for name, value := range fields {
+		var member *btf.Member
+		for i := range t.Members {
+			if t.Members[i].Name == name {
+				member = &t.Members[i]
+				break
+			}
+		}
+		if member == nil {
+			return fmt.Errorf("struct %s has no member %s", t.Name, name)
+		}
+
+		if member.BitfieldSize != 0 || member.Offset%8 != 0 {
+			return fmt.Errorf("member %s: bit fields not supported", name)
+		}
+
+		size, err := btf.Sizeof(member.Type)
+		if err != nil {
+			return fmt.Errorf("size of member %s: %w", name, err)
+		}
+
+		offset := int(member.Offset.Bytes())
+		if err := encodeValue(member.Type, value, buf[offset:offset+size]); err != nil {
+			return fmt.Errorf("member %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Encodable 判断类型的值能否通过 EncodeVariable 从 JSON 编码
+func Encodable(typ btf.Type) bool {
+	switch t := btf.UnderlyingType(typ).(type) {
+	case *btf.Int, *btf.Enum, *btf.Float:
+		return true
+	case *btf.Array:
+		return Encodable(t.Type)
+	case *btf.Struct:
+		for _, member := range t.Members {
+			if member.BitfieldSize != 0 || member.Offset%8 != 0 || !Encodable(member.Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,123 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+func TestEncodeVariable(t *testing.T) {
+	u8 := &btf.Int{Name: "unsigned char", Size: 1}
+	char := &btf.Int{Name: "char", Size: 1, Encoding: btf.Signed}
+	s32 := &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}
+	u64 := &btf.Int{Name: "unsigned long long", Size: 8}
+	boolean := &btf.Int{Name: "_Bool", Size: 1, Encoding: btf.Bool}
+	state := &btf.Enum{Name: "state", Size: 4, Values: []btf.EnumValue{{Name: "RUNNING", Value: 1}, {Name: "STOPPED", Value: 2}}}
+	config := &btf.Struct{
+		Name: "config",
+		Size: 12,
+		Members: []btf.Member{
+			{Name: "pid", Type: s32, Offset: 0},
+			{Name: "verbose", Type: boolean, Offset: 32},
+			{Name: "comm", Type: &btf.Array{Type: char, Nelems: 4}, Offset: 40},
+		},
+	}
+
+	u32le := func(v uint32) []byte {
+		buf := make([]byte, 4)
+		binary.NativeEndian.PutUint32(buf, v)
+		return buf
+	}
+	u64le := func(v uint64) []byte {
+		buf := make([]byte, 8)
+		binary.NativeEndian.PutUint64(buf, v)
+		return buf
+	}
+
+	tests := []struct {
+		name    string
+		typ     btf.Type
+		raw     string
+		current []byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "unsigned", typ: u8, raw: `255`, want: []byte{255}},
+		{name: "unsigned overflow", typ: u8, raw: `256`, wantErr: true},
+		{name: "negative", typ: s32, raw: `-1`, want: u32le(0xffffffff)},
+		{name: "u64 precision", typ: u64, raw: `18446744073709551615`, want: u64le(^uint64(0))},
+		{name: "typedef", typ: &btf.Typedef{Name: "__u32", Type: s32}, raw: `7`, want: u32le(7)},
+		{name: "volatile const", typ: &btf.Volatile{Type: &btf.Const{Type: s32}}, raw: `3`, want: u32le(3)},
+		{name: "bool", typ: boolean, raw: `true`, want: []byte{1}},
+		{name: "quoted number", typ: s32, raw: `"1"`, want: u32le(1)},
+		{name: "not a number", typ: s32, raw: `"one"`, wantErr: true},
+		{name: "enum by name", typ: state, raw: `"STOPPED"`, want: u32le(2)},
+		{name: "enum by value", typ: state, raw: `1`, want: u32le(1)},
+		{name: "unknown enum", typ: state, raw: `"PAUSED"`, wantErr: true},
+		{name: "char array string", typ: &btf.Array{Type: char, Nelems: 4}, raw: `"abc"`, want: []byte("abc\x00")},
+		{name: "char array too long", typ: &btf.Array{Type: char, Nelems: 4}, raw: `"abcd"`, wantErr: true},
+		{name: "int array", typ: &btf.Array{Type: s32, Nelems: 2}, raw: `[1]`, want: append(u32le(1), u32le(0)...)},
+		{
+			name: "struct",
+			typ:  config,
+			raw:  `{"pid": 42, "verbose": true, "comm": "sh"}`,
+			want: append(u32le(42), 1, 's', 'h', 0, 0, 0, 0, 0),
+		},
+		{
+			name:    "struct keeps unspecified members",
+			typ:     config,
+			raw:     `{"verbose": false, "comm": "a"}`,
+			current: append(u32le(7), 1, 'b', 'a', 's', 0, 0, 0, 0),
+			want:    append(u32le(7), 0, 'a', 0, 0, 0, 0, 0, 0),
+		},
+		{
+			name:    "array keeps unspecified elements",
+			typ:     &btf.Array{Type: s32, Nelems: 2},
+			raw:     `[1]`,
+			current: append(u32le(5), u32le(6)...),
+			want:    append(u32le(1), u32le(6)...),
+		},
+		{name: "current size mismatch", typ: s32, raw: `1`, current: []byte{0}, wantErr: true},
+		{name: "unknown member", typ: config, raw: `{"uid": 1}`, wantErr: true},
+		{name: "pointer", typ: &btf.Pointer{Target: s32}, raw: `0`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeVariable(tt.typ, json.RawMessage(tt.raw), tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncodeVariable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !bytes.Equal(got, tt.want) {
+				t.Errorf("EncodeVariable() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyVariables(t *testing.T) {
+	spec, err := ebpf.LoadCollectionSpec("../../../testdata/shepherd_x86_bpfel.o")
+	if err != nil {
+		t.Fatalf("load collection spec: %v", err)
+	}
+
+	if _, err := CheckVariable(spec, "unused_sched_latency_t"); err != nil {
+		t.Errorf("CheckVariable() error = %v", err)
+	}
+
+	err = ApplyVariables(spec, map[string]json.RawMessage{"missing": json.RawMessage(`1`)})
+	if !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("ApplyVariables() error = %v, want %v", err, ErrUnknownVariable)
+	}
+
+	// 指针变量无法通过 JSON 赋值
+	err = ApplyVariables(spec, map[string]json.RawMessage{"unused_sched_latency_t": json.RawMessage(`0`)})
+	if err == nil {
+		t.Errorf("ApplyVariables() expected error for pointer variable")
+	}
+}
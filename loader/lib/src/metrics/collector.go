@@ -31,9 +31,20 @@ type Collector interface {
 
 	GetAttachedPros() map[uint32]*ebpf.Program
 
+	// RegisterMapStats 注册 perf event 或 ring buffer map 的事件统计，同名的 map 会被替换
+	RegisterMapStats(name string, source MapStatsSource)
+
+	// GetMapStats 获取所有已注册 map 的事件统计
+	GetMapStats() []*meta.MapMetricsStats
+
 	Export() error
 }
 
+// MapStatsSource 提供 map 事件统计的快照
+type MapStatsSource interface {
+	Snapshot() *meta.MapMetricsStats
+}
+
 // collector 实现了 Collector 接口
 type StatsCollector struct {
 	// 互斥锁保护并发访问
@@ -48,6 +59,9 @@ type StatsCollector struct {
 	// 统计数据缓存
 	stats map[uint32]*meta.MetricsStats
 
+	// map 事件统计
+	mapStats map[string]MapStatsSource
+
 	// 采集间隔
 	interval time.Duration
 
@@ -78,6 +92,7 @@ func NewStatsCollector(interval time.Duration, exporterHandler meta.MetricsHandl
 	c := &StatsCollector{
 		programs:        make(map[uint32]*meta.ProgramStats),
 		stats:           make(map[uint32]*meta.MetricsStats),
+		mapStats:        make(map[string]MapStatsSource),
 		interval:        interval,
 		stopCh:          make(chan struct{}),
 		closer:          closer,
@@ -238,6 +253,8 @@ func (c *StatsCollector) Export() error {
 						c.logger.Error("导出 stats 信息失败", zap.Error(err))
 					}
 				}
+
+				c.exportMapStats()
 			}
 		}
 	}()
@@ -251,3 +268,35 @@ func (c *StatsCollector) GetAttachedPros() map[uint32]*ebpf.Program {
 
 	return c.attachedPros
 }
+
+func (c *StatsCollector) RegisterMapStats(name string, source MapStatsSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mapStats[name] = source
+}
+
+func (c *StatsCollector) GetMapStats() []*meta.MapMetricsStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := make([]*meta.MapMetricsStats, 0, len(c.mapStats))
+	for _, source := range c.mapStats {
+		stats = append(stats, source.Snapshot())
+	}
+	return stats
+}
+
+// exportMapStats 指标处理器实现 meta.MapMetricsHandler 时导出 map 事件统计
+func (c *StatsCollector) exportMapStats() {
+	handler, ok := c.exporterHandler.(meta.MapMetricsHandler)
+	if !ok {
+		return
+	}
+
+	for _, stats := range c.GetMapStats() {
+		if err := handler.HandleMapStats(stats); err != nil {
+			c.logger.Error("导出 map stats 信息失败", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"go.uber.org/zap/zaptest"
+)
+
+// staticMapStats 返回固定统计的 MapStatsSource
+type staticMapStats struct {
+	stats meta.MapMetricsStats
+}
+
+func (s *staticMapStats) Snapshot() *meta.MapMetricsStats {
+	stats := s.stats
+	return &stats
+}
+
+// mapStatsHandler 记录收到的 map 统计
+type mapStatsHandler struct {
+	mapStats []*meta.MapMetricsStats
+}
+
+func (h *mapStatsHandler) Handle(stats *meta.MetricsStats) error {
+	return nil
+}
+
+func (h *mapStatsHandler) HandleMapStats(stats *meta.MapMetricsStats) error {
+	h.mapStats = append(h.mapStats, stats)
+	return nil
+}
+
+func TestStatsCollector_MapStats(t *testing.T) {
+	handler := &mapStatsHandler{}
+	c := &StatsCollector{
+		mapStats:        make(map[string]MapStatsSource),
+		exporterHandler: handler,
+		logger:          zaptest.NewLogger(t),
+	}
+
+	c.RegisterMapStats("events", &staticMapStats{stats: meta.MapMetricsStats{Name: "events", Received: 1}})
+	// 同名 map 重新注册时替换旧的统计
+	c.RegisterMapStats("events", &staticMapStats{stats: meta.MapMetricsStats{Name: "events", Received: 10, Lost: 2}})
+
+	stats := c.GetMapStats()
+	if len(stats) != 1 || stats[0].Received != 10 || stats[0].Lost != 2 {
+		t.Fatalf("GetMapStats() = %+v, want one map with received 10 and lost 2", stats)
+	}
+
+	c.exportMapStats()
+	if len(handler.mapStats) != 1 || handler.mapStats[0].Name != "events" {
+		t.Errorf("HandleMapStats() got %+v", handler.mapStats)
+	}
+}
@@ -19,3 +19,13 @@ func (h *DefaultHandler) Handle(stats *meta.MetricsStats) error {
 	h.Logger.Info("stats", zap.Any("stats", stats))
 	return nil
 }
+
+func (h *DefaultHandler) HandleMapStats(stats *meta.MapMetricsStats) error {
+	if stats.Lost > 0 || stats.Dropped > 0 || (stats.Pipeline != nil && stats.Pipeline.Dropped > 0) {
+		h.Logger.Warn("map events lost", zap.Any("stats", stats))
+		return nil
+	}
+
+	h.Logger.Info("map stats", zap.Any("stats", stats))
+	return nil
+}
@@ -2,7 +2,10 @@ package profile
 
 import (
 	"fmt"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/cilium/ebpf"
@@ -10,6 +13,14 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+const (
+	// defaultSampleFreq 默认采样频率（Hz）
+	defaultSampleFreq = 200
+
+	// onlineCPUsPath 在线 CPU 列表
+	onlineCPUsPath = "/sys/devices/system/cpu/online"
+)
+
 type PerfEvent struct {
 	prog  *ebpf.Program
 	links []link.Link
@@ -17,43 +28,111 @@ type PerfEvent struct {
 	fds   []int
 }
 
+// PerfEventOptions perf event 配置
+type PerfEventOptions struct {
+	// Type perf 事件类型，例如 unix.PERF_TYPE_SOFTWARE
+	Type uint32
+
+	// Config perf 事件配置，例如 unix.PERF_COUNT_SW_CPU_CLOCK
+	Config uint64
+
+	// SampleFreq 采样频率（Hz），与 SamplePeriod 二选一，优先使用频率
+	SampleFreq uint64
+
+	// SamplePeriod 采样周期（事件数）
+	SamplePeriod uint64
+
+	// CPUs 采样的 CPU 列表，为空时使用所有在线 CPU
+	CPUs []int
+
+	// PID 只采样指定进程，0 表示所有进程
+	PID int
+
+	// CgroupPath 只采样指定 cgroup 中的进程，与 PID 互斥
+	CgroupPath string
+}
+
+// DefaultPerfEventOptions 默认配置：以 200Hz 在所有 CPU 上采样 CPU 时钟
+func DefaultPerfEventOptions() PerfEventOptions {
+	return PerfEventOptions{
+		Type:       unix.PERF_TYPE_SOFTWARE,
+		Config:     unix.PERF_COUNT_SW_CPU_CLOCK,
+		SampleFreq: defaultSampleFreq,
+	}
+}
+
 func NewPerfEvent(prog *ebpf.Program) (*PerfEvent, error) {
-	// 采样率
-	simpleRate := 200
+	return NewPerfEventWithOptions(prog, DefaultPerfEventOptions())
+}
 
-	// 创建 perf event 实例
-	pe := &PerfEvent{
-		prog:  prog,
-		links: make([]link.Link, runtime.NumCPU()),
-		attrs: make([]unix.PerfEventAttr, runtime.NumCPU()),
-		fds:   make([]int, runtime.NumCPU()),
+// NewPerfEventWithOptions 按配置在每个 CPU 上打开 perf event 并附加程序
+func NewPerfEventWithOptions(prog *ebpf.Program, opts PerfEventOptions) (*PerfEvent, error) {
+	if opts.PID != 0 && opts.CgroupPath != "" {
+		return nil, fmt.Errorf("pid and cgroup path are mutually exclusive")
+	}
+
+	cpus := opts.CPUs
+	if len(cpus) == 0 {
+		if opts.PID != 0 {
+			// 跟随进程在任意 CPU 上采样
+			cpus = []int{-1}
+		} else {
+			cpus = onlineCPUs()
+		}
 	}
 
 	// 设置采样属性
 	attr := unix.PerfEventAttr{
-		Type:        unix.PERF_TYPE_SOFTWARE,
+		Type:        opts.Type,
 		Size:        uint32(unsafe.Sizeof(unix.PerfEventAttr{})),
-		Config:      unix.PERF_COUNT_SW_CPU_CLOCK,
-		Sample:      uint64(simpleRate),
+		Config:      opts.Config,
 		Sample_type: unix.PERF_SAMPLE_RAW,
-		Bits:        unix.PerfBitDisabled | unix.PerfBitFreq,
+		Bits:        unix.PerfBitDisabled,
+	}
+	if opts.SampleFreq > 0 {
+		attr.Sample = opts.SampleFreq
+		attr.Bits |= unix.PerfBitFreq
+	} else if opts.SamplePeriod > 0 {
+		attr.Sample = opts.SamplePeriod
+	} else {
+		attr.Sample = defaultSampleFreq
+		attr.Bits |= unix.PerfBitFreq
+	}
+
+	pid := -1 // 所有进程
+	flags := unix.PERF_FLAG_FD_CLOEXEC
+	if opts.PID != 0 {
+		pid = opts.PID
+	}
+
+	if opts.CgroupPath != "" {
+		cgroup, err := os.Open(opts.CgroupPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open cgroup %s: %v", opts.CgroupPath, err)
+		}
+		defer cgroup.Close()
+
+		pid = int(cgroup.Fd())
+		flags |= unix.PERF_FLAG_PID_CGROUP
+	}
+
+	// 创建 perf event 实例
+	pe := &PerfEvent{
+		prog:  prog,
+		links: make([]link.Link, 0, len(cpus)),
+		attrs: make([]unix.PerfEventAttr, 0, len(cpus)),
+		fds:   make([]int, 0, len(cpus)),
 	}
 
 	// 在每个 CPU 上创建 perf event
-	for cpu := 0; cpu < runtime.NumCPU(); cpu++ {
+	for _, cpu := range cpus {
 		// 打开 perf event
-		fd, err := unix.PerfEventOpen(
-			&attr,
-			-1, // 所有进程
-			cpu,
-			-1,
-			unix.PERF_FLAG_FD_CLOEXEC,
-		)
+		fd, err := unix.PerfEventOpen(&attr, pid, cpu, -1, flags)
 		if err != nil {
 			pe.Close()
 			return nil, fmt.Errorf("failed to open perf event on CPU %d: %v", cpu, err)
 		}
-		pe.fds[cpu] = fd
+		pe.fds = append(pe.fds, fd)
 
 		// 附加 eBPF 程序
 		rawLink, err := link.AttachRawLink(link.RawLinkOptions{
@@ -65,8 +144,8 @@ func NewPerfEvent(prog *ebpf.Program) (*PerfEvent, error) {
 			pe.Close()
 			return nil, fmt.Errorf("failed to attach perf event on CPU %d: %v", cpu, err)
 		}
-		pe.links[cpu] = rawLink
-		pe.attrs[cpu] = attr
+		pe.links = append(pe.links, rawLink)
+		pe.attrs = append(pe.attrs, attr)
 	}
 
 	// 启用所有 perf event
@@ -80,6 +159,11 @@ func NewPerfEvent(prog *ebpf.Program) (*PerfEvent, error) {
 	return pe, nil
 }
 
+// Count 返回已附加的 perf event 数量
+func (pe *PerfEvent) Count() int {
+	return len(pe.links)
+}
+
 // Close 清理资源
 func (pe *PerfEvent) Close() error {
 	var errs []error
@@ -102,8 +186,57 @@ func (pe *PerfEvent) Close() error {
 		}
 	}
 
+	pe.links = nil
+	pe.fds = nil
+
 	if len(errs) > 0 {
 		return fmt.Errorf("errors closing perf event: %v", errs)
 	}
 	return nil
 }
+
+// onlineCPUs 返回在线 CPU 列表，读取失败时回退为 0..NumCPU-1
+func onlineCPUs() []int {
+	data, err := os.ReadFile(onlineCPUsPath)
+	if err == nil {
+		if cpus, err := parseCPUList(strings.TrimSpace(string(data))); err == nil && len(cpus) > 0 {
+			return cpus
+		}
+	}
+
+	cpus := make([]int, runtime.NumCPU())
+	for i := range cpus {
+		cpus[i] = i
+	}
+	return cpus
+}
+
+// parseCPUList 解析 "0-3,5,7-8" 格式的 CPU 列表
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		if part == "" {
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %v", list, err)
+		}
+
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(hi)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid cpu range %q", part)
+			}
+		}
+
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpus, nil
+}
@@ -0,0 +1,34 @@
+package profile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{name: "single", list: "0", want: []int{0}},
+		{name: "range", list: "0-3", want: []int{0, 1, 2, 3}},
+		{name: "mixed", list: "0-1,4,6-7", want: []int{0, 1, 4, 6, 7}},
+		{name: "empty", list: "", want: nil},
+		{name: "invalid number", list: "a-3", wantErr: true},
+		{name: "reversed range", list: "3-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCPUList(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCPUList(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCPUList(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
@@ -28,10 +28,14 @@ type BpfSkeletonBuilder struct {
 
 	// runnerConfig 运行时配置
 	runnerConfig *meta.RunnerConfig
+
+	// embeddedBTF 程序包内嵌的最小化 BTF，键为 btfhub 目录结构中的相对路径
+	embeddedBTF map[string][]byte
 }
 
 // NewBpfSkeletonBuilder 从对象元数据和对象缓冲区创建构建器
-// btfArchivePath - 内核 BTF 文件存档的根路径，如果不提供，将尝试使用环境变量 BTF_FILE_PATH 和 /sys/kernel/btf/vmlinux
+// btfArchivePath - 内核 BTF 文件存档的根路径，可以直接包含 vmlinux 文件，也可以是 btfhub 风格的目录结构
+// 如果不提供，将尝试使用环境变量 BTF_FILE_PATH 和 /sys/kernel/btf/vmlinux
 func NewBpfSkeletonBuilder(meta *meta.EunomiaObjectMeta, bpfObject []byte, btfArchivePath string) *BpfSkeletonBuilder {
 	return &BpfSkeletonBuilder{
 		btfArchivePath: btfArchivePath,
@@ -51,10 +55,16 @@ func (b *BpfSkeletonBuilder) SetRunnerConfig(cfg *meta.RunnerConfig) *BpfSkeleto
 	return b
 }
 
+// SetEmbeddedBTF 设置程序包内嵌的最小化 BTF，其他位置都找不到 BTF 时使用
+func (b *BpfSkeletonBuilder) SetEmbeddedBTF(btfs map[string][]byte) *BpfSkeletonBuilder {
+	b.embeddedBTF = btfs
+	return b
+}
+
 // Build 构建并打开骨架
 func (b *BpfSkeletonBuilder) Build() (*PreLoadBpfSkeleton, error) {
 	// 加载 BTF
-	vmlinux, err := b.loadBTF()
+	vmlinux, system, err := b.loadBTF()
 	if err != nil {
 		return nil, fmt.Errorf("load BTF error: %w", err)
 	}
@@ -87,14 +97,20 @@ func (b *BpfSkeletonBuilder) Build() (*PreLoadBpfSkeleton, error) {
 		return nil, fmt.Errorf("get map value sizes error: %w", err)
 	}
 
-	return &PreLoadBpfSkeleton{
+	skel := &PreLoadBpfSkeleton{
 		Meta:          b.objectMeta,
 		ConfigData:    b.runnerConfig,
 		Btf:           btf,
+		KernelBtf:     vmlinux,
 		Spec:          spec,
 		MapValueSizes: mapValueSizes,
 		RawElf:        rawElf,
-	}, nil
+	}
+	if !system {
+		skel.CoreBtf = vmlinux
+	}
+
+	return skel, nil
 }
 
 // createCollectionSpec 创建 CollectionSpec
@@ -108,22 +124,46 @@ func (b *BpfSkeletonBuilder) createCollectionSpec() (*ebpf.CollectionSpec, error
 	return spec, nil
 }
 
-// loadBTF 加载 BTF 信息
-func (b *BpfSkeletonBuilder) loadBTF() (*btf.Spec, error) {
+// loadBTF 加载内核 BTF 信息，system 表示 BTF 来自运行中的内核
+func (b *BpfSkeletonBuilder) loadBTF() (spec *btf.Spec, system bool, err error) {
 	// 尝试从不同位置加载 BTF 文件
-	btfPath, err := b.findBTFFile()
+	if btfPath, err := b.findBTFFile(); err == nil {
+		spec, err := btfutils.LoadBTFFile(btfPath)
+		return spec, btfPath == VMLINUX_BTF_PATH, err
+	}
+
+	// 内核没有提供 BTF 时，按发行版、版本和架构查找归档
+	platform, err := btfutils.CurrentPlatform()
 	if err != nil {
-		return nil, err
+		return nil, false, fmt.Errorf("%w: %v", btfutils.ErrBTFNotFound, err)
 	}
 
-	// 创建 BTF 容器
-	return btfutils.LoadSystemBTF(btfPath)
+	if b.btfArchivePath != "" {
+		if spec, err := btfutils.LoadArchiveBTF(b.btfArchivePath, platform); !errors.Is(err, btfutils.ErrBTFNotFound) {
+			return spec, false, err
+		}
+	}
+
+	if data, ok := b.embeddedBTF[platform.Path()]; ok {
+		spec, err := btf.LoadSpecFromReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, false, fmt.Errorf("load embedded BTF for %s error: %w", platform, err)
+		}
+		return spec, false, nil
+	}
+
+	return nil, false, fmt.Errorf("%w. Tried: custom path, %s, %s, archive and embedded BTF for %s",
+		btfutils.ErrBTFNotFound, BTF_PATH_ENV_NAME, VMLINUX_BTF_PATH, platform)
 }
 
 // findBTFFile 查找 BTF 文件
 func (b *BpfSkeletonBuilder) findBTFFile() (string, error) {
-	// 1. 检查自定义路径
+	// 1. 检查自定义路径，可以直接指定 BTF 文件
 	if b.btfArchivePath != "" {
+		if fileExists(b.btfArchivePath) {
+			return b.btfArchivePath, nil
+		}
+
 		path := filepath.Join(b.btfArchivePath, "vmlinux")
 		if fileExists(path) {
 			return path, nil
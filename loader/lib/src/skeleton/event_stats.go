@@ -0,0 +1,117 @@
+package skeleton
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf"
+)
+
+// EventStats perf event 和 ring buffer 轮询器按 CPU 累计的事件、丢失和丢弃计数
+type EventStats struct {
+	name    string
+	mapType ebpf.MapType
+
+	mu         sync.Mutex
+	cpus       map[int]*meta.MapCPUStats
+	lastUpdate time.Time
+	pipeline   *Pipeline
+}
+
+// NewEventStats 创建 map 的事件统计
+func NewEventStats(name string, mapType ebpf.MapType) *EventStats {
+	return &EventStats{
+		name:       name,
+		mapType:    mapType,
+		cpus:       make(map[int]*meta.MapCPUStats),
+		lastUpdate: time.Now(),
+	}
+}
+
+// SetPipeline 设置 map 的事件处理流水线，快照中附带其队列统计
+func (s *EventStats) SetPipeline(pipeline *Pipeline) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pipeline = pipeline
+}
+
+// cpu 返回 CPU 的计数，调用方持有锁
+func (s *EventStats) cpu(cpu int) *meta.MapCPUStats {
+	stats, ok := s.cpus[cpu]
+	if !ok {
+		stats = &meta.MapCPUStats{CPU: cpu}
+		s.cpus[cpu] = stats
+	}
+	return stats
+}
+
+// addLost 累加内核丢弃的事件数
+func (s *EventStats) addLost(cpu int, lost uint64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cpu(cpu).Lost += lost
+	s.lastUpdate = time.Now()
+}
+
+// addBatch 累加一批事件，cpus 为每条事件的 CPU，为空时全部计入 CPUUnknown
+// dropped 为处理失败的事件下标
+func (s *EventStats) addBatch(size int, cpus []int, dropped []int) {
+	if s == nil || size == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cpuOf := func(i int) int {
+		if cpus == nil {
+			return CPUUnknown
+		}
+		return cpus[i]
+	}
+
+	for i := 0; i < size; i++ {
+		s.cpu(cpuOf(i)).Received++
+	}
+	for _, i := range dropped {
+		s.cpu(cpuOf(i)).Dropped++
+	}
+	s.lastUpdate = time.Now()
+}
+
+// Snapshot 返回当前计数的副本，CPU 按编号排序
+func (s *EventStats) Snapshot() *meta.MapMetricsStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &meta.MapMetricsStats{
+		Name:       s.name,
+		Type:       s.mapType.String(),
+		CPUs:       make([]meta.MapCPUStats, 0, len(s.cpus)),
+		LastUpdate: s.lastUpdate,
+	}
+
+	for _, cpu := range s.cpus {
+		stats.Received += cpu.Received
+		stats.Lost += cpu.Lost
+		stats.Dropped += cpu.Dropped
+		stats.CPUs = append(stats.CPUs, *cpu)
+	}
+
+	if s.pipeline != nil {
+		stats.Pipeline = s.pipeline.Stats()
+	}
+
+	sort.Slice(stats.CPUs, func(i, j int) bool {
+		return stats.CPUs[i].CPU < stats.CPUs[j].CPU
+	})
+
+	return stats
+}
@@ -0,0 +1,154 @@
+package export
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// valueDelta 按 value 的 BTF 类型计算 current 相对 previous 的差值，只保留整数和浮点数字段
+// per-CPU map 按 CPU 分别输出时 current 为各 CPU 的值，逐个计算
+// 没有数值字段时第二个返回值为 false
+func valueDelta(members []CheckedExportedMember, current, previous interface{}) (interface{}, bool) {
+	if cv, ok := current.([]interface{}); ok {
+		pv, _ := previous.([]interface{})
+		if len(pv) != len(cv) {
+			pv = nil
+		}
+		out := make([]interface{}, 0, len(cv))
+		for i, v := range cv {
+			var p interface{}
+			if pv != nil {
+				p = pv[i]
+			}
+			d, ok := valueDelta(members, v, p)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, d)
+		}
+		return out, len(out) > 0
+	}
+
+	cv, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	pv, _ := previous.(map[string]interface{})
+	out := make(map[string]interface{})
+	for _, member := range members {
+		if d, ok := numericDelta(member.Type, cv[member.FieldName], pv[member.FieldName]); ok {
+			out[member.FieldName] = d
+		}
+	}
+	return out, len(out) > 0
+}
+
+// numericDelta 计算 typ 类型的 current 相对 previous 的差值，结构体和数组递归计算
+// 只有整数和浮点数参与计算，bool、char、枚举和指针等字段被忽略
+// previous 中没有对应字段时按 0 计算，数值变小时视为计数器重置，差值取当前值
+// 没有数值字段时第二个返回值为 false
+func numericDelta(typ btf.Type, current, previous interface{}) (interface{}, bool) {
+	switch t := btf.UnderlyingType(typ).(type) {
+	case *btf.Int:
+		if t.Encoding == btf.Bool || t.Encoding == btf.Char {
+			return nil, false
+		}
+		return numberDelta(current, previous)
+	case *btf.Float:
+		return numberDelta(current, previous)
+	case *btf.Struct:
+		cv, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		pv, _ := previous.(map[string]interface{})
+		out := make(map[string]interface{})
+		for _, member := range t.Members {
+			if d, ok := numericDelta(member.Type, cv[member.Name], pv[member.Name]); ok {
+				out[member.Name] = d
+			}
+		}
+		return out, len(out) > 0
+	case *btf.Array:
+		// char 数组按字符串输出，不是 []interface{}
+		cv, ok := current.([]interface{})
+		if !ok {
+			return nil, false
+		}
+		pv, _ := previous.([]interface{})
+		if len(pv) != len(cv) {
+			pv = nil
+		}
+		out := make([]interface{}, 0, len(cv))
+		for i, v := range cv {
+			var p interface{}
+			if pv != nil {
+				p = pv[i]
+			}
+			d, ok := numericDelta(t.Type, v, p)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, d)
+		}
+		return out, len(out) > 0
+	default:
+		return nil, false
+	}
+}
+
+// numberDelta 计算两个 json.Number 的差值
+func numberDelta(current, previous interface{}) (interface{}, bool) {
+	cv, ok := current.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	cur, ok := parseNumber(cv)
+	if !ok {
+		return nil, false
+	}
+	prev := new(big.Float)
+	if pv, ok := previous.(json.Number); ok {
+		if p, ok := parseNumber(pv); ok {
+			prev = p
+		}
+	}
+	if cur.Cmp(prev) < 0 {
+		return numberOf(cur), true
+	}
+	return numberOf(new(big.Float).SetPrec(128).Sub(cur, prev)), true
+}
+
+// scaleNumbers 将所有数值乘以 factor，用于由差值计算速率
+func scaleNumbers(v interface{}, factor float64) interface{} {
+	switch tv := v.(type) {
+	case json.Number:
+		n, ok := parseNumber(tv)
+		if !ok {
+			return tv
+		}
+		f, _ := new(big.Float).SetPrec(128).Mul(n, big.NewFloat(factor)).Float64()
+		return f
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(tv))
+		for k, item := range tv {
+			out[k] = scaleNumbers(item, factor)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(tv))
+		for i, item := range tv {
+			out[i] = scaleNumbers(item, factor)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// parseNumber 解析 json.Number，保留 64 位整数的精度
+func parseNumber(n json.Number) (*big.Float, bool) {
+	return new(big.Float).SetPrec(128).SetString(n.String())
+}
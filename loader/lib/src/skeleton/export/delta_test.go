@@ -0,0 +1,148 @@
+package export
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf/btf"
+)
+
+func TestValueDelta(t *testing.T) {
+	char := &btf.Int{Name: "char", Size: 1, Encoding: btf.Char}
+	members := []CheckedExportedMember{
+		{FieldName: "count", Type: u64Type},
+		{FieldName: "comm", Type: &btf.Array{Type: char, Nelems: 16}},
+		{FieldName: "hist", Type: &btf.Array{Type: u32Type, Nelems: 2}},
+		{FieldName: "max", Type: &btf.Typedef{Name: "__u64", Type: u64Type}},
+		{FieldName: "new", Type: u64Type},
+		{FieldName: "state", Type: &btf.Enum{Name: "state", Size: 4}},
+		{FieldName: "active", Type: &btf.Int{Name: "_Bool", Size: 1, Encoding: btf.Bool}},
+		{FieldName: "latency", Type: &btf.Struct{Name: "latency", Members: []btf.Member{
+			{Name: "avg", Type: &btf.Float{Name: "double", Size: 8}},
+			{Name: "level", Type: &btf.Enum{Name: "level", Size: 4}},
+		}}},
+	}
+
+	current, _ := decodeJsonNumber([]byte(`{"count": 15, "comm": "bash", "hist": [3, 5], "max": 2, "new": 7, "state": 3, "active": true,
+		"latency": {"__EUNOMIA_TYPE": "struct", "avg": 2.5, "level": 2}}`))
+	previous, _ := decodeJsonNumber([]byte(`{"count": 10, "comm": "bash", "hist": [1, 5], "max": 9, "state": 1, "active": false,
+		"latency": {"__EUNOMIA_TYPE": "struct", "avg": 1.5, "level": 1}}`))
+
+	got, ok := valueDelta(members, current, previous)
+	if !ok {
+		t.Fatal("valueDelta() found no numeric fields")
+	}
+
+	// 按 BTF 类型选择字段：char 数组、枚举和 bool 被忽略，max 变小视为重置，new 从 0 开始计算
+	want, _ := decodeJsonNumber([]byte(`{"count": 5, "hist": [2, 0], "max": 2, "new": 7, "latency": {"avg": 1}}`))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("valueDelta() = %v, want %v", got, want)
+	}
+
+	if _, ok := valueDelta(members[5:7], current, previous); ok {
+		t.Error("valueDelta() of enum and bool fields should report no fields")
+	}
+
+	// 按 CPU 分别输出时逐个 CPU 计算
+	perCPU, ok := valueDelta(members[:1], []interface{}{current, current}, []interface{}{previous, previous})
+	if !ok || len(perCPU.([]interface{})) != 2 {
+		t.Errorf("valueDelta() per CPU = %v", perCPU)
+	}
+
+	rate := scaleNumbers(got, 0.5)
+	if r := rate.(map[string]interface{})["count"]; r != 2.5 {
+		t.Errorf("rate count = %v, want 2.5", r)
+	}
+}
+
+func TestJsonMapExporter_Delta(t *testing.T) {
+	collector := &jsonCollector{}
+	exporter := &EventExporter{UserExportEventHandler: collector}
+	exporter.InternalImpl = &KeyValueMapProcessor{
+		CheckedKeyTypes:   []CheckedExportedMember{{FieldName: "pid", Type: u32Type}},
+		CheckedValueTypes: []CheckedExportedMember{{FieldName: "count", Type: u64Type}},
+		MapConfig:         &meta.MapSampleMeta{Mode: meta.SampleModeDelta},
+	}
+	h := NewJsonMapExporter(exporter)
+	if !h.ComparesSnapshots() {
+		t.Fatal("ComparesSnapshots() = false in delta mode")
+	}
+
+	entry := func(pid uint32, count uint64) ([]byte, []byte) {
+		key := binary.LittleEndian.AppendUint32(nil, pid)
+		return key, binary.LittleEndian.AppendUint64(nil, count)
+	}
+	snapshot := func(counts map[uint32]uint64) {
+		t.Helper()
+		var keys, values [][]byte
+		for pid, count := range counts {
+			k, v := entry(pid, count)
+			keys, values = append(keys, k), append(values, v)
+		}
+		if err := h.HandleSnapshot(keys, values); err != nil {
+			t.Fatalf("HandleSnapshot() error = %v", err)
+		}
+	}
+
+	// 第一次快照只作为基准
+	snapshot(map[uint32]uint64{1: 10, 2: 20})
+	if len(collector.events) != 0 {
+		t.Fatalf("baseline emitted %d events, want 0", len(collector.events))
+	}
+
+	snapshot(map[uint32]uint64{1: 15, 3: 4})
+
+	type output struct {
+		Key     map[string]uint32 `json:"key"`
+		Delta   map[string]uint64 `json:"delta"`
+		Rate    map[string]float64
+		Evicted bool `json:"evicted"`
+	}
+	got := make(map[uint32]output)
+	for _, event := range collector.events {
+		var out output
+		if err := json.Unmarshal([]byte(event), &out); err != nil {
+			t.Fatalf("unmarshal %s error = %v", event, err)
+		}
+		got[out.Key["pid"]] = out
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got events %v, want 3", collector.events)
+	}
+	if got[1].Delta["count"] != 5 || got[1].Evicted {
+		t.Errorf("pid 1 = %+v, want delta 5", got[1])
+	}
+	if got[3].Delta["count"] != 4 {
+		t.Errorf("pid 3 = %+v, want delta 4 for a new key", got[3])
+	}
+	if !got[2].Evicted {
+		t.Errorf("pid 2 = %+v, want evicted", got[2])
+	}
+}
+
+func TestJsonMapExporter_SnapshotMode(t *testing.T) {
+	collector := &jsonCollector{}
+	exporter := &EventExporter{UserExportEventHandler: collector}
+	exporter.InternalImpl = &KeyValueMapProcessor{
+		CheckedKeyTypes:   []CheckedExportedMember{{FieldName: "pid", Type: u32Type}},
+		CheckedValueTypes: []CheckedExportedMember{{FieldName: "count", Type: &btf.Int{Size: 8}}},
+	}
+	h := NewJsonMapExporter(exporter)
+
+	keys := [][]byte{{1, 0, 0, 0}, {2, 0, 0, 0}}
+	values := [][]byte{make([]byte, 8), make([]byte, 8)}
+	for i := 0; i < 2; i++ {
+		if err := h.HandleSnapshot(keys, values); err != nil {
+			t.Fatalf("HandleSnapshot() error = %v", err)
+		}
+	}
+
+	// 未配置 delta 时每次快照输出所有键
+	if len(collector.events) != 4 {
+		t.Errorf("got %d events, want 4", len(collector.events))
+	}
+}
@@ -32,9 +32,9 @@ func DumpToJson(typ btf.Type, data []byte) (json.RawMessage, error) {
 	case *btf.Typedef:
 		return handleTypedef(t, data)
 	case *btf.Volatile:
-		return DumpToJson(typ, data)
+		return DumpToJson(t.Type, data)
 	case *btf.Const:
-		return DumpToJson(typ, data)
+		return DumpToJson(t.Type, data)
 	default:
 		return nil, fmt.Errorf("unsupported type: %T", t)
 	}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/helper"
+	"github.com/cilium/ebpf"
 )
 
 // InternalBufferValueEventProcessor 内部缓冲区事件处理器
@@ -45,10 +46,9 @@ func (h *JsonExportEventHandler) HandleEvent(data []byte) error {
 		return fmt.Errorf("get checked types error: %w", err)
 	}
 
-	// 导出为JSON
-	jsonData, err := DumpToJsonWithCheckedTypes(checkedTypes, data)
+	event, err := h.decode(checkedTypes, data)
 	if err != nil {
-		return fmt.Errorf("dump to json error: %w", err)
+		return err
 	}
 
 	// 检查 UserExportEventHandler 是否为 nil
@@ -57,10 +57,49 @@ func (h *JsonExportEventHandler) HandleEvent(data []byte) error {
 	}
 
 	// 输出数据
-	return h.Exporter.UserExportEventHandler.HandleEvent(h.Exporter.UserCtx, &meta.ReceivedEventData{
+	return h.Exporter.UserExportEventHandler.HandleEvent(h.Exporter.UserCtx, event)
+}
+
+// HandleEvents 批量导出事件，解码失败的事件被跳过，其余事件照常交付
+func (h *JsonExportEventHandler) HandleEvents(batch [][]byte) error {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+
+	checkedTypes, err := h.Exporter.InternalImpl.GetCheckedTypes()
+	if err != nil {
+		return fmt.Errorf("get checked types error: %w", err)
+	}
+
+	events := make([]*meta.ReceivedEventData, 0, len(batch))
+	var decodeErr error
+	for _, data := range batch {
+		event, err := h.decode(checkedTypes, data)
+		if err != nil {
+			if decodeErr == nil {
+				decodeErr = err
+			}
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if err := deliverEvents(h.Exporter, events); err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// decode 将原始事件转换为 JSON
+func (h *JsonExportEventHandler) decode(checkedTypes []CheckedExportedMember, data []byte) (*meta.ReceivedEventData, error) {
+	jsonData, err := DumpToJsonWithCheckedTypes(checkedTypes, data)
+	if err != nil {
+		return nil, fmt.Errorf("dump to json error: %w", err)
+	}
+
+	return &meta.ReceivedEventData{
 		Type:     meta.TypeJsonText,
 		JsonText: string(jsonData),
-	})
+	}, nil
 }
 
 // PlainTextExportEventHandler 纯文本导出处理器
@@ -140,9 +179,67 @@ func (h *RawExportEventHandler) HandleEvent(data []byte) error {
 	})
 }
 
+// HandleEvents 批量传递原始数据
+func (h *RawExportEventHandler) HandleEvents(batch [][]byte) error {
+	h.Mu.RLock()
+	defer h.Mu.RUnlock()
+
+	if h.Exporter.UserExportEventHandler == nil {
+		fmt.Println("Raw export event handler expects user callback, data will be dropped")
+		return nil
+	}
+
+	events := make([]*meta.ReceivedEventData, 0, len(batch))
+	for _, data := range batch {
+		events = append(events, &meta.ReceivedEventData{
+			Type:   meta.TypeBuffer,
+			Buffer: data,
+		})
+	}
+
+	return deliverEvents(h.Exporter, events)
+}
+
+// deliverEvents 将一批事件交给用户处理器，处理器实现 BatchEventHandler 时一次交付
+func deliverEvents(exporter *EventExporter, events []*meta.ReceivedEventData) error {
+	handler := exporter.UserExportEventHandler
+	if handler == nil {
+		return fmt.Errorf("UserExportEventHandler is nil, please set it before calling HandleEvent")
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	if batch, ok := handler.(BatchEventHandler); ok {
+		return batch.HandleEvents(exporter.UserCtx, events)
+	}
+
+	for _, event := range events {
+		if err := handler.HandleEvent(exporter.UserCtx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // JsonMapExporter JSON 格式导出处理器
 type JsonMapExporter struct {
 	Exporter *EventExporter
+
+	// MapType 为 per-CPU map 时按 CPU 切分值并分别解码，按采样配置的 PerCPU 输出
+	MapType ebpf.MapType
+
+	// delta 模式下上一次快照的解码结果和时间
+	previous     map[string]*sampledEntry
+	previousTime time.Time
+}
+
+// sampledEntry 快照中一个键解码后的结果
+type sampledEntry struct {
+	key   interface{}
+	value interface{}
 }
 
 func NewJsonMapExporter(exporter *EventExporter) *JsonMapExporter {
@@ -150,36 +247,144 @@ func NewJsonMapExporter(exporter *EventExporter) *JsonMapExporter {
 }
 
 func (h *JsonMapExporter) HandleEvent(keyBuffer, valueBuffer []byte) error {
+	keyOut, valueOut, err := h.decode(keyBuffer, valueBuffer)
+	if err != nil {
+		return err
+	}
+
+	// 构造最终的 JSON
+	return h.emit(map[string]interface{}{
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"key":       keyOut,
+		"value":     valueOut,
+	})
+}
+
+// ComparesSnapshots delta 模式下需要比较前后两次快照
+func (h *JsonMapExporter) ComparesSnapshots() bool {
+	return h.sampleMode() == meta.SampleModeDelta
+}
+
+// HandleSnapshot 处理一次完整的 map 快照
+// delta 模式下按 value 的 BTF 类型输出每个键与上一次快照之间整数和浮点数字段的差值和每秒速率，上一次快照中存在而本次消失的键作为淘汰输出
+// 第一次快照只作为基准，不输出
+func (h *JsonMapExporter) HandleSnapshot(keys, values [][]byte) error {
+	if h.sampleMode() != meta.SampleModeDelta {
+		for i := range keys {
+			if err := h.HandleEvent(keys[i], values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	checkedValueTypes, err := h.Exporter.InternalImpl.GetCheckedValueTypes()
+	if err != nil {
+		return fmt.Errorf("get checked types error: %w", err)
+	}
+
+	now := time.Now()
+	current := make(map[string]*sampledEntry, len(keys))
+	for i := range keys {
+		keyOut, valueOut, err := h.decode(keys[i], values[i])
+		if err != nil {
+			return err
+		}
+		current[string(keys[i])] = &sampledEntry{key: keyOut, value: valueOut}
+	}
+
+	previous, previousTime := h.previous, h.previousTime
+	h.previous, h.previousTime = current, now
+	if previous == nil {
+		return nil
+	}
+
+	timestamp := now.Format("2006-01-02 15:04:05")
+	elapsed := now.Sub(previousTime).Seconds()
+	for i := range keys {
+		entry := current[string(keys[i])]
+
+		// 新出现的键从 0 开始计算
+		var last interface{}
+		if prev, ok := previous[string(keys[i])]; ok {
+			last = prev.value
+		}
+
+		delta, _ := valueDelta(checkedValueTypes, entry.value, last)
+		result := map[string]interface{}{
+			"timestamp": timestamp,
+			"key":       entry.key,
+			"value":     entry.value,
+			"delta":     delta,
+			"interval":  elapsed,
+		}
+		if elapsed > 0 {
+			result["rate"] = scaleNumbers(delta, 1/elapsed)
+		}
+		if err := h.emit(result); err != nil {
+			return err
+		}
+	}
+
+	for key, prev := range previous {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		if err := h.emit(map[string]interface{}{
+			"timestamp": timestamp,
+			"key":       prev.key,
+			"value":     prev.value,
+			"evicted":   true,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decode 按 BTF 解码 key 和 value，per-CPU map 的 value 按 CPU 解码后合并
+func (h *JsonMapExporter) decode(keyBuffer, valueBuffer []byte) (interface{}, interface{}, error) {
 	// 获取检查过的类型信息
 	checkedKeyTypes, err := h.Exporter.InternalImpl.GetCheckedKeyTypes()
 	if err != nil {
-		return fmt.Errorf("get checked types error: %w", err)
+		return nil, nil, fmt.Errorf("get checked types error: %w", err)
 	}
 
 	checkedValueTypes, err := h.Exporter.InternalImpl.GetCheckedValueTypes()
 	if err != nil {
-		return fmt.Errorf("get checked types error: %w", err)
+		return nil, nil, fmt.Errorf("get checked types error: %w", err)
 	}
 
 	// 导出 key
 	keyOut, err := DumpToJsonWithCheckedTypes(checkedKeyTypes, keyBuffer)
 	if err != nil {
-		return fmt.Errorf("dump key to json error: %w", err)
+		return nil, nil, fmt.Errorf("dump key to json error: %w", err)
 	}
 
-	// 导出 value
-	valueOut, err := DumpToJsonWithCheckedTypes(checkedValueTypes, valueBuffer)
-	if err != nil {
-		return fmt.Errorf("dump value to json error: %w", err)
+	// 导出 value，解码为通用结构以便合并和计算差值
+	if IsPerCPUMap(h.MapType) {
+		valueOut, err := decodePerCPU(checkedValueTypes, valueBuffer, h.sampleConfig().PerCPU)
+		if err != nil {
+			return nil, nil, fmt.Errorf("dump value to json error: %w", err)
+		}
+		return keyOut, valueOut, nil
 	}
 
-	// 构造最终的 JSON
-	result := map[string]interface{}{
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
-		"key":       keyOut,
-		"value":     valueOut,
+	valueJson, err := DumpToJsonWithCheckedTypes(checkedValueTypes, valueBuffer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dump value to json error: %w", err)
+	}
+	valueOut, err := decodeJsonNumber(valueJson)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	return keyOut, valueOut, nil
+}
+
+// emit 将结果编码为 JSON 交给用户的事件处理器
+func (h *JsonMapExporter) emit(result map[string]interface{}) error {
 	jsonData, err := json.Marshal(result)
 	if err != nil {
 		return fmt.Errorf("marshal json error: %w", err)
@@ -199,6 +404,19 @@ func (h *JsonMapExporter) HandleEvent(keyBuffer, valueBuffer []byte) error {
 	return nil
 }
 
+// sampleConfig 返回导出器的采样配置，没有配置时返回空配置
+func (h *JsonMapExporter) sampleConfig() *meta.MapSampleMeta {
+	if kv, ok := h.Exporter.InternalImpl.(*KeyValueMapProcessor); ok && kv.MapConfig != nil {
+		return kv.MapConfig
+	}
+	return &meta.MapSampleMeta{}
+}
+
+// sampleMode 返回采样配置中的采样方式
+func (h *JsonMapExporter) sampleMode() meta.SampleMode {
+	return h.sampleConfig().Mode
+}
+
 // PlainTextMapExporter 纯文本导出处理器
 type PlainTextMapExporter struct {
 	Exporter *EventExporter
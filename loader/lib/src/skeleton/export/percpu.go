@@ -0,0 +1,147 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf"
+)
+
+// IsPerCPUMap map 的值是否按 CPU 存储
+func IsPerCPUMap(t ebpf.MapType) bool {
+	return t == ebpf.PerCPUHash || t == ebpf.PerCPUArray || t == ebpf.LRUCPUHash
+}
+
+// splitPerCPU 将按 CPU 顺序拼接的值切分为每个 CPU 的值
+func splitPerCPU(value []byte) ([][]byte, error) {
+	cpus, err := ebpf.PossibleCPU()
+	if err != nil {
+		return nil, fmt.Errorf("get possible cpus error: %w", err)
+	}
+
+	if len(value) == 0 || len(value)%cpus != 0 {
+		return nil, fmt.Errorf("per-CPU value of %d bytes does not divide into %d CPUs", len(value), cpus)
+	}
+
+	size := len(value) / cpus
+	slots := make([][]byte, cpus)
+	for i := range slots {
+		slots[i] = value[i*size : (i+1)*size]
+	}
+	return slots, nil
+}
+
+// decodePerCPU 按 BTF 解码每个 CPU 的值，并按 mode 合并
+func decodePerCPU(checkedTypes []CheckedExportedMember, value []byte, mode meta.PerCPUMode) (interface{}, error) {
+	slots, err := splitPerCPU(value)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(slots))
+	for cpu, slot := range slots {
+		out, err := DumpToJsonWithCheckedTypes(checkedTypes, slot)
+		if err != nil {
+			return nil, fmt.Errorf("dump cpu %d value to json error: %w", cpu, err)
+		}
+		if values[cpu], err = decodeJsonNumber(out); err != nil {
+			return nil, err
+		}
+	}
+
+	switch mode {
+	case meta.PerCPUBreakdown:
+		return values, nil
+	case meta.PerCPUMax:
+		return mergeValues(values, maxNumber), nil
+	case meta.PerCPUSum, "":
+		return mergeValues(values, sumNumber), nil
+	default:
+		return nil, fmt.Errorf("unknown per-CPU mode %q", mode)
+	}
+}
+
+// decodeJsonNumber 解码 JSON，数字保留为 json.Number 避免丢失精度
+func decodeJsonNumber(data []byte) (interface{}, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode json error: %w", err)
+	}
+	return v, nil
+}
+
+// mergeValues 逐字段合并各 CPU 的值，数字按 op 合并，结构体和数组递归合并，其余字段取第一个 CPU 的值
+func mergeValues(values []interface{}, op func(a, b *big.Float) *big.Float) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	result := values[0]
+	for _, v := range values[1:] {
+		result = merge(result, v, op)
+	}
+	return result
+}
+
+func merge(a, b interface{}, op func(a, b *big.Float) *big.Float) interface{} {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return a
+		}
+		x, okA := parseNumber(av)
+		y, okB := parseNumber(bv)
+		if !okA || !okB {
+			return a
+		}
+		return numberOf(op(x, y))
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			return a
+		}
+		out := make(map[string]interface{}, len(av))
+		for k, v := range av {
+			out[k] = merge(v, bv[k], op)
+		}
+		return out
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return a
+		}
+		out := make([]interface{}, len(av))
+		for i := range av {
+			out[i] = merge(av[i], bv[i], op)
+		}
+		return out
+	default:
+		return a
+	}
+}
+
+func sumNumber(a, b *big.Float) *big.Float {
+	return new(big.Float).SetPrec(128).Add(a, b)
+}
+
+func maxNumber(a, b *big.Float) *big.Float {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// numberOf 整数按整数输出，其余按浮点数输出
+func numberOf(f *big.Float) json.Number {
+	if f.IsInt() {
+		i, _ := f.Int(nil)
+		return json.Number(i.String())
+	}
+	return json.Number(f.Text('g', -1))
+}
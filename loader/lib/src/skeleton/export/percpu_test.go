@@ -0,0 +1,141 @@
+package export
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// jsonCollector 记录收到的 JSON
+type jsonCollector struct {
+	events []string
+}
+
+func (c *jsonCollector) HandleEvent(ctx *meta.UserContext, data *meta.ReceivedEventData) error {
+	c.events = append(c.events, data.JsonText)
+	return nil
+}
+
+var (
+	u32Type = &btf.Int{Name: "u32", Size: 4}
+	u64Type = &btf.Int{Name: "u64", Size: 8}
+
+	// counterMembers 值为 struct { u64 count; u32 hist[2]; }
+	counterMembers = []CheckedExportedMember{
+		{FieldName: "count", Type: u64Type, BitOffset: 0},
+		{FieldName: "hist", Type: &btf.Array{Type: u32Type, Index: u32Type, Nelems: 2}, BitOffset: 64},
+	}
+)
+
+// perCPUCounters 返回每个 CPU 的 count = cpu+1，hist = [cpu, 10]
+func perCPUCounters(t *testing.T) ([]byte, int) {
+	t.Helper()
+
+	cpus, err := ebpf.PossibleCPU()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := make([]byte, 16*cpus)
+	for cpu := 0; cpu < cpus; cpu++ {
+		slot := value[cpu*16:]
+		binary.LittleEndian.PutUint64(slot, uint64(cpu+1))
+		binary.LittleEndian.PutUint32(slot[8:], uint32(cpu))
+		binary.LittleEndian.PutUint32(slot[12:], 10)
+	}
+	return value, cpus
+}
+
+func TestDecodePerCPU(t *testing.T) {
+	value, cpus := perCPUCounters(t)
+
+	type counter struct {
+		Count uint64    `json:"count"`
+		Hist  [2]uint64 `json:"hist"`
+	}
+
+	decode := func(mode meta.PerCPUMode, out interface{}) {
+		t.Helper()
+		v, err := decodePerCPU(counterMembers, value, mode)
+		if err != nil {
+			t.Fatalf("decodePerCPU(%s) error = %v", mode, err)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("unmarshal %s error = %v", data, err)
+		}
+	}
+
+	var sum counter
+	decode(meta.PerCPUSum, &sum)
+	want := counter{Count: uint64(cpus * (cpus + 1) / 2), Hist: [2]uint64{uint64(cpus * (cpus - 1) / 2), uint64(10 * cpus)}}
+	if sum != want {
+		t.Errorf("sum = %+v, want %+v", sum, want)
+	}
+
+	var max counter
+	decode(meta.PerCPUMax, &max)
+	want = counter{Count: uint64(cpus), Hist: [2]uint64{uint64(cpus - 1), 10}}
+	if max != want {
+		t.Errorf("max = %+v, want %+v", max, want)
+	}
+
+	var breakdown []counter
+	decode(meta.PerCPUBreakdown, &breakdown)
+	if len(breakdown) != cpus {
+		t.Fatalf("got %d CPUs, want %d", len(breakdown), cpus)
+	}
+	for cpu, c := range breakdown {
+		if c.Count != uint64(cpu+1) || c.Hist != [2]uint64{uint64(cpu), 10} {
+			t.Errorf("cpu %d = %+v", cpu, c)
+		}
+	}
+
+	if _, err := decodePerCPU(counterMembers, value[:len(value)-1], meta.PerCPUSum); err == nil {
+		t.Error("decodePerCPU() with truncated value error = nil, want error")
+	}
+	if _, err := decodePerCPU(counterMembers, value, "avg"); err == nil {
+		t.Error("decodePerCPU() with unknown mode error = nil, want error")
+	}
+}
+
+func TestJsonMapExporter_PerCPU(t *testing.T) {
+	value, cpus := perCPUCounters(t)
+
+	collector := &jsonCollector{}
+	exporter := &EventExporter{UserExportEventHandler: collector}
+	exporter.InternalImpl = &KeyValueMapProcessor{
+		CheckedKeyTypes:   []CheckedExportedMember{{FieldName: "slot", Type: u32Type}},
+		CheckedValueTypes: counterMembers,
+		MapConfig:         &meta.MapSampleMeta{PerCPU: meta.PerCPUMax},
+	}
+
+	h := NewJsonMapExporter(exporter)
+	h.MapType = ebpf.PerCPUArray
+	if err := h.HandleEvent([]byte{1, 0, 0, 0}, value); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	var out struct {
+		Key   map[string]uint32 `json:"key"`
+		Value struct {
+			Count uint64 `json:"count"`
+		} `json:"value"`
+	}
+	if len(collector.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(collector.events))
+	}
+	if err := json.Unmarshal([]byte(collector.events[0]), &out); err != nil {
+		t.Fatalf("unmarshal %s error = %v", collector.events[0], err)
+	}
+	if out.Key["slot"] != 1 || out.Value.Count != uint64(cpus) {
+		t.Errorf("got %s, want slot 1 and count %d", collector.events[0], cpus)
+	}
+}
@@ -20,6 +20,7 @@ const (
 type DataType = meta.DataType
 type ReceivedEventData = meta.ReceivedEventData
 type EventHandler = meta.EventHandler
+type BatchEventHandler = meta.BatchEventHandler
 type UserContext = meta.UserContext
 
 // 常量别名
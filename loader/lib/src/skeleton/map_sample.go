@@ -0,0 +1,211 @@
+package skeleton
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
+	"github.com/cilium/ebpf"
+)
+
+// DefaultSampleBatchSize 批量读取 map 时每次系统调用读取的最大条目数
+const DefaultSampleBatchSize = 1024
+
+// MapEntry map 采样得到的一个键值
+// per-CPU map 的 Value 为按 CPU 顺序拼接的各 CPU 的值，每个 CPU 占 ValueSize 字节
+type MapEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// MapSampler 读取 map 的快照
+// 内核支持时使用 BatchLookup/BatchLookupAndDelete，否则逐个键遍历
+type MapSampler struct {
+	Map *ebpf.Map
+
+	// BatchSize 每次批量读取的条目数，为 0 时使用 DefaultSampleBatchSize
+	BatchSize int
+
+	// noBatch 内核或 map 类型不支持批量操作，之后直接遍历
+	noBatch bool
+}
+
+// NewMapSampler 创建 map 采样器
+func NewMapSampler(m *ebpf.Map) *MapSampler {
+	return &MapSampler{Map: m}
+}
+
+// Snapshot 读取 map 的所有键值
+// clear 为 true 时读取后清理：hash 类 map 删除条目，array 类 map 将值置零
+// hash 类 map 批量读取时在同一次系统调用中读取并删除；array 类 map 每读取一批立即用一次 BatchUpdate 将这一批置零，
+// 逐个键遍历时每读取一个键立即清理。array 类 map 和逐个键遍历时，读取与清理两次系统调用之间对这些条目的更新会丢失，
+// 适合直方图等允许少量误差的计数 map
+// 批量清理中途失败时同时返回已经清理的条目和错误
+func (s *MapSampler) Snapshot(clear bool) ([]MapEntry, error) {
+	// cilium/ebpf 的 per-CPU 批量读取会丢失系统调用错误，per-CPU map 直接遍历
+	if !s.noBatch && !export.IsPerCPUMap(s.Map.Type()) {
+		entries, err := s.batchSnapshot(clear)
+		if err == nil || len(entries) > 0 || !errors.Is(err, ebpf.ErrNotSupported) {
+			return entries, err
+		}
+		s.noBatch = true
+	}
+
+	return s.iterateSnapshot(clear)
+}
+
+// batchSnapshot 使用批量系统调用读取 map
+func (s *MapSampler) batchSnapshot(clear bool) ([]MapEntry, error) {
+	deletes := clear && !isArrayMap(s.Map.Type())
+	zeroes := clear && isArrayMap(s.Map.Type())
+
+	lookup := s.Map.BatchLookup
+	if deletes {
+		lookup = s.Map.BatchLookupAndDelete
+	}
+
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultSampleBatchSize
+	}
+	if maxEntries := int(s.Map.MaxEntries()); maxEntries > 0 && maxEntries < batchSize {
+		batchSize = maxEntries
+	}
+
+	keys := newRows(s.Map.KeySize(), batchSize)
+	values := newRows(s.Map.ValueSize(), batchSize)
+
+	var entries []MapEntry
+	cursor := new(ebpf.MapBatchCursor)
+	for {
+		n, err := lookup(cursor, keys.Interface(), values.Interface(), nil)
+		cleared := len(entries) > 0
+		for i := 0; i < n; i++ {
+			entries = append(entries, MapEntry{
+				Key:   rowBytes(keys, i),
+				Value: rowBytes(values, i),
+			})
+		}
+
+		// 读到的这一批立即置零，缩短读取与置零之间的窗口
+		if zeroes && n > 0 {
+			if zeroErr := s.batchZero(keys, n); zeroErr != nil {
+				if cleared {
+					// 之前的批次已经置零，和错误一起返回已经清理的部分
+					return entries[:len(entries)-n], zeroErr
+				}
+				return nil, zeroErr
+			}
+		}
+
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			break
+		}
+		if err != nil {
+			if len(entries) > 0 && clear {
+				// 已删除或置零的条目不能重新读取，和错误一起返回已经取到的部分
+				return entries, fmt.Errorf("sample map %s error: %w", s.Map.String(), err)
+			}
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// batchZero 将 array map 中 keys 的前 n 个键对应的值置零
+func (s *MapSampler) batchZero(keys reflect.Value, n int) error {
+	values := newRows(s.Map.ValueSize(), n)
+	if _, err := s.Map.BatchUpdate(keys.Slice(0, n).Interface(), values.Interface(), nil); err != nil {
+		return fmt.Errorf("clear map %s error: %w", s.Map.String(), err)
+	}
+	return nil
+}
+
+// iterateSnapshot 逐个键遍历 map，clear 为 true 时每读到一个键立即删除或置零
+func (s *MapSampler) iterateSnapshot(clear bool) ([]MapEntry, error) {
+	valueSize := int(s.Map.ValueSize())
+	perCPU := export.IsPerCPUMap(s.Map.Type())
+
+	var value any = new([]byte)
+	var zero any = make([]byte, valueSize)
+	if perCPU {
+		cpus, err := ebpf.PossibleCPU()
+		if err != nil {
+			return nil, fmt.Errorf("get possible cpus error: %w", err)
+		}
+		value = newRows(uint32(valueSize), cpus).Interface()
+		zero = newRows(uint32(valueSize), cpus).Interface()
+	}
+
+	// 先取出所有键，避免遍历过程中删除条目打乱迭代顺序
+	var keys [][]byte
+	var key []byte
+	iter := s.Map.Iterate()
+	for iter.Next(&key, value) {
+		keys = append(keys, append([]byte(nil), key...))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate map %s error: %w", s.Map.String(), err)
+	}
+
+	entries := make([]MapEntry, 0, len(keys))
+	for _, key := range keys {
+		if err := s.Map.Lookup(key, value); err != nil {
+			if errors.Is(err, ebpf.ErrKeyNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("lookup map %s error: %w", s.Map.String(), err)
+		}
+
+		if clear {
+			var err error
+			if isArrayMap(s.Map.Type()) {
+				err = s.Map.Put(key, zero)
+			} else {
+				err = s.Map.Delete(key)
+			}
+			if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+				return nil, fmt.Errorf("clear map %s error: %w", s.Map.String(), err)
+			}
+		}
+
+		entry := MapEntry{Key: key}
+		if perCPU {
+			entry.Value = flattenRows(reflect.ValueOf(value))
+		} else {
+			entry.Value = append([]byte(nil), *value.(*[]byte)...)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// newRows 创建 n 个 size 字节数组的切片，批量系统调用要求键和值按条目数传入切片
+func newRows(size uint32, n int) reflect.Value {
+	rowType := reflect.ArrayOf(int(size), reflect.TypeOf(byte(0)))
+	return reflect.MakeSlice(reflect.SliceOf(rowType), n, n)
+}
+
+// rowBytes 复制第 i 行
+func rowBytes(rows reflect.Value, i int) []byte {
+	row := rows.Index(i)
+	return append([]byte(nil), row.Slice(0, row.Len()).Bytes()...)
+}
+
+// flattenRows 按顺序拼接所有行
+func flattenRows(rows reflect.Value) []byte {
+	var out []byte
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		out = append(out, row.Slice(0, row.Len()).Bytes()...)
+	}
+	return out
+}
+
+// isArrayMap array 类 map 的条目不能删除，清理时置零
+func isArrayMap(t ebpf.MapType) bool {
+	return t == ebpf.Array || t == ebpf.PerCPUArray
+}
@@ -0,0 +1,208 @@
+package skeleton
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
+	"github.com/cilium/ebpf"
+)
+
+// newSampleMap 创建键为 u32、值为 u64 的 map，写入 key → key*10
+func newSampleMap(tb testing.TB, mapType ebpf.MapType, entries uint32) *ebpf.Map {
+	tb.Helper()
+
+	m, err := ebpf.NewMap(&ebpf.MapSpec{
+		Type:       mapType,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: entries,
+	})
+	if err != nil {
+		tb.Skipf("create %s map error = %v", mapType, err)
+	}
+	tb.Cleanup(func() { m.Close() })
+
+	cpus, err := ebpf.PossibleCPU()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for key := uint32(0); key < entries; key++ {
+		if export.IsPerCPUMap(mapType) {
+			values := make([]uint64, cpus)
+			for cpu := range values {
+				values[cpu] = uint64(key) * 10
+			}
+			err = m.Put(key, values)
+		} else {
+			err = m.Put(key, uint64(key)*10)
+		}
+		if err != nil {
+			tb.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	return m
+}
+
+// checkEntries 检查快照中每个键的每个 CPU 的值都是 key*10
+func checkEntries(t *testing.T, entries []MapEntry, want int) {
+	t.Helper()
+
+	if len(entries) != want {
+		t.Fatalf("got %d entries, want %d", len(entries), want)
+	}
+	for _, entry := range entries {
+		key := binary.NativeEndian.Uint32(entry.Key)
+		if len(entry.Value) == 0 || len(entry.Value)%8 != 0 {
+			t.Fatalf("key %d value has %d bytes", key, len(entry.Value))
+		}
+		for i := 0; i < len(entry.Value); i += 8 {
+			if got := binary.NativeEndian.Uint64(entry.Value[i:]); got != uint64(key)*10 {
+				t.Errorf("key %d slot %d = %d, want %d", key, i/8, got, key*10)
+			}
+		}
+	}
+}
+
+func TestMapSampler_Snapshot(t *testing.T) {
+	const entries = 100
+
+	for _, mapType := range []ebpf.MapType{ebpf.Hash, ebpf.Array, ebpf.PerCPUHash, ebpf.PerCPUArray} {
+		for _, batch := range []bool{true, false} {
+			name := mapType.String() + "/iterate"
+			if batch {
+				name = mapType.String() + "/batch"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				m := newSampleMap(t, mapType, entries)
+				// 批量读取每次只取一部分，覆盖游标续读
+				sampler := &MapSampler{Map: m, BatchSize: 16, noBatch: !batch}
+
+				snapshot, err := sampler.Snapshot(false)
+				if err != nil {
+					t.Fatalf("Snapshot() error = %v", err)
+				}
+				checkEntries(t, snapshot, entries)
+				if batch && !export.IsPerCPUMap(mapType) && sampler.noBatch {
+					t.Log("batch operations not supported, fell back to iteration")
+				}
+
+				snapshot, err = sampler.Snapshot(true)
+				if err != nil {
+					t.Fatalf("Snapshot(clear) error = %v", err)
+				}
+				checkEntries(t, snapshot, entries)
+
+				// 清理后 hash 类 map 为空，array 类 map 的值为 0
+				snapshot, err = sampler.Snapshot(false)
+				if err != nil {
+					t.Fatalf("Snapshot() after clear error = %v", err)
+				}
+				if !isArrayMap(mapType) {
+					if len(snapshot) != 0 {
+						t.Errorf("got %d entries after clear, want 0", len(snapshot))
+					}
+					return
+				}
+				if len(snapshot) != entries {
+					t.Fatalf("got %d entries after clear, want %d", len(snapshot), entries)
+				}
+				for _, entry := range snapshot {
+					for _, b := range entry.Value {
+						if b != 0 {
+							t.Fatalf("key %v not cleared: %v", entry.Key, entry.Value)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// sampleRecorder 记录采样到的键值
+type sampleRecorder struct {
+	keys int
+}
+
+func (r *sampleRecorder) HandleEvent(key, value []byte) error {
+	r.keys++
+	return nil
+}
+
+func TestSampleMapPoller_Poll(t *testing.T) {
+	m := newSampleMap(t, ebpf.Hash, 10)
+
+	recorder := &sampleRecorder{}
+	p := NewSampleMapPoller(m, recorder, &MapSampleConfig{Interval: 1000, ClearMap: true})
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if recorder.keys != 10 {
+		t.Errorf("sampled %d keys, want 10", recorder.keys)
+	}
+
+	// 清理后再次采样没有数据，Close 不关闭 map
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if recorder.keys != 10 {
+		t.Errorf("sampled %d keys after clear, want 10", recorder.keys)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := m.Put(uint32(1), uint64(1)); err != nil {
+		t.Errorf("Put() after Close error = %v", err)
+	}
+}
+
+// snapshotRecorder 记录每次快照的条目数
+type snapshotRecorder struct {
+	sampleRecorder
+	snapshots []int
+	compares  bool
+}
+
+func (r *snapshotRecorder) ComparesSnapshots() bool {
+	return r.compares
+}
+
+func (r *snapshotRecorder) HandleSnapshot(keys, values [][]byte) error {
+	r.snapshots = append(r.snapshots, len(keys))
+	return nil
+}
+
+func TestSampleMapPoller_PollSnapshot(t *testing.T) {
+	m := newSampleMap(t, ebpf.Array, 10)
+
+	recorder := &snapshotRecorder{compares: true}
+	p := NewSampleMapPoller(m, recorder, &MapSampleConfig{Interval: 1000})
+	for i := 0; i < 2; i++ {
+		if err := p.Poll(); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+	}
+
+	// 快照处理器一次收到整个快照，不再逐条调用 HandleEvent
+	if len(recorder.snapshots) != 2 || recorder.snapshots[0] != 10 || recorder.keys != 0 {
+		t.Errorf("snapshots = %v, single events = %d, want two snapshots of 10", recorder.snapshots, recorder.keys)
+	}
+}
+
+func TestSampleMapPoller_PollSnapshotEvents(t *testing.T) {
+	m := newSampleMap(t, ebpf.Array, 10)
+
+	// 不比较快照的处理器仍然逐条收到条目
+	recorder := &snapshotRecorder{}
+	p := NewSampleMapPoller(m, recorder, &MapSampleConfig{Interval: 1000})
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	if len(recorder.snapshots) != 0 || recorder.keys != 10 {
+		t.Errorf("snapshots = %v, single events = %d, want 10 single events", recorder.snapshots, recorder.keys)
+	}
+}
@@ -0,0 +1,242 @@
+package skeleton
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+)
+
+const (
+	// DefaultPipelineQueueSize 流水线默认的队列容量
+	DefaultPipelineQueueSize = 4096
+
+	// DefaultPipelineWorkers 流水线默认的工作协程数
+	DefaultPipelineWorkers = 1
+)
+
+// KeyFunc 从原始事件中提取排序键，键相同的事件按入队顺序处理
+type KeyFunc func(data []byte) uint64
+
+// PipelineConfig 事件处理流水线配置
+type PipelineConfig struct {
+	// QueueSize 队列容量，为 0 时使用 DefaultPipelineQueueSize
+	QueueSize int
+
+	// Workers 工作协程数，为 0 时使用 DefaultPipelineWorkers
+	Workers int
+
+	// DropPolicy 队列已满时的处理策略，为空时为 meta.DropNewest
+	DropPolicy meta.DropPolicy
+
+	// KeyFunc 不为空时按键将事件分配给固定的工作协程，每个工作协程有独立的队列
+	KeyFunc KeyFunc
+
+	// OnError 工作协程处理事件失败时调用，可以为空
+	OnError func(error)
+}
+
+// Pipeline 轮询器和事件处理器之间的有界队列
+// 轮询器调用 HandleEvent 只负责入队，事件在工作协程中交给下游处理器，慢速的输出不会阻塞内核缓冲区的读取
+type Pipeline struct {
+	processor EventProcessor
+	policy    meta.DropPolicy
+	keyFunc   KeyFunc
+	onError   func(error)
+
+	// queues 未设置 KeyFunc 时所有工作协程共享一个队列
+	queues   []chan []byte
+	capacity int
+	workers  int
+
+	// mu 保护入队和关闭，drop-oldest 需要先出队再入队
+	mu     sync.Mutex
+	closed bool
+	// done 关闭时唤醒阻塞在入队上的事件，blocking 为阻塞入队中的事件数，队列在它们返回后关闭
+	done     chan struct{}
+	blocking sync.WaitGroup
+	wg       sync.WaitGroup
+
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// NewPipeline 创建流水线并启动工作协程
+func NewPipeline(processor EventProcessor, config PipelineConfig) (*Pipeline, error) {
+	if processor == nil {
+		return nil, fmt.Errorf("pipeline processor is nil")
+	}
+
+	switch config.DropPolicy {
+	case "":
+		config.DropPolicy = meta.DropNewest
+	case meta.DropNewest, meta.DropOldest, meta.Block:
+	default:
+		return nil, fmt.Errorf("unknown pipeline drop policy %q", config.DropPolicy)
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultPipelineQueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultPipelineWorkers
+	}
+
+	p := &Pipeline{
+		processor: processor,
+		policy:    config.DropPolicy,
+		keyFunc:   config.KeyFunc,
+		onError:   config.OnError,
+		workers:   config.Workers,
+		done:      make(chan struct{}),
+	}
+
+	if p.keyFunc == nil {
+		p.queues = []chan []byte{make(chan []byte, config.QueueSize)}
+		p.capacity = config.QueueSize
+	} else {
+		// 按工作协程均分队列容量
+		size := config.QueueSize / config.Workers
+		if size == 0 {
+			size = 1
+		}
+		for i := 0; i < config.Workers; i++ {
+			p.queues = append(p.queues, make(chan []byte, size))
+		}
+		p.capacity = size * config.Workers
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		queue := p.queues[i%len(p.queues)]
+		p.wg.Add(1)
+		go p.work(queue)
+	}
+
+	return p, nil
+}
+
+// HandleEvent 将事件入队，队列已满时按策略丢弃或阻塞，丢弃的事件只计入统计，不返回错误
+// 阻塞策略在锁外等待入队，Close 时放弃等待并计入丢弃
+func (p *Pipeline) HandleEvent(data []byte) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.dropped.Add(1)
+		return nil
+	}
+
+	queue := p.queue(data)
+	if p.policy == meta.Block {
+		p.blocking.Add(1)
+		p.mu.Unlock()
+		defer p.blocking.Done()
+
+		select {
+		case queue <- data:
+		case <-p.done:
+			p.dropped.Add(1)
+		}
+		return nil
+	}
+	defer p.mu.Unlock()
+
+	switch p.policy {
+	case meta.DropOldest:
+		for {
+			select {
+			case queue <- data:
+				return nil
+			default:
+			}
+			// 工作协程可能已经取走了事件，此时不计丢弃
+			select {
+			case <-queue:
+				p.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case queue <- data:
+		default:
+			p.dropped.Add(1)
+		}
+	}
+
+	return nil
+}
+
+// queue 返回事件所属的队列
+func (p *Pipeline) queue(data []byte) chan []byte {
+	if len(p.queues) == 1 {
+		return p.queues[0]
+	}
+	return p.queues[p.keyFunc(data)%uint64(len(p.queues))]
+}
+
+// work 从队列中取出事件交给下游处理器，直到队列关闭
+func (p *Pipeline) work(queue chan []byte) {
+	defer p.wg.Done()
+
+	for data := range queue {
+		if err := p.processor.HandleEvent(data); err != nil {
+			p.failed.Add(1)
+			if p.onError != nil {
+				p.onError(err)
+			}
+		}
+	}
+}
+
+// Stats 返回队列深度和丢弃计数
+func (p *Pipeline) Stats() *meta.PipelineStats {
+	depth := 0
+	for _, queue := range p.queues {
+		depth += len(queue)
+	}
+
+	return &meta.PipelineStats{
+		QueueDepth:    depth,
+		QueueCapacity: p.capacity,
+		Workers:       p.workers,
+		Dropped:       p.dropped.Load(),
+		Failed:        p.failed.Load(),
+	}
+}
+
+// Close 停止接收事件，等待工作协程处理完已入队的事件
+func (p *Pipeline) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	// 等待阻塞的入队返回后再关闭队列
+	p.blocking.Wait()
+	for _, queue := range p.queues {
+		close(queue)
+	}
+
+	p.wg.Wait()
+	return nil
+}
+
+// FieldKey 返回以事件中 [offset, offset+size) 字节的哈希为键的 KeyFunc，事件长度不足时键为 0
+func FieldKey(offset, size uint32) KeyFunc {
+	return func(data []byte) uint64 {
+		end := uint64(offset) + uint64(size)
+		if end > uint64(len(data)) {
+			return 0
+		}
+
+		h := fnv.New64a()
+		h.Write(data[offset:end])
+		return h.Sum64()
+	}
+}
@@ -0,0 +1,209 @@
+package skeleton
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+)
+
+// gateProcessor 在 gate 关闭前阻塞处理，记录收到的事件
+type gateProcessor struct {
+	gate chan struct{}
+
+	mu     sync.Mutex
+	events [][]byte
+}
+
+func (p *gateProcessor) HandleEvent(data []byte) error {
+	<-p.gate
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, data)
+	if string(data) == "bad" {
+		return errors.New("bad event")
+	}
+	return nil
+}
+
+func TestPipeline_DropPolicy(t *testing.T) {
+	tests := []struct {
+		policy      meta.DropPolicy
+		wantDropped uint64
+		// 工作协程阻塞在第一条事件上，队列容量为 2
+		want []string
+	}{
+		{policy: meta.DropNewest, wantDropped: 2, want: []string{"0", "1", "2"}},
+		{policy: meta.DropOldest, wantDropped: 2, want: []string{"0", "3", "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			processor := &gateProcessor{gate: make(chan struct{})}
+			p, err := NewPipeline(processor, PipelineConfig{QueueSize: 2, DropPolicy: tt.policy})
+			if err != nil {
+				t.Fatalf("NewPipeline() error = %v", err)
+			}
+
+			p.HandleEvent([]byte("0"))
+			// 等待工作协程取走第一条事件
+			for p.Stats().QueueDepth != 0 {
+				runtime.Gosched()
+			}
+			for _, data := range []string{"1", "2", "3", "4"} {
+				if err := p.HandleEvent([]byte(data)); err != nil {
+					t.Fatalf("HandleEvent() error = %v", err)
+				}
+			}
+
+			stats := p.Stats()
+			if stats.QueueDepth != 2 || stats.QueueCapacity != 2 || stats.Dropped != tt.wantDropped {
+				t.Errorf("Stats() = %+v, want depth 2, capacity 2, dropped %d", stats, tt.wantDropped)
+			}
+
+			close(processor.gate)
+			p.Close()
+
+			var got []string
+			for _, event := range processor.events {
+				got = append(got, string(event))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("handled %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("handled %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline_Block(t *testing.T) {
+	processor := &gateProcessor{gate: make(chan struct{})}
+	p, err := NewPipeline(processor, PipelineConfig{QueueSize: 1, DropPolicy: meta.Block})
+	if err != nil {
+		t.Fatalf("NewPipeline() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, data := range []string{"0", "1", "2", "bad"} {
+			p.HandleEvent([]byte(data))
+		}
+		close(done)
+	}()
+
+	close(processor.gate)
+	<-done
+	p.Close()
+
+	stats := p.Stats()
+	if len(processor.events) != 4 || stats.Dropped != 0 || stats.Failed != 1 {
+		t.Errorf("handled %d events, stats %+v, want 4 events, none dropped and one failed", len(processor.events), stats)
+	}
+
+	// 关闭后的事件计为丢弃
+	p.HandleEvent([]byte("late"))
+	if p.Stats().Dropped != 1 {
+		t.Errorf("Dropped = %d after Close, want 1", p.Stats().Dropped)
+	}
+}
+
+func TestPipeline_CloseWakesBlockedSender(t *testing.T) {
+	processor := &gateProcessor{gate: make(chan struct{})}
+	p, err := NewPipeline(processor, PipelineConfig{QueueSize: 1, DropPolicy: meta.Block})
+	if err != nil {
+		t.Fatalf("NewPipeline() error = %v", err)
+	}
+
+	// 工作协程阻塞在 "0" 上，"1" 占满队列
+	p.HandleEvent([]byte("0"))
+	p.HandleEvent([]byte("1"))
+
+	sent := make(chan struct{})
+	go func() {
+		p.HandleEvent([]byte("2"))
+		close(sent)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	// 阻塞的入队不持有锁，Close 唤醒它并丢弃事件
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("blocked HandleEvent not released by Close")
+	}
+
+	close(processor.gate)
+	<-closed
+
+	if len(processor.events) != 2 || p.Stats().Dropped != 1 {
+		t.Errorf("handled %d events, stats %+v, want 2 events and one dropped", len(processor.events), p.Stats())
+	}
+}
+
+func TestPipeline_OrderKey(t *testing.T) {
+	processor := &gateProcessor{gate: make(chan struct{})}
+	close(processor.gate)
+
+	// 事件首字节为键，第二个字节为序号
+	p, err := NewPipeline(processor, PipelineConfig{
+		QueueSize:  64,
+		Workers:    4,
+		DropPolicy: meta.Block,
+		KeyFunc:    FieldKey(0, 1),
+	})
+	if err != nil {
+		t.Fatalf("NewPipeline() error = %v", err)
+	}
+
+	const keys, perKey = 8, 50
+	for seq := 0; seq < perKey; seq++ {
+		for key := 0; key < keys; key++ {
+			p.HandleEvent([]byte{byte(key), byte(seq)})
+		}
+	}
+	p.Close()
+
+	if len(processor.events) != keys*perKey {
+		t.Fatalf("handled %d events, want %d", len(processor.events), keys*perKey)
+	}
+	next := make([]byte, keys)
+	for _, event := range processor.events {
+		key, seq := event[0], event[1]
+		if seq != next[key] {
+			t.Fatalf("key %d got seq %d, want %d", key, seq, next[key])
+		}
+		next[key]++
+	}
+}
+
+func TestNewPipeline_InvalidPolicy(t *testing.T) {
+	if _, err := NewPipeline(&gateProcessor{}, PipelineConfig{DropPolicy: "drop-all"}); err == nil {
+		t.Error("NewPipeline() error = nil, want error")
+	}
+}
+
+func TestFieldKey(t *testing.T) {
+	key := FieldKey(2, 2)
+	if key([]byte{0, 0, 1, 2}) != key([]byte{9, 9, 1, 2}) {
+		t.Error("FieldKey() differs for equal fields")
+	}
+	if key([]byte{0, 0, 1, 2}) == key([]byte{0, 0, 2, 1}) {
+		t.Error("FieldKey() equal for different fields")
+	}
+	if key([]byte{0, 0, 1}) != 0 {
+		t.Error("FieldKey() of short event should be 0")
+	}
+}
@@ -1,30 +1,53 @@
 package skeleton
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/btf"
 	"github.com/cilium/ebpf/perf"
 	"github.com/cilium/ebpf/ringbuf"
+	"golang.org/x/sys/unix"
 )
 
+// socketPollBufferSize socket 报文读取缓冲区大小，足以容纳一个巨帧
+const socketPollBufferSize = 65536
+
+// DefaultPollBatchSize 一次 Poll 最多交付的事件数
+const DefaultPollBatchSize = 256
+
 // EventProcessor 事件处理器接口
 type EventProcessor interface {
 	HandleEvent(data []byte) error
 }
 
+// BatchEventProcessor 可选的批量事件处理接口，轮询器一次读到的事件通过 HandleEvents 一次交付
+type BatchEventProcessor interface {
+	EventProcessor
+	HandleEvents(batch [][]byte) error
+}
+
 // SampleMapProcessor map 采样处理器接口
 type SampleMapProcessor interface {
 	HandleEvent(key []byte, value []byte) error
 }
 
+// SnapshotMapProcessor 可选的快照处理接口，一次采样读到的所有键值通过 HandleSnapshot 一次交付
+// 用于需要比较前后两次快照的处理器
+type SnapshotMapProcessor interface {
+	SampleMapProcessor
+	HandleSnapshot(keys [][]byte, values [][]byte) error
+	// ComparesSnapshots 是否比较前后两次快照，为 false 时快照仍逐条交给 HandleEvent
+	ComparesSnapshots() bool
+}
+
 // Poller 轮询器接口
 type Poller interface {
 	Poll() error
@@ -32,11 +55,27 @@ type Poller interface {
 	GetPollFunc() PollFunc
 }
 
+// BlockingPoller 在 Poll 中阻塞等待事件的轮询器
+// ProgramPoller.StartPoller 会连续调用 Poll，Stop 时调用 Wakeup 让阻塞中的 Poll 立即返回
+type BlockingPoller interface {
+	Poller
+	Wakeup() error
+}
+
 // RingBufPoller ring buffer 轮询器
 type RingBufPoller struct {
 	Reader    *ringbuf.Reader
 	Processor EventProcessor
-	Timeout   time.Duration
+	// Timeout 一次 Poll 等待第一条事件的最长时间，为 0 时一直等待直到 Wakeup 或 Close
+	Timeout time.Duration
+	// BatchSize 一次 Poll 最多交付的事件数，为 0 时使用 DefaultPollBatchSize
+	BatchSize int
+	// MapName 录制时使用的 map 名称
+	MapName string
+	// Recorder 不为空时录制读到的原始事件
+	Recorder *Recorder
+	// Stats 不为空时统计读到、丢失和丢弃的事件数
+	Stats *EventStats
 }
 
 // PerfEventPoller perf event 轮询器
@@ -44,19 +83,40 @@ type PerfEventPoller struct {
 	Reader    *perf.Reader
 	Processor EventProcessor
 	ErrorFlag atomic.Bool
-	Timeout   time.Duration
+	// Timeout 一次 Poll 等待第一条事件的最长时间，为 0 时一直等待直到 Wakeup 或 Close
+	Timeout time.Duration
+	// BatchSize 一次 Poll 最多交付的事件数，为 0 时使用 DefaultPollBatchSize
+	BatchSize int
+	// MapName 录制时使用的 map 名称
+	MapName string
+	// Recorder 不为空时录制读到的原始事件
+	Recorder *Recorder
+	// Stats 不为空时统计读到、丢失和丢弃的事件数
+	Stats *EventStats
 }
 
 // SampleMapPoller map 采样轮询器
+// 每次 Poll 读取一次 map 快照，采样间隔由调用方按 SampleConfig.Interval 调度
 type SampleMapPoller struct {
 	BpfMap       *ebpf.Map
 	Processor    SampleMapProcessor
 	SampleConfig *MapSampleConfig
+
+	sampler *MapSampler
+}
+
+// SocketPoller socket filter 报文轮询器
+type SocketPoller struct {
+	Fd        int
+	Processor EventProcessor
+	buf       []byte
 }
 
 // MapSampleConfig map 采样配置
 type MapSampleConfig struct {
-	Interval int  `json:"interval"`
+	// Interval 采样间隔（毫秒）
+	Interval int `json:"interval"`
+	// ClearMap 读取后清理 map，hash 类 map 删除条目，array 类 map 将值置零
 	ClearMap bool `json:"clear_map"`
 }
 
@@ -66,6 +126,10 @@ type ProgramPoller struct {
 	stopped  atomic.Bool // 添加状态标记
 	wg       sync.WaitGroup
 
+	// wakeups 停止时唤醒阻塞读取的函数
+	wakeupMu sync.Mutex
+	wakeups  []func() error
+
 	// 错误处理
 	errChan chan error
 
@@ -127,6 +191,68 @@ func (p *ProgramPoller) StartPolling(
 	}()
 }
 
+// StartPoller 开始轮询 poller
+// BlockingPoller 由事件驱动：连续调用 Poll，每次唤醒读完所有可读事件，出错时等待 interval 后重试
+// 其他轮询器按 interval 定时调用 Poll
+func (p *ProgramPoller) StartPoller(
+	name string,
+	poller Poller,
+	errorHandler func(error),
+) {
+	blocking, ok := poller.(BlockingPoller)
+	if !ok {
+		p.StartPolling(name, poller.GetPollFunc(), errorHandler)
+		return
+	}
+
+	if p.stopped.Load() {
+		return
+	}
+
+	p.wakeupMu.Lock()
+	p.wakeups = append(p.wakeups, blocking.Wakeup)
+	p.wakeupMu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		for {
+			select {
+			case <-p.stopChan:
+				return
+			default:
+			}
+
+			err := blocking.Poll()
+			if err == nil {
+				continue
+			}
+
+			// reader 已关闭，不会再有事件
+			if errors.Is(err, os.ErrClosed) {
+				return
+			}
+
+			if errorHandler != nil {
+				errorHandler(err)
+			}
+			select {
+			case p.errChan <- fmt.Errorf("poll %s error: %w", name, err):
+			default:
+				log.Printf("Error polling %s: %v", name, err)
+			}
+
+			// 避免持续出错时空转
+			select {
+			case <-p.stopChan:
+				return
+			case <-time.After(p.interval):
+			}
+		}
+	}()
+}
+
 // Stop 停止轮询
 func (p *ProgramPoller) Stop() {
 	// 1. 使用 CAS 避免重复停止
@@ -134,9 +260,17 @@ func (p *ProgramPoller) Stop() {
 		return
 	}
 
-	// 2. 关闭停止信号通道
+	// 2. 关闭停止信号通道，并唤醒阻塞在读取上的轮询
 	close(p.stopChan)
 
+	p.wakeupMu.Lock()
+	for _, wakeup := range p.wakeups {
+		if err := wakeup(); err != nil && !errors.Is(err, os.ErrClosed) {
+			log.Printf("Warning: wakeup poller: %v", err)
+		}
+	}
+	p.wakeupMu.Unlock()
+
 	// 3. 添加超时控制
 	done := make(chan struct{})
 	go func() {
@@ -178,44 +312,59 @@ func (p *RingBufPoller) GetPollFunc() PollFunc {
 	}
 }
 
-// Poll 实现轮询方法
+// Poll 等待第一条事件，随后不再阻塞，读完 ring buffer 中所有可读的事件并按批交付
 func (p *RingBufPoller) Poll() error {
-	record, err := p.Reader.Read()
-	if err != nil {
-		return fmt.Errorf("read ringbuf error: %w", err)
+	p.Reader.SetDeadline(pollDeadline(p.Timeout))
+
+	batchSize := pollBatchSize(p.BatchSize)
+	batch := make([][]byte, 0, batchSize)
+	var readErr error
+	for len(batch) < batchSize {
+		record, err := p.Reader.Read()
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, ringbuf.ErrFlushed) {
+				readErr = fmt.Errorf("read ringbuf error: %w", err)
+			}
+			break
+		}
+
+		// 录制失败时事件已经从缓冲区取出，仍然交付给处理器
+		if p.Recorder != nil {
+			p.Recorder.recordEvent(p.MapName, CPUUnknown, record.RawSample)
+		}
+
+		batch = append(batch, record.RawSample)
+		if len(batch) == 1 {
+			// 已被唤醒，后续读取只取走已有的事件
+			p.Reader.SetDeadline(time.Now())
+		}
 	}
 
-	err = saveRawBytes("test.bin", record.RawSample)
+	dropped, err := handleBatch(p.Processor, batch)
+	p.Stats.addBatch(len(batch), nil, dropped)
 	if err != nil {
 		return err
 	}
+	return readErr
+}
 
-	if err := p.Processor.HandleEvent(record.RawSample); err != nil {
-		return fmt.Errorf("handle event error: %w", err)
-	}
-
-	return nil
+// Wakeup 让阻塞中的 Poll 读完已有事件后返回
+func (p *RingBufPoller) Wakeup() error {
+	return p.Reader.Flush()
 }
 
 func (p *RingBufPoller) Close() error {
 	return p.Reader.Close()
 }
 
-// 直接将字节数组保存为文件的辅助函数
-func saveRawBytes(filename string, data []byte) error {
-	// 确保目录存在
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("create directory failed: %w", err)
-	}
-
-	// 直接写入原始字节
-	return os.WriteFile(filename, data, 0644)
+// NewPerfEventPoller 使用默认的 perf 缓冲区配置创建 perf event 轮询器
+func NewPerfEventPoller(bpfMap *ebpf.Map, processor EventProcessor, timeoutMs uint64) (*PerfEventPoller, error) {
+	return NewPerfEventPollerWithOptions(bpfMap, processor, timeoutMs, meta.PerfBufferOptions{})
 }
 
-// NewPerfEventPoller 创建 perf event 轮询器
-func NewPerfEventPoller(bpfMap *ebpf.Map, processor EventProcessor, timeoutMs uint64) (*PerfEventPoller, error) {
-	reader, err := perf.NewReader(bpfMap, os.Getpagesize())
+// NewPerfEventPollerWithOptions 按 perf 缓冲区配置创建 perf event 轮询器
+func NewPerfEventPollerWithOptions(bpfMap *ebpf.Map, processor EventProcessor, timeoutMs uint64, opts meta.PerfBufferOptions) (*PerfEventPoller, error) {
+	reader, err := NewPerfReader(bpfMap, opts)
 	if err != nil {
 		return nil, fmt.Errorf("create perf reader error: %w", err)
 	}
@@ -227,19 +376,57 @@ func NewPerfEventPoller(bpfMap *ebpf.Map, processor EventProcessor, timeoutMs ui
 	}, nil
 }
 
-// Poll 实现轮询方法
+// Poll 等待第一条事件，随后不再阻塞，读完所有 CPU 缓冲区中可读的事件并按批交付
 func (p *PerfEventPoller) Poll() error {
-	record, err := p.Reader.Read()
-	if err != nil {
-		return fmt.Errorf("read perf event error: %w", err)
+	p.Reader.SetDeadline(pollDeadline(p.Timeout))
+
+	batchSize := pollBatchSize(p.BatchSize)
+	batch := make([][]byte, 0, batchSize)
+	cpus := make([]int, 0, batchSize)
+	var readErr error
+	for len(batch) < batchSize {
+		record, err := p.Reader.Read()
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, perf.ErrFlushed) {
+				readErr = fmt.Errorf("read perf event error: %w", err)
+			}
+			break
+		}
+
+		// 丢失事件的通知没有数据
+		if record.LostSamples > 0 {
+			p.Stats.addLost(record.CPU, record.LostSamples)
+			continue
+		}
+		if len(record.RawSample) == 0 {
+			continue
+		}
+
+		// 录制失败时事件已经从缓冲区取出，仍然交付给处理器
+		if p.Recorder != nil {
+			p.Recorder.recordEvent(p.MapName, record.CPU, record.RawSample)
+		}
+
+		batch = append(batch, record.RawSample)
+		cpus = append(cpus, record.CPU)
+		if len(batch) == 1 {
+			// 已被唤醒，后续读取只取走已有的事件
+			p.Reader.SetDeadline(time.Now())
+		}
 	}
 
-	if err := p.Processor.HandleEvent(record.RawSample); err != nil {
+	dropped, err := handleBatch(p.Processor, batch)
+	p.Stats.addBatch(len(batch), cpus, dropped)
+	if err != nil {
 		p.ErrorFlag.Store(true)
-		return fmt.Errorf("handle event error: %w", err)
+		return err
 	}
+	return readErr
+}
 
-	return nil
+// Wakeup 让阻塞中的 Poll 读完已有事件后返回
+func (p *PerfEventPoller) Wakeup() error {
+	return p.Reader.Flush()
 }
 
 func (p *PerfEventPoller) GetPollFunc() PollFunc {
@@ -252,29 +439,155 @@ func (p *PerfEventPoller) Close() error {
 	return p.Reader.Close()
 }
 
+// NewPerfReader 按 perf 缓冲区配置创建 perf reader，每个 CPU 缓冲区大小为 Pages 个页
+func NewPerfReader(bpfMap *ebpf.Map, opts meta.PerfBufferOptions) (*perf.Reader, error) {
+	pages := opts.Pages
+	if pages <= 0 {
+		pages = meta.DefaultPerfBufferPages
+	}
+
+	return perf.NewReaderWithOptions(bpfMap, pages*os.Getpagesize(), perf.ReaderOptions{
+		Watermark:    opts.Watermark,
+		WakeupEvents: opts.WakeupEvents,
+	})
+}
+
+// pollDeadline 返回阻塞读取的截止时间，timeout 为 0 时不设置截止时间
+func pollDeadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// pollBatchSize 返回一次 Poll 最多交付的事件数
+func pollBatchSize(size int) int {
+	if size <= 0 {
+		return DefaultPollBatchSize
+	}
+	return size
+}
+
+// handleBatch 交付一批事件，处理器实现 BatchEventProcessor 时一次交付，返回处理失败的事件下标
+// 逐条交付时单条事件出错不影响其余事件，返回第一个错误；批量交付失败时整批计为失败
+func handleBatch(processor EventProcessor, batch [][]byte) ([]int, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	if bp, ok := processor.(BatchEventProcessor); ok {
+		if err := bp.HandleEvents(batch); err != nil {
+			dropped := make([]int, len(batch))
+			for i := range dropped {
+				dropped[i] = i
+			}
+			return dropped, fmt.Errorf("handle events error: %w", err)
+		}
+		return nil, nil
+	}
+
+	var (
+		firstErr error
+		dropped  []int
+	)
+	for i, data := range batch {
+		if err := processor.HandleEvent(data); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			dropped = append(dropped, i)
+		}
+	}
+
+	if firstErr != nil {
+		return dropped, fmt.Errorf("handle event error (%d of %d failed): %w", len(dropped), len(batch), firstErr)
+	}
+	return nil, nil
+}
+
+// NewSocketPoller 创建 socket filter 报文轮询器
+func NewSocketPoller(fd int, processor EventProcessor) *SocketPoller {
+	return &SocketPoller{
+		Fd:        fd,
+		Processor: processor,
+		buf:       make([]byte, socketPollBufferSize),
+	}
+}
+
+// Poll 以非阻塞方式读取 socket 上当前所有可读的报文
+func (p *SocketPoller) Poll() error {
+	for {
+		n, _, err := unix.Recvfrom(p.Fd, p.buf, unix.MSG_DONTWAIT)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				return nil
+			}
+			return fmt.Errorf("read socket error: %w", err)
+		}
+
+		// 拷贝一份报文，避免处理器持有的数据被下一次读取覆盖
+		packet := make([]byte, n)
+		copy(packet, p.buf[:n])
+		if err := p.Processor.HandleEvent(packet); err != nil {
+			return fmt.Errorf("handle event error: %w", err)
+		}
+	}
+}
+
+func (p *SocketPoller) GetPollFunc() PollFunc {
+	return func() error {
+		return p.Poll()
+	}
+}
+
+// Close socket 由附加点管理，这里无需关闭
+func (p *SocketPoller) Close() error {
+	return nil
+}
+
 // NewSampleMapPoller 创建 map 采样轮询器
 func NewSampleMapPoller(bpfMap *ebpf.Map, processor SampleMapProcessor, config *MapSampleConfig) *SampleMapPoller {
 	return &SampleMapPoller{
 		BpfMap:       bpfMap,
 		Processor:    processor,
 		SampleConfig: config,
+		sampler:      NewMapSampler(bpfMap),
 	}
 }
 
-// Poll 实现轮询方法
+// Poll 读取 map 快照并逐条交给处理器
 func (p *SampleMapPoller) Poll() error {
-	var key []byte
-	var value []byte
+	if p.sampler == nil {
+		p.sampler = NewMapSampler(p.BpfMap)
+	}
 
-	iter := p.BpfMap.Iterate()
-	for iter.Next(&key, &value) {
-		if err := p.Processor.HandleEvent(key, value); err != nil {
+	clear := p.SampleConfig != nil && p.SampleConfig.ClearMap
+	entries, sampleErr := p.sampler.Snapshot(clear)
+	if processor, ok := p.Processor.(SnapshotMapProcessor); ok && processor.ComparesSnapshots() {
+		// 读取失败时快照不完整，不交给需要比较快照的处理器
+		if sampleErr != nil {
+			return sampleErr
+		}
+
+		keys := make([][]byte, len(entries))
+		values := make([][]byte, len(entries))
+		for i, entry := range entries {
+			keys[i], values[i] = entry.Key, entry.Value
+		}
+		if err := processor.HandleSnapshot(keys, values); err != nil {
+			return fmt.Errorf("handle snapshot error: %w", err)
+		}
+		return nil
+	}
+
+	// 清理中途失败时已经删除或置零的条目无法重新读取，先交给处理器再返回错误
+	for _, entry := range entries {
+		if err := p.Processor.HandleEvent(entry.Key, entry.Value); err != nil {
 			return fmt.Errorf("handle event error: %w", err)
 		}
 	}
 
-	time.Sleep(time.Duration(p.SampleConfig.Interval) * time.Millisecond)
-	return nil
+	return sampleErr
 }
 
 func (p *SampleMapPoller) GetPollFunc() PollFunc {
@@ -283,13 +596,8 @@ func (p *SampleMapPoller) GetPollFunc() PollFunc {
 	}
 }
 
-// Close 清理资源
+// Close 清理资源，map 由 collection 持有，这里不关闭
 func (p *SampleMapPoller) Close() error {
-	if p.SampleConfig.ClearMap && p.BpfMap != nil {
-		if err := p.BpfMap.Close(); err != nil {
-			return fmt.Errorf("delete map error: %w", err)
-		}
-	}
 	return nil
 }
 
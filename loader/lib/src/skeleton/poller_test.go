@@ -1,8 +1,11 @@
 package skeleton
 
 import (
+	"errors"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"reflect"
 	"sync/atomic"
 	"syscall"
 	"testing"
@@ -11,9 +14,11 @@ import (
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/skeleton/export"
 	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
 	"github.com/cilium/ebpf/perf"
 	"github.com/cilium/ebpf/ringbuf"
 	"go.uber.org/zap/zaptest"
+	"golang.org/x/sys/unix"
 )
 
 func TestRingBufferPoller_Poll(t *testing.T) {
@@ -125,3 +130,453 @@ func TestRingBufferPoller_Poll(t *testing.T) {
 		})
 	}
 }
+
+// recordProcessor 记录收到的事件，用于测试
+type recordProcessor struct {
+	events [][]byte
+}
+
+func (r *recordProcessor) HandleEvent(data []byte) error {
+	r.events = append(r.events, data)
+	return nil
+}
+
+func TestSocketPoller_Poll(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		t.Fatalf("Socketpair() error = %v", err)
+	}
+	defer unix.Close(fds[0])
+	defer unix.Close(fds[1])
+
+	packets := []string{"packet-1", "packet-2", "packet-3"}
+	for _, pkt := range packets {
+		if _, err := unix.Write(fds[1], []byte(pkt)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	processor := &recordProcessor{}
+	p := NewSocketPoller(fds[0], processor)
+
+	// 一次 Poll 应读取所有可读的报文，且在没有数据时不阻塞
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	if len(processor.events) != len(packets) {
+		t.Fatalf("Poll() got %d events, want %d", len(processor.events), len(packets))
+	}
+
+	for i, pkt := range packets {
+		if string(processor.events[i]) != pkt {
+			t.Errorf("Poll() event %d = %q, want %q", i, processor.events[i], pkt)
+		}
+	}
+}
+
+// batchRecorder 记录每次批量交付的事件
+type batchRecorder struct {
+	batches [][][]byte
+}
+
+func (r *batchRecorder) HandleEvent(data []byte) error {
+	r.batches = append(r.batches, [][]byte{data})
+	return nil
+}
+
+func (r *batchRecorder) HandleEvents(batch [][]byte) error {
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+// failingProcessor 处理指定事件时返回错误
+type failingProcessor struct {
+	handled int
+	fail    string
+}
+
+func (f *failingProcessor) HandleEvent(data []byte) error {
+	f.handled++
+	if string(data) == f.fail {
+		return errors.New("bad event")
+	}
+	return nil
+}
+
+func TestHandleBatch(t *testing.T) {
+	batch := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+
+	batcher := &batchRecorder{}
+	if _, err := handleBatch(batcher, batch); err != nil {
+		t.Fatalf("handleBatch() error = %v", err)
+	}
+	if len(batcher.batches) != 1 || len(batcher.batches[0]) != 3 {
+		t.Errorf("BatchEventProcessor got %d batches, want one batch of 3", len(batcher.batches))
+	}
+
+	// 逐条交付时单条事件出错不影响其余事件
+	failing := &failingProcessor{fail: "b"}
+	dropped, err := handleBatch(failing, batch)
+	if err == nil {
+		t.Error("handleBatch() error = nil, want error")
+	}
+	if !reflect.DeepEqual(dropped, []int{1}) {
+		t.Errorf("handleBatch() dropped = %v, want [1]", dropped)
+	}
+	if failing.handled != 3 {
+		t.Errorf("handled %d events, want 3", failing.handled)
+	}
+
+	if _, err := handleBatch(failing, nil); err != nil {
+		t.Errorf("handleBatch() empty batch error = %v", err)
+	}
+}
+
+// blockingPoller Poll 一直阻塞直到 Wakeup
+type blockingPoller struct {
+	polls  atomic.Int32
+	wakeup chan struct{}
+}
+
+func (p *blockingPoller) Poll() error {
+	p.polls.Add(1)
+	<-p.wakeup
+	return nil
+}
+
+func (p *blockingPoller) Wakeup() error {
+	close(p.wakeup)
+	return nil
+}
+
+func (p *blockingPoller) Close() error { return nil }
+
+func (p *blockingPoller) GetPollFunc() PollFunc { return p.Poll }
+
+func TestProgramPoller_StopWakesBlockingPoller(t *testing.T) {
+	poller := &blockingPoller{wakeup: make(chan struct{})}
+	pp := NewProgramPoller(time.Hour)
+	pp.StartPoller("test", poller, nil)
+
+	// 事件驱动的轮询不等待 ticker
+	deadline := time.Now().Add(time.Second)
+	for poller.polls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Poll() not called")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	pp.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop() took %v, want prompt return", elapsed)
+	}
+}
+
+// newEventProducer 创建 map 和向其写入 8 字节事件的 XDP 程序，通过 BPF_PROG_TEST_RUN 产生事件
+func newEventProducer(tb testing.TB, mapType ebpf.MapType) (*ebpf.Map, *ebpf.Program) {
+	tb.Helper()
+
+	spec := &ebpf.MapSpec{Type: mapType}
+	if mapType == ebpf.RingBuf {
+		spec.MaxEntries = 1 << 20
+	}
+
+	m, err := ebpf.NewMap(spec)
+	if err != nil {
+		tb.Skipf("create %s map: %v", mapType, err)
+	}
+	tb.Cleanup(func() { m.Close() })
+
+	insns := asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+		asm.StoreImm(asm.RFP, -8, 0x42, asm.DWord),
+	}
+	if mapType == ebpf.RingBuf {
+		insns = append(insns,
+			asm.LoadMapPtr(asm.R1, m.FD()),
+			asm.Mov.Reg(asm.R2, asm.RFP),
+			asm.Add.Imm(asm.R2, -8),
+			asm.Mov.Imm(asm.R3, 8),
+			asm.Mov.Imm(asm.R4, 0),
+			asm.FnRingbufOutput.Call(),
+		)
+	} else {
+		insns = append(insns,
+			asm.Mov.Reg(asm.R1, asm.R6),
+			asm.LoadMapPtr(asm.R2, m.FD()),
+			// BPF_F_CURRENT_CPU
+			asm.LoadImm(asm.R3, 0xffffffff, asm.DWord),
+			asm.Mov.Reg(asm.R4, asm.RFP),
+			asm.Add.Imm(asm.R4, -8),
+			asm.Mov.Imm(asm.R5, 8),
+			asm.FnPerfEventOutput.Call(),
+		)
+	}
+	insns = append(insns,
+		// XDP_PASS
+		asm.Mov.Imm(asm.R0, 2),
+		asm.Return(),
+	)
+
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.XDP,
+		License:      "GPL",
+		Instructions: insns,
+	})
+	if err != nil {
+		tb.Skipf("load producer program: %v", err)
+	}
+	tb.Cleanup(func() { prog.Close() })
+
+	return m, prog
+}
+
+// produceEvents 产生 n 条事件
+// 每条事件单独运行一次程序，XDP 的 Repeat 在部分内核上每次迭代都有较大开销
+func produceEvents(tb testing.TB, prog *ebpf.Program, n int) {
+	tb.Helper()
+
+	data := make([]byte, 64)
+	for i := 0; i < n; i++ {
+		if _, err := prog.Run(&ebpf.RunOptions{Data: data}); err != nil {
+			tb.Skipf("run producer program: %v", err)
+		}
+	}
+}
+
+// newEventPoller 为 map 创建轮询器
+func newEventPoller(tb testing.TB, m *ebpf.Map, processor EventProcessor, batchSize int) BlockingPoller {
+	tb.Helper()
+
+	if m.Type() == ebpf.RingBuf {
+		p, err := NewRingBufPoller(m, processor, 100)
+		if err != nil {
+			tb.Fatalf("NewRingBufPoller() error = %v", err)
+		}
+		p.BatchSize = batchSize
+		return p
+	}
+
+	p, err := NewPerfEventPoller(m, processor, 100)
+	if err != nil {
+		tb.Fatalf("NewPerfEventPoller() error = %v", err)
+	}
+	p.BatchSize = batchSize
+	return p
+}
+
+func TestEventPoller_PollDrainsBatch(t *testing.T) {
+	for _, mapType := range []ebpf.MapType{ebpf.RingBuf, ebpf.PerfEventArray} {
+		t.Run(mapType.String(), func(t *testing.T) {
+			m, prog := newEventProducer(t, mapType)
+			processor := &batchRecorder{}
+			p := newEventPoller(t, m, processor, 0)
+			defer p.Close()
+
+			produceEvents(t, prog, 50)
+
+			// 一次唤醒读完所有事件，作为一批交付
+			if err := p.Poll(); err != nil {
+				t.Fatalf("Poll() error = %v", err)
+			}
+			if len(processor.batches) != 1 || len(processor.batches[0]) != 50 {
+				t.Fatalf("Poll() got batches %d, want one batch of 50", len(processor.batches))
+			}
+
+			// 没有事件时等待 Timeout 后返回
+			start := time.Now()
+			if err := p.Poll(); err != nil {
+				t.Fatalf("Poll() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Poll() without events took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestEventPoller_PollRecordFailure(t *testing.T) {
+	for _, mapType := range []ebpf.MapType{ebpf.RingBuf, ebpf.PerfEventArray} {
+		t.Run(mapType.String(), func(t *testing.T) {
+			m, prog := newEventProducer(t, mapType)
+			processor := &batchRecorder{}
+			p := newEventPoller(t, m, processor, 0)
+			defer p.Close()
+
+			// 已关闭的录制文件写入失败
+			recorder, err := NewRecorder(filepath.Join(t.TempDir(), "events.rec"))
+			if err != nil {
+				t.Fatalf("NewRecorder() error = %v", err)
+			}
+			recorder.Close()
+			switch p := p.(type) {
+			case *RingBufPoller:
+				p.Recorder = recorder
+			case *PerfEventPoller:
+				p.Recorder = recorder
+			}
+
+			produceEvents(t, prog, 10)
+
+			// 录制失败不能丢弃已经从内核取出的事件
+			if err := p.Poll(); err != nil {
+				t.Fatalf("Poll() error = %v", err)
+			}
+			if len(processor.batches) != 1 || len(processor.batches[0]) != 10 {
+				t.Fatalf("Poll() got batches %v, want one batch of 10", processor.batches)
+			}
+			if got := recorder.Failures(); got != 10 {
+				t.Errorf("Failures() = %d, want 10", got)
+			}
+		})
+	}
+}
+
+// countProcessor 统计收到的事件数，收到 target 条时通知 done
+type countProcessor struct {
+	n      atomic.Int64
+	target atomic.Int64
+	done   chan struct{}
+}
+
+func (c *countProcessor) HandleEvent(data []byte) error {
+	if c.n.Add(1) == c.target.Load() {
+		c.done <- struct{}{}
+	}
+	return nil
+}
+
+// legacyPollFunc 旧的轮询方式：每个 tick 阻塞读取一条事件后交给处理器
+func legacyPollFunc(p BlockingPoller, processor EventProcessor) PollFunc {
+	return func() error {
+		var (
+			sample []byte
+			err    error
+		)
+		switch p := p.(type) {
+		case *RingBufPoller:
+			var record ringbuf.Record
+			record, err = p.Reader.Read()
+			sample = record.RawSample
+		case *PerfEventPoller:
+			var record perf.Record
+			record, err = p.Reader.Read()
+			sample = record.RawSample
+		}
+		if errors.Is(err, os.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return processor.HandleEvent(sample)
+	}
+}
+
+// benchmarkPoller 测量从产生事件到处理完成的吞吐
+// legacy 为旧的轮询方式：ticker 每个 tick 阻塞读取一条事件
+func benchmarkPoller(b *testing.B, mapType ebpf.MapType, legacy bool) {
+	const chunk = 64
+
+	m, prog := newEventProducer(b, mapType)
+	processor := &countProcessor{done: make(chan struct{}, 1)}
+
+	pp := NewProgramPoller(time.Millisecond)
+	defer pp.Stop()
+
+	// 先关闭 reader，唤醒阻塞在读取上的旧轮询
+	p := newEventPoller(b, m, processor, 0)
+	defer p.Close()
+
+	if legacy {
+		pp.StartPolling("bench", legacyPollFunc(p, processor), nil)
+	} else {
+		pp.StartPoller("bench", p, nil)
+	}
+
+	b.ResetTimer()
+	for sent := 0; sent < b.N; {
+		n := min(chunk, b.N-sent)
+		sent += n
+		processor.target.Store(int64(sent))
+		produceEvents(b, prog, n)
+
+		// 等待处理完再继续产生，避免缓冲区溢出丢失事件
+		select {
+		case <-processor.done:
+		case <-time.After(10 * time.Second):
+			b.Fatalf("processed %d of %d events", processor.n.Load(), sent)
+		}
+	}
+}
+
+func BenchmarkRingBufPoller_Legacy(b *testing.B) {
+	benchmarkPoller(b, ebpf.RingBuf, true)
+}
+
+func BenchmarkRingBufPoller_EventDriven(b *testing.B) {
+	benchmarkPoller(b, ebpf.RingBuf, false)
+}
+
+func BenchmarkPerfEventPoller_Legacy(b *testing.B) {
+	benchmarkPoller(b, ebpf.PerfEventArray, true)
+}
+
+func BenchmarkPerfEventPoller_EventDriven(b *testing.B) {
+	benchmarkPoller(b, ebpf.PerfEventArray, false)
+}
+
+// rejectProcessor 拒绝所有事件
+type rejectProcessor struct{}
+
+func (rejectProcessor) HandleEvent(data []byte) error {
+	return errors.New("rejected")
+}
+
+func TestPerfEventPoller_Stats(t *testing.T) {
+	m, prog := newEventProducer(t, ebpf.PerfEventArray)
+
+	p, err := NewPerfEventPollerWithOptions(m, rejectProcessor{}, 10, meta.PerfBufferOptions{Pages: 1})
+	if err != nil {
+		t.Fatalf("NewPerfEventPollerWithOptions() error = %v", err)
+	}
+	defer p.Close()
+	p.Stats = NewEventStats("events", ebpf.PerfEventArray)
+
+	// 单页缓冲区无法容纳所有事件，多出的事件由内核丢弃
+	const total = 1000
+	produceEvents(t, prog, total)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		// 每条事件都处理失败，计为丢弃
+		_ = p.Poll()
+		if stats := p.Stats.Snapshot(); stats.Received+stats.Lost >= total {
+			break
+		}
+		// 内核在缓冲区腾出空间后的下一次输出时才写入丢失通知
+		produceEvents(t, prog, 1)
+	}
+
+	stats := p.Stats.Snapshot()
+	if stats.Received == 0 || stats.Lost == 0 {
+		t.Errorf("Received = %d, Lost = %d, want both non-zero with a one page buffer", stats.Received, stats.Lost)
+	}
+	if stats.Received+stats.Lost < total {
+		t.Errorf("Received %d + Lost %d, want at least %d", stats.Received, stats.Lost, total)
+	}
+	if stats.Dropped != stats.Received {
+		t.Errorf("Dropped = %d, want %d", stats.Dropped, stats.Received)
+	}
+
+	var cpuLost uint64
+	for _, cpu := range stats.CPUs {
+		cpuLost += cpu.Lost
+	}
+	if cpuLost != stats.Lost {
+		t.Errorf("per CPU lost %d, want %d", cpuLost, stats.Lost)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
 	"github.com/cilium/ebpf/link"
 )
 
@@ -93,11 +94,52 @@ func (p *PreLoadBpfSkeleton) LoadPinProgram(progMeta *meta.ProgMeta) (*ebpf.Prog
 	return pinnedProg, nil
 }
 
+// CheckAttachTargets 在加载前检查程序的附加点是否可用
+// 可选程序检查失败时只记录状态，加载时会被跳过；必需程序检查失败时返回错误
+func (p *PreLoadBpfSkeleton) CheckAttachTargets(progAttachStatus map[string]meta.ProgAttachStatus) error {
+	for _, progMeta := range p.Meta.BpfSkel.Progs {
+		progSpec := p.Spec.Programs[progMeta.Name]
+		if progSpec == nil || !progMeta.Link {
+			continue
+		}
+
+		if progSpec.Type != ebpf.LSM {
+			continue
+		}
+
+		if err := progMeta.CheckLsm(p.KernelBtf); err != nil {
+			err = fmt.Errorf("check lsm program %s error: %w", progMeta.Name, err)
+			progAttachStatus[progMeta.Name] = genAttachErr(meta.ProgAttachStatus{
+				ProgName: progMeta.Name,
+				Optional: progMeta.Properties.IsOptional(),
+			}, err)
+			if progMeta.Properties.IsOptional() {
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 // LoadAndAttach 加载并附加 eBPF 程序
 func (p *PreLoadBpfSkeleton) LoadAndAttach() (*BpfSkeleton, map[string]meta.ProgAttachStatus, error) {
+	return p.loadAndAttach(nil, nil)
+}
+
+// loadAndAttach 加载并附加 eBPF 程序
+// sharedMaps 中的 map 代替 spec 中的同名 map；previous 不为空时表示升级，优先在 previous 的链接上原地替换程序
+func (p *PreLoadBpfSkeleton) loadAndAttach(sharedMaps map[string]*ebpf.Map, previous *BpfSkeleton) (*BpfSkeleton, map[string]meta.ProgAttachStatus, error) {
 	progAttachStatus := make(map[string]meta.ProgAttachStatus)
 
+	// 加载前检查附加点，避免加载后才得到难以理解的错误
+	if err := p.CheckAttachTargets(progAttachStatus); err != nil {
+		return nil, progAttachStatus, err
+	}
+
 	collectionOptions := ebpf.CollectionOptions{}
+	collectionOptions.Programs.KernelTypes = p.CoreBtf
 	mergedMaps, err := p.MergeMapProperties()
 	if err != nil {
 		return nil, progAttachStatus, fmt.Errorf("merge map properties error: %w", err)
@@ -107,86 +149,259 @@ func (p *PreLoadBpfSkeleton) LoadAndAttach() (*BpfSkeleton, map[string]meta.Prog
 		return nil, progAttachStatus, fmt.Errorf("check pin path error: %w", err)
 	}
 
+	// pin 的 map 已经是同一个内核对象，优先使用
+	for name, m := range sharedMaps {
+		if _, ok := mergedMaps[name]; !ok {
+			mergedMaps[name] = m
+		}
+	}
+
 	collectionOptions.MapReplacements = mergedMaps
 
+	// 以其他 BPF 程序为目标的 tracing 程序需要修改 spec，使用副本避免影响原始 spec
+	spec := p.Spec.Copy()
+
+	// 写入配置的全局变量值
+	if err := meta.ApplyVariables(spec, p.Meta.BpfSkel.Variables()); err != nil {
+		return nil, progAttachStatus, fmt.Errorf("apply variables error: %w", err)
+	}
+
+	// 内核不支持 multi link 时以普通 kprobe 加载，附加时逐个函数回退
+	meta.PrepareMultiProbes(spec)
+
+	// 跳过检查失败的可选程序
+	for name, status := range progAttachStatus {
+		if status.Status == meta.TaskStatusFailed {
+			delete(spec.Programs, name)
+		}
+	}
+
+	deferred, targets, err := p.prepareTracingTargets(spec)
+	if err != nil {
+		return nil, progAttachStatus, fmt.Errorf("prepare tracing targets error: %w", err)
+	}
+	defer closePrograms(targets)
+
+	deferredSpecs := make(map[string]*ebpf.ProgramSpec, len(deferred))
+	for name := range deferred {
+		deferredSpecs[name] = spec.Programs[name]
+		delete(spec.Programs, name)
+	}
+
 	// 直接加载 BPF 对象集合，cilium/ebpf 会自动处理 .rodata 和 .bss
-	coll, err := ebpf.NewCollectionWithOptions(p.Spec, collectionOptions)
+	coll, err := ebpf.NewCollectionWithOptions(spec, collectionOptions)
 	if err != nil {
-		return nil, progAttachStatus, fmt.Errorf("load collection error: %w", err)
+		return nil, progAttachStatus, fmt.Errorf("load collection error: %w", meta.NewLoadError(spec, p.coreKernel(), err))
+	}
+
+	if len(deferredSpecs) > 0 {
+		if err := loadDeferredPrograms(coll, spec, deferredSpecs, deferred, p.coreKernel()); err != nil {
+			coll.Close()
+			return nil, progAttachStatus, err
+		}
 	}
 
 	// 附加程序
+	links, progLinks, pinned, err := p.attachPrograms(coll, previous, progAttachStatus)
+	if err != nil {
+		coll.Close()
+		return nil, progAttachStatus, err
+	}
+
+	return &BpfSkeleton{
+		Meta:        p.Meta,
+		ConfigData:  p.ConfigData,
+		Links:       links,
+		ProgLinks:   progLinks,
+		PinnedLinks: pinned,
+		Collection:  coll,
+		Btf:         p.Btf,
+	}, progAttachStatus, nil
+}
+
+// coreKernel 返回 CO-RE 重定位使用的内核 BTF
+func (p *PreLoadBpfSkeleton) coreKernel() *btf.Spec {
+	if p.CoreBtf != nil {
+		return p.CoreBtf
+	}
+	return p.KernelBtf
+}
+
+// attachPrograms 附加所有需要 link 的程序
+// 可选程序附加失败时记录状态并继续；必需程序附加失败时撤销本次附加：新建的链接被关闭，
+// 升级时已原地替换的链接恢复为旧程序
+// 返回的 pinned 为由本加载器 pin 的链接
+func (p *PreLoadBpfSkeleton) attachPrograms(
+	coll *ebpf.Collection,
+	previous *BpfSkeleton,
+	progAttachStatus map[string]meta.ProgAttachStatus,
+) ([]link.Link, map[string]link.Link, map[link.Link]struct{}, error) {
 	var links []link.Link
+	progLinks := make(map[string]link.Link)
+	updated := make(map[string]link.Link)
+	pinned := make(map[link.Link]struct{})
 	for _, progMeta := range p.Meta.BpfSkel.Progs {
-		status := meta.ProgAttachStatus{
-			ProgName: progMeta.Name,
-			Status:   meta.TaskStatusPending,
+		// 加载前检查失败的可选程序未被加载
+		if status, ok := progAttachStatus[progMeta.Name]; ok && status.Status == meta.TaskStatusFailed {
+			continue
 		}
 
-		prog := coll.Programs[progMeta.Name]
-		if prog == nil {
-			err := fmt.Errorf("program %s not found", progMeta.Name)
-			progAttachStatus[progMeta.Name] = genAttachErr(status, err)
-			return nil, progAttachStatus, err
+		// 被禁用的程序只加载不附加，之后可以通过 EnableProgram 重新附加
+		if _, ok := p.Disabled[progMeta.Name]; ok && progMeta.Link {
+			progAttachStatus[progMeta.Name] = meta.ProgAttachStatus{
+				ProgName: progMeta.Name,
+				Status:   meta.TaskStatusDisabled,
+				Optional: progMeta.Properties.IsOptional(),
+			}
+			continue
 		}
 
-		progSpec := p.Spec.Programs[progMeta.Name]
-		if progSpec == nil {
-			err := fmt.Errorf("program %s not found", progMeta.Name)
+		link, reused, newPin, err := p.attachProgram(progMeta, coll, previous, progAttachStatus)
+		if err != nil && progMeta.Properties.IsOptional() {
+			// previous 中的旧链接保持原样，由 CloseReplaced 关闭
+			continue
+		}
+
+		if err != nil {
+			if previous != nil {
+				previous.rollbackUpgrade(links, updated, pinned)
+			} else {
+				closeLinks(links, pinned)
+			}
+			return nil, nil, nil, err
+		}
+
+		if link == nil {
+			continue
+		}
+
+		if reused {
+			updated[progMeta.Name] = link
+		}
+		if newPin {
+			pinned[link] = struct{}{}
+		}
+		links = append(links, link)
+		progLinks[progMeta.Name] = link
+	}
+
+	return links, progLinks, pinned, nil
+}
+
+// attachProgram 附加单个程序并记录附加状态，不需要 link 的程序返回 nil
+// reused 表示程序通过 link.Update 替换到了 previous 的链接上，pinned 表示链接的 pin 归本加载器所有
+func (p *PreLoadBpfSkeleton) attachProgram(
+	progMeta *meta.ProgMeta,
+	coll *ebpf.Collection,
+	previous *BpfSkeleton,
+	progAttachStatus map[string]meta.ProgAttachStatus,
+) (l link.Link, reused bool, pinned bool, err error) {
+	status := meta.ProgAttachStatus{
+		ProgName: progMeta.Name,
+		Status:   meta.TaskStatusPending,
+		Optional: progMeta.Properties.IsOptional(),
+	}
+	defer func() {
+		if err != nil {
 			progAttachStatus[progMeta.Name] = genAttachErr(status, err)
-			return nil, progAttachStatus, err
 		}
+	}()
+
+	prog := coll.Programs[progMeta.Name]
+	if prog == nil {
+		return nil, false, false, fmt.Errorf("program %s not found", progMeta.Name)
+	}
+
+	progSpec := p.Spec.Programs[progMeta.Name]
+	if progSpec == nil {
+		return nil, false, false, fmt.Errorf("program %s not found", progMeta.Name)
+	}
+
+	if !progMeta.Link {
+		return nil, false, false, nil // 跳过不需要 link 的程序
+	}
+
+	if err := progMeta.ResolveIterMap(coll.Maps); err != nil {
+		return nil, false, false, err
+	}
 
-		if !progMeta.Link {
-			continue // 跳过不需要 link 的程序
+	prevLink := previous.progLink(progMeta.Name)
+	if prevLink != nil && prevLink.Update(prog) == nil {
+		// 原地替换程序，附加点和 pin 保持不变
+		l, reused, pinned = prevLink, true, previous.ownsPin(prevLink)
+	} else {
+		// XDP 替换模式会直接使用已 pin 在 LinkPinPath 上的链接，这类链接不是本次 pin 的
+		linkPinPath := progMeta.Properties.LinkPinPath
+		pinExisted := false
+		if linkPinPath != "" && prevLink == nil {
+			_, statErr := os.Stat(linkPinPath)
+			pinExisted = statErr == nil
 		}
 
 		// 根据不同的 AttachType 使用对应的 attach 方式
-		link, err := progMeta.AttachProgram(progSpec, prog)
+		l, err = progMeta.AttachProgram(progSpec, prog)
 		if err != nil {
-			err := fmt.Errorf("attach program %s error: %w", progMeta.Name, err)
-			progAttachStatus[progMeta.Name] = genAttachErr(status, err)
-			return nil, progAttachStatus, err
+			return nil, false, false, fmt.Errorf("attach program %s error: %w", progMeta.Name, err)
 		}
 
 		// 如果设置了 pinPath，则将程序 pin 到文件系统
-		linkPinPath := progMeta.Properties.LinkPinPath
 		if linkPinPath != "" {
-			if err := link.Pin(linkPinPath); err != nil {
-				err := fmt.Errorf("pin program %s error: %w", progMeta.Name, err)
-				progAttachStatus[progMeta.Name] = genAttachErr(status, err)
-				return nil, progAttachStatus, err
+			// 旧链接仍然附加，只是将 pin 路径让给新链接
+			if prevLink != nil {
+				_ = prevLink.Unpin()
 			}
-		}
-
-		links = append(links, link)
 
-		progInfo, err := prog.Info()
-		if err != nil {
-			err := fmt.Errorf("get program info error: %w", err)
-			progAttachStatus[progMeta.Name] = genAttachErr(status, err)
-			return nil, progAttachStatus, err
+			if err := l.Pin(linkPinPath); err != nil {
+				l.Close()
+				return nil, false, false, fmt.Errorf("pin program %s error: %w", progMeta.Name, err)
+			}
+			// 接替旧链接的 pin 时沿用其归属
+			pinned = !pinExisted
+			if prevLink != nil {
+				pinned = previous.ownsPin(prevLink)
+			}
 		}
+	}
 
-		id, ok := progInfo.ID()
-		if !ok {
-			err := fmt.Errorf("get program id error: %w", err)
-			progAttachStatus[progMeta.Name] = genAttachErr(status, err)
-			return nil, progAttachStatus, err
+	progInfo, err := prog.Info()
+	if err != nil {
+		if pinned {
+			_ = l.Unpin()
 		}
+		if !reused {
+			l.Close()
+		}
+		return nil, false, false, fmt.Errorf("get program info error: %w", err)
+	}
 
-		status.Status = meta.TaskStatusSuccess
-		status.AttachID = uint32(id)
-		progAttachStatus[progMeta.Name] = status
+	id, ok := progInfo.ID()
+	if !ok {
+		if pinned {
+			_ = l.Unpin()
+		}
+		if !reused {
+			l.Close()
+		}
+		return nil, false, false, fmt.Errorf("get program %s id error", progMeta.Name)
 	}
 
-	return &BpfSkeleton{
-		Meta:       p.Meta,
-		ConfigData: p.ConfigData,
-		Links:      links,
-		Collection: coll,
-		Btf:        p.Btf,
-	}, progAttachStatus, nil
+	status.Status = meta.TaskStatusSuccess
+	status.AttachID = uint32(id)
+	status.Mechanism = meta.AttachMechanism(l)
+	status.AttachCount = meta.AttachCount(l)
+	progAttachStatus[progMeta.Name] = status
+
+	return l, reused, pinned, nil
+}
+
+// closeLinks 撤销并关闭本次加载创建的链接，只移除本次 pin 的链接的 pin
+func closeLinks(links []link.Link, pinned map[link.Link]struct{}) {
+	for _, l := range links {
+		if _, ok := pinned[l]; ok {
+			_ = l.Unpin()
+		}
+		_ = l.Close()
+	}
 }
 
 func genAttachErr(status meta.ProgAttachStatus, err error) meta.ProgAttachStatus {
@@ -6,6 +6,7 @@ import (
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/container"
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
 )
 
 func TestPreLoadBpfSkeleton_LoadAndAttach(t *testing.T) {
@@ -62,3 +63,88 @@ func TestPreLoadBpfSkeleton_LoadAndAttach(t *testing.T) {
 		})
 	}
 }
+
+func TestPreLoadBpfSkeleton_attachProgramsPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties *meta.ProgramProperties
+		wantErr    bool
+	}{
+		{name: "default is required", properties: nil, wantErr: true},
+		{name: "optional", properties: &meta.ProgramProperties{Optional: true}},
+		{name: "required wins", properties: &meta.ProgramProperties{Optional: true, Required: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PreLoadBpfSkeleton{
+				Meta: &meta.EunomiaObjectMeta{
+					BpfSkel: meta.BpfSkeletonMeta{
+						Progs: map[string]*meta.ProgMeta{
+							"missing": {Name: "missing", Link: true, Properties: tt.properties},
+						},
+					},
+				},
+				Spec: &ebpf.CollectionSpec{},
+			}
+
+			status := make(map[string]meta.ProgAttachStatus)
+			links, _, _, err := p.attachPrograms(&ebpf.Collection{}, nil, status)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("attachPrograms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(links) != 0 {
+				t.Errorf("attachPrograms() links = %v, want none", links)
+			}
+
+			got := status["missing"]
+			if got.Status != meta.TaskStatusFailed || got.Error == "" {
+				t.Errorf("attach status = %+v, want failed with error", got)
+			}
+			if got.Optional != tt.properties.IsOptional() {
+				t.Errorf("attach status optional = %v, want %v", got.Optional, tt.properties.IsOptional())
+			}
+		})
+	}
+}
+
+func TestCloseLinks(t *testing.T) {
+	// loaded 模拟 XDP 替换模式下从已有 pin 加载的链接
+	created, loaded := &fakeLink{}, &fakeLink{}
+	closeLinks([]link.Link{created, loaded}, map[link.Link]struct{}{created: {}})
+
+	if !created.unpinned || !created.closed {
+		t.Error("link pinned by this attempt must be unpinned and closed")
+	}
+	if loaded.unpinned || !loaded.closed {
+		t.Error("link loaded from an existing pin must keep its pin")
+	}
+}
+
+func TestPreLoadBpfSkeleton_attachProgramsDisabled(t *testing.T) {
+	p := &PreLoadBpfSkeleton{
+		Meta: &meta.EunomiaObjectMeta{
+			BpfSkel: meta.BpfSkeletonMeta{
+				Progs: map[string]*meta.ProgMeta{
+					"disabled": {Name: "disabled", Link: true},
+				},
+			},
+		},
+		Spec:     &ebpf.CollectionSpec{},
+		Disabled: map[string]struct{}{"disabled": {}},
+	}
+
+	// 被禁用的程序不查找也不附加，因此即使不在 collection 中也不会失败
+	status := make(map[string]meta.ProgAttachStatus)
+	links, _, _, err := p.attachPrograms(&ebpf.Collection{}, nil, status)
+	if err != nil {
+		t.Fatalf("attachPrograms() error = %v", err)
+	}
+	if len(links) != 0 {
+		t.Errorf("attachPrograms() links = %v, want none", links)
+	}
+	if got := status["disabled"]; got.Status != meta.TaskStatusDisabled {
+		t.Errorf("attach status = %+v, want disabled", got)
+	}
+}
@@ -0,0 +1,249 @@
+package skeleton
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recordMagic 录制文件头，最后一个字节为格式版本
+var recordMagic = []byte{'B', 'E', 'E', 'P', 'F', 'R', 'C', 1}
+
+// recordHeaderSize 每条记录的固定头部长度：时间戳(8) + CPU(4) + map 名长度(2) + 数据长度(4)
+const recordHeaderSize = 8 + 4 + 2 + 4
+
+// CPUUnknown ring buffer 等不区分 CPU 的 map 录制时使用的 CPU 编号
+const CPUUnknown = -1
+
+// ErrInvalidRecording 录制文件格式错误
+var ErrInvalidRecording = errors.New("invalid recording")
+
+// RawRecord 录制的一条原始事件
+type RawRecord struct {
+	// Time 事件从内核读出的时间
+	Time time.Time
+	// MapName 事件所在的 map
+	MapName string
+	// CPU 产生事件的 CPU，未知时为 CPUUnknown
+	CPU int
+	// Data 原始事件数据
+	Data []byte
+}
+
+// Recorder 将轮询到的原始事件追加写入录制文件，可被多个轮询器共享
+type Recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	buf []byte
+
+	// failures 录制失败的事件数
+	failures atomic.Uint64
+}
+
+// NewRecorder 以追加方式打开录制文件，文件为空时写入文件头
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open recording %s error: %w", path, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat recording %s error: %w", path, err)
+	}
+
+	if info.Size() == 0 {
+		if _, err := f.Write(recordMagic); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("write recording header error: %w", err)
+		}
+	}
+
+	return &Recorder{f: f}, nil
+}
+
+// Record 追加一条记录，每条记录通过一次 write 写入
+func (r *Recorder) Record(mapName string, cpu int, data []byte) error {
+	if len(mapName) > math.MaxUint16 {
+		return fmt.Errorf("map name %q too long", mapName)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return os.ErrClosed
+	}
+
+	r.buf = r.buf[:0]
+	r.buf = binary.LittleEndian.AppendUint64(r.buf, uint64(time.Now().UnixNano()))
+	r.buf = binary.LittleEndian.AppendUint32(r.buf, uint32(int32(cpu)))
+	r.buf = binary.LittleEndian.AppendUint16(r.buf, uint16(len(mapName)))
+	r.buf = binary.LittleEndian.AppendUint32(r.buf, uint32(len(data)))
+	r.buf = append(r.buf, mapName...)
+	r.buf = append(r.buf, data...)
+
+	if _, err := r.f.Write(r.buf); err != nil {
+		return fmt.Errorf("write recording error: %w", err)
+	}
+
+	return nil
+}
+
+// recordEvent 供轮询器录制读到的事件，录制失败不影响事件交付
+// 失败只计数，第一次失败时记录日志
+func (r *Recorder) recordEvent(mapName string, cpu int, data []byte) {
+	if err := r.Record(mapName, cpu, data); err != nil && r.failures.Add(1) == 1 {
+		log.Printf("Warning: record %s event: %v, further failures are only counted", mapName, err)
+	}
+}
+
+// Failures 返回录制失败的事件数
+func (r *Recorder) Failures() uint64 {
+	return r.failures.Load()
+}
+
+// Close 关闭录制文件
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return nil
+	}
+
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// RecordReader 顺序读取录制文件中的记录
+type RecordReader struct {
+	r      *bufio.Reader
+	header [recordHeaderSize]byte
+}
+
+// NewRecordReader 校验文件头并创建读取器
+func NewRecordReader(r io.Reader) (*RecordReader, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(recordMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("%w: read header: %v", ErrInvalidRecording, err)
+	}
+
+	if !bytes.Equal(magic, recordMagic) {
+		return nil, fmt.Errorf("%w: unknown header %q", ErrInvalidRecording, magic)
+	}
+
+	return &RecordReader{r: br}, nil
+}
+
+// Read 读取下一条记录，读完时返回 io.EOF
+func (r *RecordReader) Read() (*RawRecord, error) {
+	if _, err := io.ReadFull(r.r, r.header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("%w: truncated record header: %v", ErrInvalidRecording, err)
+	}
+
+	ts := int64(binary.LittleEndian.Uint64(r.header[0:8]))
+	cpu := int32(binary.LittleEndian.Uint32(r.header[8:12]))
+	nameLen := binary.LittleEndian.Uint16(r.header[12:14])
+	dataLen := binary.LittleEndian.Uint32(r.header[14:18])
+
+	body := make([]byte, int(nameLen)+int(dataLen))
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		return nil, fmt.Errorf("%w: truncated record body: %v", ErrInvalidRecording, err)
+	}
+
+	return &RawRecord{
+		Time:    time.Unix(0, ts),
+		MapName: string(body[:nameLen]),
+		CPU:     int(cpu),
+		Data:    body[nameLen:],
+	}, nil
+}
+
+// ReplaySource 将录制的事件按 map 名称送入与实时轮询相同的处理器，无需加载 eBPF 程序
+type ReplaySource struct {
+	Reader *RecordReader
+	// Processors map 名称到事件处理器的映射
+	Processors map[string]EventProcessor
+	// Realtime 按录制时的时间间隔回放，为 false 时尽快回放
+	Realtime bool
+	// Skipped 没有对应处理器而被跳过的记录数
+	Skipped int
+}
+
+// NewReplaySource 创建回放源
+func NewReplaySource(reader *RecordReader, processors map[string]EventProcessor) *ReplaySource {
+	return &ReplaySource{
+		Reader:     reader,
+		Processors: processors,
+	}
+}
+
+// Run 回放所有记录，直到文件结束、处理器出错或 ctx 被取消
+func (s *ReplaySource) Run(ctx context.Context) error {
+	var last time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record, err := s.Reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		processor, ok := s.Processors[record.MapName]
+		if !ok {
+			s.Skipped++
+			continue
+		}
+
+		if s.Realtime && !last.IsZero() {
+			if err := sleepContext(ctx, record.Time.Sub(last)); err != nil {
+				return err
+			}
+		}
+		last = record.Time
+
+		if err := processor.HandleEvent(record.Data); err != nil {
+			return fmt.Errorf("replay %s event error: %w", record.MapName, err)
+		}
+	}
+}
+
+// sleepContext 等待 d 或 ctx 被取消
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
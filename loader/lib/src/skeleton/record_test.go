@@ -0,0 +1,134 @@
+package skeleton
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// collectProcessor 记录收到的事件
+type collectProcessor struct {
+	events [][]byte
+}
+
+func (p *collectProcessor) HandleEvent(data []byte) error {
+	p.events = append(p.events, data)
+	return nil
+}
+
+func TestRecorder_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.rec")
+
+	records := []RawRecord{
+		{MapName: "events", CPU: 2, Data: []byte{1, 2, 3}},
+		{MapName: "rb", CPU: CPUUnknown, Data: []byte{4}},
+		{MapName: "events", CPU: 0, Data: []byte{}},
+	}
+
+	// 两次打开同一个文件，第二次应追加而不是重写文件头
+	for _, batch := range [][]RawRecord{records[:2], records[2:]} {
+		recorder, err := NewRecorder(path)
+		if err != nil {
+			t.Fatalf("NewRecorder() error = %v", err)
+		}
+		for _, r := range batch {
+			if err := recorder.Record(r.MapName, r.CPU, r.Data); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+		}
+		if err := recorder.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	reader, err := NewRecordReader(f)
+	if err != nil {
+		t.Fatalf("NewRecordReader() error = %v", err)
+	}
+
+	for i, want := range records {
+		got, err := reader.Read()
+		if err != nil {
+			t.Fatalf("Read() #%d error = %v", i, err)
+		}
+		if got.MapName != want.MapName || got.CPU != want.CPU || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("Read() #%d = %+v, want %+v", i, got, want)
+		}
+		if got.Time.IsZero() {
+			t.Errorf("Read() #%d has no timestamp", i)
+		}
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		t.Errorf("Read() after last record error = %v, want EOF", err)
+	}
+}
+
+func TestRecordReader_Invalid(t *testing.T) {
+	if _, err := NewRecordReader(bytes.NewReader([]byte("not a recording"))); !errors.Is(err, ErrInvalidRecording) {
+		t.Errorf("NewRecordReader() error = %v, want ErrInvalidRecording", err)
+	}
+
+	// 截断的记录
+	data := append(append([]byte{}, recordMagic...), 1, 2, 3)
+	reader, err := NewRecordReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewRecordReader() error = %v", err)
+	}
+	if _, err := reader.Read(); !errors.Is(err, ErrInvalidRecording) {
+		t.Errorf("Read() error = %v, want ErrInvalidRecording", err)
+	}
+}
+
+func TestReplaySource_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.rec")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []RawRecord{
+		{MapName: "events", Data: []byte{1}},
+		{MapName: "unknown", Data: []byte{2}},
+		{MapName: "events", Data: []byte{3}},
+	} {
+		if err := recorder.Record(r.MapName, 0, r.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorder.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	reader, err := NewRecordReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := &collectProcessor{}
+	source := NewReplaySource(reader, map[string]EventProcessor{"events": processor})
+	if err := source.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if want := [][]byte{{1}, {3}}; !reflect.DeepEqual(processor.events, want) {
+		t.Errorf("replayed events = %v, want %v", processor.events, want)
+	}
+	if source.Skipped != 1 {
+		t.Errorf("Skipped = %d, want 1", source.Skipped)
+	}
+}
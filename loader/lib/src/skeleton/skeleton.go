@@ -6,6 +6,7 @@ import (
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/container"
 	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
 	"github.com/cilium/ebpf/link"
 )
 
@@ -21,6 +22,13 @@ type PreLoadBpfSkeleton struct {
 	// BTF 信息
 	Btf *container.BTFContainer
 
+	// KernelBtf 内核 BTF 信息，用于加载前检查附加点
+	KernelBtf *btf.Spec
+
+	// CoreBtf 不是由运行中的内核提供的 BTF，例如 btfhub 归档或程序包内嵌的最小化 BTF
+	// 不为空时加载程序用它代替内核 BTF 进行 CO-RE 重定位
+	CoreBtf *btf.Spec
+
 	// CollectionSpec 替代原来的 bpf_object
 	// 包含了未加载的程序和 maps 的规格说明
 	Spec *ebpf.CollectionSpec
@@ -30,6 +38,9 @@ type PreLoadBpfSkeleton struct {
 
 	// 原始 ELF 数据
 	RawElf *container.ElfContainer
+
+	// Disabled 加载后不附加的程序，升级时用于保持被禁用的程序仍处于禁用状态
+	Disabled map[string]struct{}
 }
 
 // BpfSkeleton 表示一个已加载并运行的 BPF 程序
@@ -49,6 +60,12 @@ type BpfSkeleton struct {
 	// 程序的链接信息
 	Links []link.Link
 
+	// ProgLinks 程序名称到链接的映射
+	ProgLinks map[string]link.Link
+
+	// PinnedLinks 由本加载器 pin 到文件系统的链接，复用的他人 pin 不在其中，停止时只移除这些链接的 pin
+	PinnedLinks map[link.Link]struct{}
+
 	// Collection 替代原来的 prog
 	// 包含已加载的程序和 maps
 	Collection *ebpf.Collection
@@ -0,0 +1,110 @@
+package skeleton
+
+import (
+	"fmt"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// prepareTracingTargets 为以其他 BPF 程序为目标的 fentry/fexit/freplace 程序设置附加目标
+// 外部目标（ID 或 pin 路径）直接写入 spec；同一对象中的目标需要先加载，
+// 因此将这些程序从 spec 中移出，返回程序名称到目标程序名称的映射
+func (p *PreLoadBpfSkeleton) prepareTracingTargets(spec *ebpf.CollectionSpec) (map[string]string, []*ebpf.Program, error) {
+	deferred := make(map[string]string)
+	var targets []*ebpf.Program
+
+	for _, progMeta := range p.Meta.BpfSkel.Progs {
+		if progMeta.Properties == nil || !progMeta.Properties.Tracing.HasProgramTarget() {
+			continue
+		}
+
+		progSpec := spec.Programs[progMeta.Name]
+		if progSpec == nil {
+			continue
+		}
+
+		if progSpec.Type != ebpf.Tracing && progSpec.Type != ebpf.Extension {
+			closePrograms(targets)
+			return nil, nil, fmt.Errorf("program %s of type %s cannot target a bpf program", progMeta.Name, progSpec.Type)
+		}
+
+		tracing := progMeta.Properties.Tracing
+		if tracing.TargetFunc != "" {
+			progSpec.AttachTo = tracing.TargetFunc
+		}
+
+		target, err := tracing.LoadTarget()
+		if err != nil {
+			closePrograms(targets)
+			return nil, nil, fmt.Errorf("program %s: %w", progMeta.Name, err)
+		}
+
+		if target != nil {
+			progSpec.AttachTarget = target
+			targets = append(targets, target)
+			continue
+		}
+
+		if _, ok := spec.Programs[tracing.TargetProgName]; !ok {
+			closePrograms(targets)
+			return nil, nil, fmt.Errorf("target program %s of %s not found", tracing.TargetProgName, progMeta.Name)
+		}
+		deferred[progMeta.Name] = tracing.TargetProgName
+	}
+
+	for name, target := range deferred {
+		if _, ok := deferred[target]; ok {
+			closePrograms(targets)
+			return nil, nil, fmt.Errorf("target program %s of %s also targets a program in the same object", target, name)
+		}
+	}
+
+	return deferred, targets, nil
+}
+
+// loadDeferredPrograms 在目标程序加载完成后加载依赖它们的 tracing 程序
+// 新程序复用已加载集合中的 map，加载后合并到 coll.Programs 中；kernel 用于分析加载失败时的 CO-RE 重定位
+func loadDeferredPrograms(coll *ebpf.Collection, spec *ebpf.CollectionSpec, deferred map[string]*ebpf.ProgramSpec, targets map[string]string, kernel *btf.Spec) error {
+	deferredSpec := &ebpf.CollectionSpec{
+		Maps:      spec.Maps,
+		Programs:  make(map[string]*ebpf.ProgramSpec, len(deferred)),
+		Types:     spec.Types,
+		ByteOrder: spec.ByteOrder,
+	}
+
+	for name, progSpec := range deferred {
+		target := coll.Programs[targets[name]]
+		if target == nil {
+			return fmt.Errorf("target program %s of %s not loaded", targets[name], name)
+		}
+
+		progSpec.AttachTarget = target
+		deferredSpec.Programs[name] = progSpec
+	}
+
+	deferredColl, err := ebpf.NewCollectionWithOptions(deferredSpec, ebpf.CollectionOptions{
+		MapReplacements: coll.Maps,
+	})
+	if err != nil {
+		return fmt.Errorf("load programs targeting %v error: %w", targets, meta.NewLoadError(deferredSpec, kernel, err))
+	}
+
+	// 集合中的 map 是 coll.Maps 的副本，程序已持有引用，可以直接关闭
+	for _, m := range deferredColl.Maps {
+		m.Close()
+	}
+
+	for name, prog := range deferredColl.Programs {
+		coll.Programs[name] = prog
+	}
+
+	return nil
+}
+
+func closePrograms(progs []*ebpf.Program) {
+	for _, prog := range progs {
+		prog.Close()
+	}
+}
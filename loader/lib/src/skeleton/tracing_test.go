@@ -0,0 +1,99 @@
+package skeleton
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf"
+)
+
+func TestPreLoadBpfSkeleton_prepareTracingTargets(t *testing.T) {
+	newSpec := func() *ebpf.CollectionSpec {
+		return &ebpf.CollectionSpec{
+			Programs: map[string]*ebpf.ProgramSpec{
+				"xdp_main":   {Name: "xdp_main", Type: ebpf.XDP},
+				"trace_xdp":  {Name: "trace_xdp", Type: ebpf.Tracing, AttachTo: "xdp_main"},
+				"patch_xdp":  {Name: "patch_xdp", Type: ebpf.Extension, AttachTo: "parse"},
+				"kprobe_tcp": {Name: "kprobe_tcp", Type: ebpf.Kprobe},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		progs    map[string]*meta.TracingProperties
+		want     map[string]string
+		wantFunc map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "same object",
+			progs: map[string]*meta.TracingProperties{
+				"trace_xdp": {TargetProgName: "xdp_main"},
+				"patch_xdp": {TargetProgName: "xdp_main", TargetFunc: "parse_eth"},
+			},
+			want:     map[string]string{"trace_xdp": "xdp_main", "patch_xdp": "xdp_main"},
+			wantFunc: map[string]string{"trace_xdp": "xdp_main", "patch_xdp": "parse_eth"},
+		},
+		{
+			name:    "target not found",
+			progs:   map[string]*meta.TracingProperties{"trace_xdp": {TargetProgName: "missing"}},
+			wantErr: true,
+		},
+		{
+			name:    "not a tracing program",
+			progs:   map[string]*meta.TracingProperties{"kprobe_tcp": {TargetProgName: "xdp_main"}},
+			wantErr: true,
+		},
+		{
+			name: "chained targets",
+			progs: map[string]*meta.TracingProperties{
+				"trace_xdp": {TargetProgName: "patch_xdp"},
+				"patch_xdp": {TargetProgName: "xdp_main"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progs := make(map[string]*meta.ProgMeta)
+			for name, tracing := range tt.progs {
+				progs[name] = &meta.ProgMeta{
+					Name:       name,
+					Link:       true,
+					Properties: &meta.ProgramProperties{Tracing: tracing},
+				}
+			}
+
+			p := &PreLoadBpfSkeleton{
+				Meta: &meta.EunomiaObjectMeta{BpfSkel: meta.BpfSkeletonMeta{Progs: progs}},
+			}
+
+			spec := newSpec()
+			got, targets, err := p.prepareTracingTargets(spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("prepareTracingTargets() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if len(targets) != 0 {
+				t.Errorf("prepareTracingTargets() loaded %d external targets, want 0", len(targets))
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prepareTracingTargets() got = %v, want %v", got, tt.want)
+			}
+
+			for name, fn := range tt.wantFunc {
+				if spec.Programs[name].AttachTo != fn {
+					t.Errorf("program %s AttachTo = %s, want %s", name, spec.Programs[name].AttachTo, fn)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package skeleton
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// Upgrade 加载新版本的对象并接管正在运行的 previous
+// 与 previous 中同名且兼容的 map 会被沿用以保留状态；支持 link.Update 的附加点原地替换程序，
+// 其余附加点先附加新程序，旧链接由调用方在切换完成后通过 previous.CloseReplaced 关闭
+// 附加失败时 previous 保持原样继续运行
+func (p *PreLoadBpfSkeleton) Upgrade(previous *BpfSkeleton) (*BpfSkeleton, map[string]meta.ProgAttachStatus, error) {
+	if previous == nil || previous.Collection == nil {
+		return nil, nil, fmt.Errorf("upgrade: previous skeleton is not loaded")
+	}
+
+	return p.loadAndAttach(p.SharedMaps(previous.Collection), previous)
+}
+
+// SharedMaps 返回 coll 中可以被新对象沿用的 map
+// .rodata、.data、.bss 等全局变量 map 的布局随对象变化，不沿用
+func (p *PreLoadBpfSkeleton) SharedMaps(coll *ebpf.Collection) map[string]*ebpf.Map {
+	shared := make(map[string]*ebpf.Map)
+	for name, spec := range p.Spec.Maps {
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		m, ok := coll.Maps[name]
+		if !ok {
+			continue
+		}
+
+		if err := spec.Compatible(m); err != nil {
+			continue
+		}
+
+		shared[name] = m
+	}
+
+	return shared
+}
+
+// CloseReplaced 关闭被 next 取代的链接和 collection
+// next 中原地替换了程序的链接会被保留
+func (s *BpfSkeleton) CloseReplaced(next *BpfSkeleton) error {
+	kept := make(map[link.Link]struct{}, len(next.Links))
+	for _, l := range next.Links {
+		kept[l] = struct{}{}
+	}
+
+	var errs []error
+	for _, l := range s.Links {
+		if _, ok := kept[l]; ok {
+			continue
+		}
+
+		if err := l.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.Collection != nil {
+		s.Collection.Close()
+	}
+
+	s.Links = nil
+	s.ProgLinks = nil
+	s.Collection = nil
+
+	return errors.Join(errs...)
+}
+
+// ownsPin 判断链接的 pin 是否由本加载器创建，s 为空时返回 false
+func (s *BpfSkeleton) ownsPin(l link.Link) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.PinnedLinks[l]
+	return ok
+}
+
+// progLink 返回程序对应的链接，s 为空时返回 nil
+func (s *BpfSkeleton) progLink(name string) link.Link {
+	if s == nil {
+		return nil
+	}
+	return s.ProgLinks[name]
+}
+
+// rollbackUpgrade 撤销失败的升级：已原地替换的链接恢复为旧程序，新建的链接被关闭，旧链接的 pin 被恢复
+// pinned 为本次升级 pin 的链接，只有这些链接的 pin 会被移除
+func (s *BpfSkeleton) rollbackUpgrade(links []link.Link, updated map[string]link.Link, pinned map[link.Link]struct{}) {
+	reused := make(map[link.Link]struct{}, len(updated))
+	for name, l := range updated {
+		reused[l] = struct{}{}
+		if prog := s.Collection.Programs[name]; prog != nil {
+			_ = l.Update(prog)
+		}
+	}
+
+	created := make([]link.Link, 0, len(links))
+	for _, l := range links {
+		if _, ok := reused[l]; !ok {
+			created = append(created, l)
+		}
+	}
+	closeLinks(created, pinned)
+
+	if s.Meta == nil {
+		return
+	}
+
+	for name, progMeta := range s.Meta.BpfSkel.Progs {
+		l := s.ProgLinks[name]
+		if l == nil || progMeta.Properties == nil || progMeta.Properties.LinkPinPath == "" {
+			continue
+		}
+		_ = l.Pin(progMeta.Properties.LinkPinPath)
+	}
+}
@@ -0,0 +1,91 @@
+package skeleton
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+)
+
+// fakeLink 记录 Update、Unpin 和 Close 调用的链接
+type fakeLink struct {
+	link.Link
+
+	updated  *ebpf.Program
+	unpinned bool
+	closed   bool
+}
+
+func (l *fakeLink) Update(prog *ebpf.Program) error {
+	l.updated = prog
+	return nil
+}
+
+func (l *fakeLink) Unpin() error {
+	l.unpinned = true
+	return nil
+}
+
+func (l *fakeLink) Close() error {
+	l.closed = true
+	return nil
+}
+
+func TestBpfSkeleton_CloseReplaced(t *testing.T) {
+	kept, replaced := &fakeLink{}, &fakeLink{}
+	previous := &BpfSkeleton{
+		Links:     []link.Link{kept, replaced},
+		ProgLinks: map[string]link.Link{"a": kept, "b": replaced},
+	}
+	next := &BpfSkeleton{
+		Links: []link.Link{kept, &fakeLink{}},
+	}
+
+	if err := previous.CloseReplaced(next); err != nil {
+		t.Fatalf("CloseReplaced() error = %v", err)
+	}
+
+	if kept.closed {
+		t.Error("link updated in place must not be closed")
+	}
+	if !replaced.closed {
+		t.Error("replaced link must be closed")
+	}
+	if previous.Links != nil || previous.ProgLinks != nil {
+		t.Error("previous skeleton must drop its links")
+	}
+}
+
+func TestBpfSkeleton_rollbackUpgrade(t *testing.T) {
+	oldProg := &ebpf.Program{}
+	updated, created := &fakeLink{}, &fakeLink{}
+	previous := &BpfSkeleton{
+		ProgLinks:  map[string]link.Link{"a": updated},
+		Collection: &ebpf.Collection{Programs: map[string]*ebpf.Program{"a": oldProg}},
+	}
+
+	previous.rollbackUpgrade([]link.Link{updated, created}, map[string]link.Link{"a": updated}, map[link.Link]struct{}{created: {}})
+
+	if updated.updated != oldProg {
+		t.Error("updated link must be restored to the old program")
+	}
+	if updated.closed {
+		t.Error("updated link must stay attached")
+	}
+	if !created.closed || !created.unpinned {
+		t.Error("link created by the upgrade must be unpinned and closed")
+	}
+}
+
+func TestBpfSkeleton_progLink(t *testing.T) {
+	var nilSkel *BpfSkeleton
+	if nilSkel.progLink("a") != nil {
+		t.Error("nil skeleton must return nil link")
+	}
+
+	l := &fakeLink{}
+	skel := &BpfSkeleton{ProgLinks: map[string]link.Link{"a": l}}
+	if skel.progLink("a") != l {
+		t.Error("progLink() did not return the program link")
+	}
+}
@@ -0,0 +1,110 @@
+// runner 通用的 eBPF 对象运行器
+// 根据对象文件中的全局变量和骨架文档生成命令行参数，无需为简单工具编写 main.go
+//
+// 用法: runner [选项] <object.o> [变量参数]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	loader "github.com/cen-ngc5139/BeePF/loader/lib/src/cli"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
+	"go.uber.org/zap"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("runner", flag.ContinueOnError)
+	metaPath := fs.String("meta", "", "骨架 JSON 文件，提供 doc 和变量的 cmdarg (可选)")
+	btfPath := fs.String("btf", "", "内核 BTF 文件路径 (可选)")
+	pollTimeout := fs.Duration("poll-timeout", 100*time.Millisecond, "轮询超时时间")
+	verbose := fs.Bool("v", false, "输出调试日志")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: runner [options] <object.o> [variable flags]\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\n使用 runner <object.o> -h 查看对象文件的变量参数\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("object file is required")
+	}
+
+	objectPath := fs.Arg(0)
+	objectBytes, err := os.ReadFile(objectPath)
+	if err != nil {
+		return fmt.Errorf("read object file: %w", err)
+	}
+
+	var properties meta.Properties
+	if *metaPath != "" {
+		data, err := os.ReadFile(*metaPath)
+		if err != nil {
+			return fmt.Errorf("read meta file: %w", err)
+		}
+
+		if err := properties.MergeSkelDoc(data); err != nil {
+			return err
+		}
+	}
+
+	// 根据对象文件中的全局变量生成参数
+	varFlags, err := loader.NewVariableFlagSet(filepath.Base(objectPath), objectBytes, properties)
+	if err != nil {
+		return err
+	}
+
+	if err := varFlags.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+
+	properties.Variables = varFlags.Variables()
+
+	logger, err := newLogger(*verbose)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	bpfLoader, err := loader.NewBPFLoader(&loader.Config{
+		ObjectPath:  objectPath,
+		BTFPath:     *btfPath,
+		Logger:      logger,
+		PollTimeout: *pollTimeout,
+		Properties:  properties,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := loader.SignalContext(context.Background())
+	defer cancel()
+
+	return bpfLoader.Run(ctx)
+}
+
+func newLogger(verbose bool) (*zap.Logger, error) {
+	if verbose {
+		return zap.NewDevelopment()
+	}
+	return zap.NewProduction()
+}
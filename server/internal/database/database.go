@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/cen-ngc5139/BeePF/server/conf"
+	"github.com/cen-ngc5139/BeePF/server/models"
 
 	"gorm.io/gorm/logger"
 
@@ -47,5 +48,21 @@ func Setup() {
 	sqlDB.SetMaxIdleConns(config.Database.MaxIdle)
 	sqlDB.SetMaxOpenConns(config.Database.MaxOpen)
 
+	if err := migrate(db); err != nil {
+		log.Fatalf("migrate database err: %v (run sql/migrations/001_task_load_error.sql manually to add task.load_error column)", err)
+	}
+
 	DB = db
 }
+
+// migrate 为已有部署补齐后续版本新增的列，新部署由 sql 目录中的建表语句创建
+// 没有 ALTER 权限时可以手动执行 sql/migrations 中对应的语句
+func migrate(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.TaskDB{}, "LoadError") {
+		if err := db.Migrator().AddColumn(&models.TaskDB{}, "LoadError"); err != nil {
+			return fmt.Errorf("add column task.load_error error: %w", err)
+		}
+	}
+
+	return nil
+}
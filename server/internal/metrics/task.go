@@ -3,6 +3,7 @@ package metrics
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"sync"
 
 	"github.com/cen-ngc5139/BeePF/server/models"
@@ -36,10 +37,22 @@ const (
 	TaskTotalAvgRunTimeNS = "beepf_task_total_avg_run_time_ns"
 	// 任务采样周期(ns)
 	TaskPeriodNS = "beepf_task_period_ns"
+	// 任务 map 读到的事件数
+	TaskMapEventsReceived = "beepf_task_map_events_received"
+	// 任务 map 中内核因缓冲区已满丢弃的事件数
+	TaskMapEventsLost = "beepf_task_map_events_lost"
+	// 任务 map 中处理失败而丢弃的事件数
+	TaskMapEventsDropped = "beepf_task_map_events_dropped"
+	// 任务 map 事件处理流水线中排队的事件数
+	TaskMapQueueDepth = "beepf_task_map_queue_depth"
+	// 任务 map 事件处理流水线队列已满时丢弃的事件数
+	TaskMapQueueDropped = "beepf_task_map_queue_dropped"
 )
 
 var (
-	TaskMetricsLabels = []string{"task_id", "component_id", "program_id", "node_name"}
+	TaskMetricsLabels    = []string{"task_id", "component_id", "program_id", "node_name"}
+	TaskMapMetricsLabels = []string{"task_id", "component_id", "map_name", "cpu", "node_name"}
+	TaskMapQueueLabels   = []string{"task_id", "component_id", "map_name", "node_name"}
 )
 
 type TaskStatsMetrics struct {
@@ -48,6 +61,11 @@ type TaskStatsMetrics struct {
 	TaskAvgRunTimeNS      *prometheus.GaugeVec
 	TaskTotalAvgRunTimeNS *prometheus.GaugeVec
 	TaskPeriodNS          *prometheus.GaugeVec
+	TaskMapEventsReceived *prometheus.GaugeVec
+	TaskMapEventsLost     *prometheus.GaugeVec
+	TaskMapEventsDropped  *prometheus.GaugeVec
+	TaskMapQueueDepth     *prometheus.GaugeVec
+	TaskMapQueueDropped   *prometheus.GaugeVec
 }
 
 func createGaugeVec(name, help string, labels []string) *prometheus.GaugeVec {
@@ -67,6 +85,11 @@ func NewTaskStatsMetrics() *TaskStatsMetrics {
 		TaskAvgRunTimeNS:      createGaugeVec(TaskAvgRunTimeNS, "ebpf program task avg run time ns", TaskMetricsLabels),
 		TaskTotalAvgRunTimeNS: createGaugeVec(TaskTotalAvgRunTimeNS, "ebpf program task total avg run time ns", TaskMetricsLabels),
 		TaskPeriodNS:          createGaugeVec(TaskPeriodNS, "ebpf program task period ns", TaskMetricsLabels),
+		TaskMapEventsReceived: createGaugeVec(TaskMapEventsReceived, "ebpf task map events received", TaskMapMetricsLabels),
+		TaskMapEventsLost:     createGaugeVec(TaskMapEventsLost, "ebpf task map events lost in kernel", TaskMapMetricsLabels),
+		TaskMapEventsDropped:  createGaugeVec(TaskMapEventsDropped, "ebpf task map events dropped in user space", TaskMapMetricsLabels),
+		TaskMapQueueDepth:     createGaugeVec(TaskMapQueueDepth, "ebpf task map events queued in pipeline", TaskMapQueueLabels),
+		TaskMapQueueDropped:   createGaugeVec(TaskMapQueueDropped, "ebpf task map events dropped by pipeline", TaskMapQueueLabels),
 	}
 }
 
@@ -76,6 +99,11 @@ func (m *TaskMetrics) ResetMetrics() {
 	m.TaskStats.TaskAvgRunTimeNS.Reset()
 	m.TaskStats.TaskTotalAvgRunTimeNS.Reset()
 	m.TaskStats.TaskPeriodNS.Reset()
+	m.TaskStats.TaskMapEventsReceived.Reset()
+	m.TaskStats.TaskMapEventsLost.Reset()
+	m.TaskStats.TaskMapEventsDropped.Reset()
+	m.TaskStats.TaskMapQueueDepth.Reset()
+	m.TaskStats.TaskMapQueueDropped.Reset()
 }
 
 func (m *TaskMetrics) UpdateMetricsFromCache(nodeName string) {
@@ -83,6 +111,7 @@ func (m *TaskMetrics) UpdateMetricsFromCache(nodeName string) {
 	m.TaskStore.Range(func(key, value interface{}) bool {
 		task := value.(*models.RunningTask)
 		taskID := fmt.Sprintf("%d", task.Task.ID)
+		m.updateMapMetrics(task, taskID, nodeName)
 		for _, v := range task.Task.ProgStatus {
 			// todo 此处通过 prog attach id 无法找到对应的 prog stats
 			programStats, err := task.BPFLoader.StatsCollector.GetProgramStats(v.AttachID)
@@ -104,6 +133,29 @@ func (m *TaskMetrics) UpdateMetricsFromCache(nodeName string) {
 	})
 }
 
+// updateMapMetrics 按 map 和 CPU 更新任务的事件、丢失和丢弃计数，以及流水线的队列深度和丢弃计数
+func (m *TaskMetrics) updateMapMetrics(task *models.RunningTask, taskID, nodeName string) {
+	if task.BPFLoader == nil || task.BPFLoader.StatsCollector == nil {
+		return
+	}
+
+	componentID := fmt.Sprintf("%d", task.Task.ComponentID)
+	for _, stats := range task.BPFLoader.StatsCollector.GetMapStats() {
+		for _, cpu := range stats.CPUs {
+			labels := []string{taskID, componentID, stats.Name, strconv.Itoa(cpu.CPU), nodeName}
+			m.TaskStats.TaskMapEventsReceived.WithLabelValues(labels...).Set(float64(cpu.Received))
+			m.TaskStats.TaskMapEventsLost.WithLabelValues(labels...).Set(float64(cpu.Lost))
+			m.TaskStats.TaskMapEventsDropped.WithLabelValues(labels...).Set(float64(cpu.Dropped))
+		}
+
+		if stats.Pipeline != nil {
+			labels := []string{taskID, componentID, stats.Name, nodeName}
+			m.TaskStats.TaskMapQueueDepth.WithLabelValues(labels...).Set(float64(stats.Pipeline.QueueDepth))
+			m.TaskStats.TaskMapQueueDropped.WithLabelValues(labels...).Set(float64(stats.Pipeline.Dropped))
+		}
+	}
+}
+
 func (m *TaskMetrics) Handler() gin.HandlerFunc {
 	h := promhttp.Handler()
 
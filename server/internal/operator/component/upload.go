@@ -70,7 +70,11 @@ func (o *Operator) UploadBinary() (err error) {
 		},
 	}
 
-	bpfLoader := loader.NewBPFLoader(config)
+	bpfLoader, err := loader.NewBPFLoader(config)
+	if err != nil {
+		logger.Error("创建 BPF 加载器失败", zap.Error(err))
+		return errors.Wrap(err, "创建 BPF 加载器失败")
+	}
 
 	err = bpfLoader.Init()
 	if err != nil {
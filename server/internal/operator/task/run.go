@@ -2,6 +2,7 @@ package task
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -125,7 +126,17 @@ func (o *Operator) RunComponentAsync(task *models.Task, component *models.Compon
 		},
 	}
 
-	bpfLoader := loader.NewBPFLoader(config)
+	bpfLoader, err := loader.NewBPFLoader(config)
+	if err != nil {
+		logger.Error("创建 BPF 加载器失败", zap.Error(err))
+		task.Status = models.TaskStatusFailed
+		task.Error = "创建 BPF 加载器失败: " + err.Error()
+		task.UpdatedAt = time.Now()
+		if updateErr := o.TaskStore.UpdateTask(task); updateErr != nil {
+			logger.Error("更新任务状态失败", zap.Error(updateErr))
+		}
+		return
+	}
 	runningTask.BPFLoader = bpfLoader
 
 	// 初始化BPF加载器
@@ -165,6 +176,10 @@ func (o *Operator) RunComponentAsync(task *models.Task, component *models.Compon
 		logger.Error("加载 BPF 程序失败", zap.Error(err))
 		task.Status = models.TaskStatusFailed
 		task.Error = "加载 BPF 程序失败: " + err.Error()
+		var loadErr *meta.LoadError
+		if errors.As(err, &loadErr) {
+			task.LoadError = loadErr
+		}
 		task.UpdatedAt = time.Now()
 		if updateErr := o.TaskStore.UpdateTask(task); updateErr != nil {
 			logger.Error("更新任务状态失败", zap.Error(updateErr))
@@ -180,21 +195,7 @@ func (o *Operator) RunComponentAsync(task *models.Task, component *models.Compon
 	cache.TaskRunningStore.Store(task.ID, runningTask)
 
 	// 更新程序状态
-	progStatuses := make([]models.ComProgStatus, 0, len(task.ProgStatus))
-	for _, prog := range task.ProgStatus {
-		status, ok := bpfLoader.ProgAttachStatus[prog.ProgramName]
-		if !ok {
-			prog.Status = models.TaskStatusFailed
-			prog.Error = "程序未找到"
-		}
-
-		prog.Status = models.TaskStatus(status.Status)
-		prog.AttachID = status.AttachID
-		prog.Error = status.Error
-		progStatuses = append(progStatuses, prog)
-	}
-
-	task.ProgStatus = progStatuses
+	syncProgStatus(task, bpfLoader)
 
 	err = o.TaskStore.UpdateTask(task)
 	if err != nil {
@@ -293,6 +294,111 @@ func (o *Operator) StopTask(taskID uint64) error {
 	return nil
 }
 
+// syncProgStatus 使用加载器的程序附加状态更新任务的程序状态
+func syncProgStatus(task *models.Task, bpfLoader *loader.BPFLoader) {
+	attachStatus := bpfLoader.ProgStatus()
+	progStatuses := make([]models.ComProgStatus, 0, len(task.ProgStatus))
+	for _, prog := range task.ProgStatus {
+		status, ok := attachStatus[prog.ProgramName]
+		if !ok {
+			prog.Status = models.TaskStatusFailed
+			prog.Error = "程序未找到"
+			progStatuses = append(progStatuses, prog)
+			continue
+		}
+
+		prog.Status = models.TaskStatus(status.Status)
+		prog.AttachID = status.AttachID
+		prog.Error = status.Error
+		prog.UpdatedAt = time.Now()
+		progStatuses = append(progStatuses, prog)
+	}
+
+	task.ProgStatus = progStatuses
+}
+
+// SetTaskProgramEnabled 在正在运行的任务中启用或禁用单个程序，其余程序和 map 不受影响
+func (o *Operator) SetTaskProgramEnabled(taskID uint64, progName string, enabled bool) error {
+	runningTask, err := loadedRunningTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	bpfLoader := runningTask.BPFLoader
+	if enabled {
+		err = bpfLoader.EnableProgram(progName)
+	} else {
+		err = bpfLoader.DisableProgram(progName)
+	}
+	if err != nil {
+		return err
+	}
+
+	task := runningTask.Task
+	syncProgStatus(task, bpfLoader)
+	task.UpdatedAt = time.Now()
+	return o.TaskStore.UpdateTask(task)
+}
+
+// RunTaskIterator 触发正在运行任务中的迭代器程序执行一次迭代
+func (o *Operator) RunTaskIterator(taskID uint64, progName string) (*loader.IteratorOutput, error) {
+	bpfLoader, err := runningLoader(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bpfLoader.RunIterator(progName)
+}
+
+// GetTaskVariables 读取运行中任务的全局变量
+func (o *Operator) GetTaskVariables(taskID uint64) ([]loader.VariableValue, error) {
+	bpfLoader, err := runningLoader(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bpfLoader.ListVariables()
+}
+
+// SetTaskVariable 修改运行中任务 .data/.bss 中的全局变量
+func (o *Operator) SetTaskVariable(taskID uint64, name string, value json.RawMessage) (*loader.VariableValue, error) {
+	bpfLoader, err := runningLoader(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bpfLoader.SetVariable(name, value); err != nil {
+		return nil, err
+	}
+
+	return bpfLoader.GetVariable(name)
+}
+
+// runningLoader 返回运行中任务的加载器
+func runningLoader(taskID uint64) (*loader.BPFLoader, error) {
+	runningTask, err := loadedRunningTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return runningTask.BPFLoader, nil
+}
+
+// loadedRunningTask 返回已完成加载的运行中任务
+func loadedRunningTask(taskID uint64) (*models.RunningTask, error) {
+	value, exists := cache.TaskRunningStore.Load(taskID)
+	if !exists {
+		return nil, errors.New("任务不存在或已停止")
+	}
+
+	runningTask := value.(*models.RunningTask)
+	if runningTask.BPFLoader == nil {
+		return nil, errors.New("任务尚未完成加载")
+	}
+
+	return runningTask, nil
+}
+
 // GetRunningTasks 获取所有正在运行的任务
 func (o *Operator) GetRunningTasks() []*models.Task {
 	runningTasks := make([]*models.Task, 0)
@@ -126,6 +126,7 @@ func (s *Store) UpdateTask(task *models.Task) error {
 		taskDB.Status = int(task.Status)
 		taskDB.Step = int(task.Step)
 		taskDB.Error = task.Error
+		taskDB.LoadError = (*models.JSONLoadError)(task.LoadError)
 
 		// 确保更新时间有效
 		if !task.UpdatedAt.IsZero() {
@@ -135,7 +136,7 @@ func (s *Store) UpdateTask(task *models.Task) error {
 		}
 
 		// 使用Select指定要更新的字段，避免更新零值
-		if err := tx.Model(&taskDB).Select("status", "step", "error", "last_update_time").Updates(taskDB).Error; err != nil {
+		if err := tx.Model(&taskDB).Select("status", "step", "error", "load_error", "last_update_time").Updates(taskDB).Error; err != nil {
 			return err
 		}
 
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	loader "github.com/cen-ngc5139/BeePF/loader/lib/src/cli"
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -18,6 +19,7 @@ type Task struct {
 	Step          TaskStep        `json:"step"`
 	Status        TaskStatus      `json:"status"`
 	Error         string          `json:"error"`
+	LoadError     *meta.LoadError `json:"load_error,omitempty"`
 	ProgStatus    []ComProgStatus `json:"prog_status"`
 	CreatedAt     time.Time       `json:"created_at"`
 	UpdatedAt     time.Time       `json:"updated_at"`
@@ -41,6 +43,7 @@ const (
 	TaskStatusRunning
 	TaskStatusSuccess
 	TaskStatusFailed
+	TaskStatusDisabled
 )
 
 type ComProgStatus struct {
@@ -1,23 +1,28 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
+
+	"github.com/cen-ngc5139/BeePF/loader/lib/src/meta"
 )
 
 // TaskDB 任务数据库模型
 type TaskDB struct {
-	ID             uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	Name           string    `gorm:"column:name" json:"name"`
-	Description    string    `gorm:"column:description;type:text" json:"description"`
-	ComponentID    uint64    `gorm:"column:component_id;index" json:"component_id"`
-	ComponentName  string    `gorm:"column:component_name" json:"component_name"`
-	Step           int       `gorm:"column:step;comment:任务步骤" json:"step"`
-	Status         int       `gorm:"column:status;comment:任务状态" json:"status"`
-	Error          string    `gorm:"column:error;type:text" json:"error"`
-	Deleted        uint8     `gorm:"column:deleted;default:0" json:"deleted"`
-	Creator        string    `gorm:"column:creator" json:"creator"`
-	CreatedTime    time.Time `gorm:"column:created_time;autoCreateTime" json:"created_time"`
-	LastUpdateTime time.Time `gorm:"column:last_update_time;autoUpdateTime" json:"last_update_time"`
+	ID             uint64         `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Name           string         `gorm:"column:name" json:"name"`
+	Description    string         `gorm:"column:description;type:text" json:"description"`
+	ComponentID    uint64         `gorm:"column:component_id;index" json:"component_id"`
+	ComponentName  string         `gorm:"column:component_name" json:"component_name"`
+	Step           int            `gorm:"column:step;comment:任务步骤" json:"step"`
+	Status         int            `gorm:"column:status;comment:任务状态" json:"status"`
+	Error          string         `gorm:"column:error;type:text" json:"error"`
+	LoadError      *JSONLoadError `gorm:"column:load_error;type:json" json:"load_error"`
+	Deleted        uint8          `gorm:"column:deleted;default:0" json:"deleted"`
+	Creator        string         `gorm:"column:creator" json:"creator"`
+	CreatedTime    time.Time      `gorm:"column:created_time;autoCreateTime" json:"created_time"`
+	LastUpdateTime time.Time      `gorm:"column:last_update_time;autoUpdateTime" json:"last_update_time"`
 
 	// 关联关系
 	ProgStatuses []TaskProgStatusDB `gorm:"foreignKey:TaskID" json:"prog_statuses"`
@@ -39,6 +44,7 @@ func (t *TaskDB) ToTask() *Task {
 		Step:          TaskStep(t.Step),
 		Status:        TaskStatus(t.Status),
 		Error:         t.Error,
+		LoadError:     (*meta.LoadError)(t.LoadError),
 		CreatedAt:     t.CreatedTime,
 		UpdatedAt:     t.LastUpdateTime,
 	}
@@ -101,6 +107,7 @@ func TaskDBFromTask(task *Task) *TaskDB {
 		Step:           int(task.Step),
 		Status:         int(task.Status),
 		Error:          task.Error,
+		LoadError:      (*JSONLoadError)(task.LoadError),
 		CreatedTime:    task.CreatedAt,
 		LastUpdateTime: task.UpdatedAt,
 	}
@@ -126,3 +133,20 @@ func TaskDBFromTask(task *Task) *TaskDB {
 
 	return taskDB
 }
+
+// JSONLoadError 用于存储 meta.LoadError 的 JSON 类型
+type JSONLoadError meta.LoadError
+
+// Value 实现 driver.Valuer 接口
+func (j JSONLoadError) Value() (driver.Value, error) {
+	return json.Marshal(j)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (j *JSONLoadError) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, &j)
+}
@@ -44,6 +44,11 @@ func (s *Server) initRouter() *gin.Engine {
 		v1.GET("/task/running", taskService.Running())
 		v1.POST("/task/:taskId/stop", taskService.Stop())
 		v1.GET("/task/:taskId/metrics", taskService.Metrics())
+		v1.POST("/task/:taskId/iter/:progName", taskService.Iterator())
+		v1.POST("/task/:taskId/prog/:progName/enable", taskService.EnableProgram())
+		v1.POST("/task/:taskId/prog/:progName/disable", taskService.DisableProgram())
+		v1.GET("/task/:taskId/variables", taskService.Variables())
+		v1.PUT("/task/:taskId/variables/:name", taskService.SetVariable())
 
 		// 可观测相关接口
 		v1.GET("/observability/topo", topoService.Topo())
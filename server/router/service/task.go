@@ -1,6 +1,7 @@
 package service
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"github.com/cen-ngc5139/BeePF/server/internal/operator/component"
@@ -127,3 +128,93 @@ func (t *Task) Metrics() gin.HandlerFunc {
 		utils.HandleResult(c, metrics)
 	}
 }
+
+// Iterator 触发任务中的迭代器程序执行一次迭代
+func (t *Task) Iterator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Param("taskId")
+		id, err := strconv.ParseUint(taskId, 10, 64)
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		taskOp := task.NewOperator()
+		output, err := taskOp.RunTaskIterator(id, c.Param("progName"))
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		utils.HandleResult(c, output)
+	}
+}
+
+// EnableProgram 启用任务中被禁用的程序
+func (t *Task) EnableProgram() gin.HandlerFunc {
+	return t.setProgramEnabled(true)
+}
+
+// DisableProgram 禁用任务中的程序
+func (t *Task) DisableProgram() gin.HandlerFunc {
+	return t.setProgramEnabled(false)
+}
+
+func (t *Task) setProgramEnabled(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Param("taskId")
+		id, err := strconv.ParseUint(taskId, 10, 64)
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		taskOp := task.NewOperator()
+		err = taskOp.SetTaskProgramEnabled(id, c.Param("progName"), enabled)
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		utils.HandleResult(c, nil)
+	}
+}
+
+// Variables 读取任务中全局变量的当前值
+func (t *Task) Variables() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Param("taskId")
+		id, err := strconv.ParseUint(taskId, 10, 64)
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		taskOp := task.NewOperator()
+		variables, err := taskOp.GetTaskVariables(id)
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		utils.HandleResult(c, variables)
+	}
+}
+
+// SetVariable 修改任务中的全局变量，请求体为变量的 JSON 值
+func (t *Task) SetVariable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taskId := c.Param("taskId")
+		id, err := strconv.ParseUint(taskId, 10, 64)
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		var value json.RawMessage
+		if err := c.BindJSON(&value); utils.HandleError(c, err) {
+			return
+		}
+
+		taskOp := task.NewOperator()
+		variable, err := taskOp.SetTaskVariable(id, c.Param("name"), value)
+		if utils.HandleError(c, err) {
+			return
+		}
+
+		utils.HandleResult(c, variable)
+	}
+}
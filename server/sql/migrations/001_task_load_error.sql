@@ -0,0 +1,4 @@
+-- 为已有部署的任务表添加程序加载失败信息列
+-- 新部署直接使用 task.sql 建表，无需执行；server 启动时也会自动补齐该列
+ALTER TABLE `beepf`.`task`
+  ADD COLUMN `load_error` JSON NULL COMMENT '程序加载失败的校验器日志和 CO-RE 重定位信息' AFTER `error`;
@@ -8,6 +8,7 @@ CREATE TABLE IF NOT EXISTS `beepf`.`task` (
   `step` INT NOT NULL COMMENT '任务步骤: 0-初始化, 1-加载, 2-启动, 3-统计, 4-指标, 5-停止',
   `status` INT NOT NULL COMMENT '任务状态: 0-等待中, 1-运行中, 2-成功, 3-失败',
   `error` TEXT NULL COMMENT '错误信息',
+  `load_error` JSON NULL COMMENT '程序加载失败的校验器日志和 CO-RE 重定位信息',
   `deleted` TINYINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '是否删除: 0-否, 1-是',
   `creator` VARCHAR(255) NULL COMMENT '创建者',
   `created_time` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',